@@ -0,0 +1,277 @@
+package answer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"med-platform/internal/common/cache"
+	"med-platform/internal/common/db"
+	"med-platform/internal/question"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// =========================================================
+// 📈 个人学习分析：全年热力图、周 / 月正确率趋势、分科 / 分题型正确率、
+// 作答时段分布、重做提升。全部用分组 + 窗口查询一次算出，不在 Go 里逐天循环查库
+// =========================================================
+
+// analyticsTTL 分析结果缓存时长 (Redis 不可用时每次现算)
+const analyticsTTL = 10 * time.Minute
+
+type AnalyticsResponse struct {
+	Heatmap   []DailyActivity `json:"heatmap"` // 最近一年 (按天)
+	Streak    StreakStat      `json:"streak"`
+	Weekly    []TrendPoint    `json:"weekly"`  // 最近 26 周
+	Monthly   []TrendPoint    `json:"monthly"` // 最近 12 个月
+	Subjects  []AccuracyStat  `json:"subjects"`
+	Types     []AccuracyStat  `json:"types"`
+	Hours     []HourStat      `json:"hours"` // 0~23 点
+	Reattempt ReattemptStat   `json:"reattempt"`
+}
+
+// StreakStat 连续打卡：当前连续天数 (今天没做题则为 0) 与历史最长
+type StreakStat struct {
+	Current     int    `json:"current"`
+	Longest     int    `json:"longest"`
+	LongestFrom string `json:"longest_from"`
+	LongestTo   string `json:"longest_to"`
+	ActiveDays  int    `json:"active_days"` // 最近一年有做题的天数
+}
+
+type TrendPoint struct {
+	Period   string  `json:"period"` // 周一日期 / 月份 "2024-03"
+	Attempts int64   `json:"attempts"`
+	Correct  int64   `json:"correct"`
+	Accuracy float64 `json:"accuracy"`
+}
+
+type AccuracyStat struct {
+	Name     string  `json:"name"`
+	Source   string  `json:"source,omitempty"`
+	Attempts int64   `json:"attempts"`
+	Correct  int64   `json:"correct"`
+	Accuracy float64 `json:"accuracy"`
+}
+
+type HourStat struct {
+	Hour     int     `json:"hour"`
+	Attempts int64   `json:"attempts"`
+	Accuracy float64 `json:"accuracy"`
+}
+
+// ReattemptStat 做过不止一次的题：首次与最近一次的对比
+type ReattemptStat struct {
+	Questions      int64   `json:"questions"`
+	FirstCorrect   int64   `json:"first_correct"`
+	LatestCorrect  int64   `json:"latest_correct"`
+	Fixed          int64   `json:"fixed"`     // 首次错、最近对
+	Regressed      int64   `json:"regressed"` // 首次对、最近错
+	FirstAccuracy  float64 `json:"first_accuracy"`
+	LatestAccuracy float64 `json:"latest_accuracy"`
+	Improvement    float64 `json:"improvement"` // 正确率提升的百分点
+}
+
+// percent 正确率 (百分比，保留一位小数)
+func percent(correct, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(correct)/float64(total)*1000) / 10
+}
+
+// activityLevel 热力图色阶
+func activityLevel(count int) int {
+	switch {
+	case count > 100:
+		return 4
+	case count > 50:
+		return 3
+	case count > 20:
+		return 2
+	case count > 0:
+		return 1
+	}
+	return 0
+}
+
+// computeStreak 连续打卡天数：一条查询得出所有连续区间 (见 question.StreakIslandsSQL)
+func computeStreak(tx *gorm.DB, userID uint, today string) StreakStat {
+	type island struct {
+		StartDay string
+		EndDay   string
+		Days     int
+	}
+	var islands []island
+	tx.Raw(`SELECT to_char(start_day, 'YYYY-MM-DD') AS start_day, to_char(end_day, 'YYYY-MM-DD') AS end_day, days
+		FROM (`+question.StreakIslandsSQL("user_id = @user")+`) s`, map[string]interface{}{"user": userID}).Scan(&islands)
+
+	var s StreakStat
+	for _, is := range islands {
+		s.ActiveDays += is.Days
+		if is.EndDay == today {
+			s.Current = is.Days
+		}
+		if is.Days > s.Longest {
+			s.Longest, s.LongestFrom, s.LongestTo = is.Days, is.StartDay, is.EndDay
+		}
+	}
+	return s
+}
+
+// trend 按周 / 月统计作答正确率 (以作答历史为准，重做也算)
+func trend(userID uint, unit, layout string, since time.Time) []TrendPoint {
+	points := []TrendPoint{}
+	db.DB.Raw(`
+		SELECT to_char(date_trunc('`+unit+`', created_at), '`+layout+`') AS period,
+			COUNT(*) AS attempts, COUNT(*) FILTER (WHERE is_correct) AS correct
+		FROM answer_histories
+		WHERE user_id = ? AND created_at >= ?
+		GROUP BY 1 ORDER BY 1
+	`, userID, since).Scan(&points)
+	for i := range points {
+		points[i].Accuracy = percent(points[i].Correct, points[i].Attempts)
+	}
+	return points
+}
+
+// accuracyBy 按题目的某个字段 (科目 / 题型) 分组统计正确率
+func accuracyBy(userID uint, fields, groupBy string) []AccuracyStat {
+	stats := []AccuracyStat{}
+	db.DB.Raw(`
+		SELECT `+fields+`, COUNT(*) AS attempts, COUNT(*) FILTER (WHERE h.is_correct) AS correct
+		FROM answer_histories h JOIN questions q ON q.id = h.question_id
+		WHERE h.user_id = ?
+		GROUP BY `+groupBy+`
+		ORDER BY attempts DESC
+	`, userID).Scan(&stats)
+	for i := range stats {
+		stats[i].Accuracy = percent(stats[i].Correct, stats[i].Attempts)
+	}
+	return stats
+}
+
+// reattemptStat 对做过多次的题，比较首次与最近一次作答
+func reattemptStat(userID uint) ReattemptStat {
+	var s ReattemptStat
+	db.DB.Raw(`
+		WITH h AS (
+			SELECT question_id, is_correct,
+				ROW_NUMBER() OVER (PARTITION BY question_id ORDER BY created_at, id) AS first_rn,
+				ROW_NUMBER() OVER (PARTITION BY question_id ORDER BY created_at DESC, id DESC) AS last_rn,
+				COUNT(*) OVER (PARTITION BY question_id) AS n
+			FROM answer_histories WHERE user_id = ?
+		), per AS (
+			SELECT question_id,
+				bool_or(first_rn = 1 AND is_correct) AS first_ok,
+				bool_or(last_rn = 1 AND is_correct) AS last_ok
+			FROM h WHERE n > 1 GROUP BY question_id
+		)
+		SELECT COUNT(*) AS questions,
+			COUNT(*) FILTER (WHERE first_ok) AS first_correct,
+			COUNT(*) FILTER (WHERE last_ok) AS latest_correct,
+			COUNT(*) FILTER (WHERE NOT first_ok AND last_ok) AS fixed,
+			COUNT(*) FILTER (WHERE first_ok AND NOT last_ok) AS regressed
+		FROM per
+	`, userID).Scan(&s)
+	s.FirstAccuracy = percent(s.FirstCorrect, s.Questions)
+	s.LatestAccuracy = percent(s.LatestCorrect, s.Questions)
+	s.Improvement = math.Round((s.LatestAccuracy-s.FirstAccuracy)*10) / 10
+	return s
+}
+
+// BuildAnalytics 计算某个用户的学习分析
+func BuildAnalytics(userID uint) AnalyticsResponse {
+	now := time.Now()
+	today := now.Format("2006-01-02")
+	res := AnalyticsResponse{}
+
+	// 1. 全年热力图 (每日统计表本身只保留一年，超出的已归档)
+	var stats []struct {
+		DateStr string
+		Count   int
+	}
+	yearAgo := now.AddDate(0, 0, -364).Format("2006-01-02")
+	db.DB.Table("user_daily_stats").Select("date_str, count").
+		Where("user_id = ? AND date_str >= ?", userID, yearAgo).Scan(&stats)
+	counts := make(map[string]int, len(stats))
+	for _, s := range stats {
+		counts[s.DateStr] = s.Count
+	}
+	res.Heatmap = make([]DailyActivity, 0, 365)
+	for i := 364; i >= 0; i-- {
+		d := now.AddDate(0, 0, -i).Format("2006-01-02")
+		res.Heatmap = append(res.Heatmap, DailyActivity{Date: d, Count: counts[d], Level: activityLevel(counts[d])})
+	}
+
+	// 2. 连续打卡
+	res.Streak = computeStreak(db.DB, userID, today)
+
+	// 3. 周 / 月趋势
+	weekStart := now.AddDate(0, 0, -7*25-int((now.Weekday()+6)%7))
+	res.Weekly = trend(userID, "week", "YYYY-MM-DD", time.Date(weekStart.Year(), weekStart.Month(), weekStart.Day(), 0, 0, 0, 0, now.Location()))
+	res.Monthly = trend(userID, "month", "YYYY-MM", time.Date(now.Year(), now.Month()-11, 1, 0, 0, 0, 0, now.Location()))
+
+	// 4. 分科目 / 分题型
+	res.Subjects = accuracyBy(userID, "q.category AS name, q.source AS source", "q.category, q.source")
+	res.Types = accuracyBy(userID, "q.type AS name", "q.type")
+
+	// 5. 作答时段 (补齐 24 个小时)
+	var hours []struct {
+		Hour     int
+		Attempts int64
+		Correct  int64
+	}
+	db.DB.Raw(`
+		SELECT EXTRACT(HOUR FROM created_at)::int AS hour, COUNT(*) AS attempts, COUNT(*) FILTER (WHERE is_correct) AS correct
+		FROM answer_histories WHERE user_id = ? GROUP BY 1
+	`, userID).Scan(&hours)
+	res.Hours = make([]HourStat, 24)
+	for i := range res.Hours {
+		res.Hours[i].Hour = i
+	}
+	for _, h := range hours {
+		if h.Hour >= 0 && h.Hour < 24 {
+			res.Hours[h.Hour] = HourStat{Hour: h.Hour, Attempts: h.Attempts, Accuracy: percent(h.Correct, h.Attempts)}
+		}
+	}
+
+	// 6. 重做提升
+	res.Reattempt = reattemptStat(userID)
+	return res
+}
+
+// GetAnalytics 个人学习分析
+// GET /stats/analytics
+func (h *Handler) GetAnalytics(c *gin.Context) {
+	uid := h.getUserID(c)
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	ctx := context.Background()
+	key := fmt.Sprintf("analytics:user:%d", uid)
+	if cache.RDB != nil {
+		if raw, err := cache.RDB.Get(ctx, key).Bytes(); err == nil {
+			var cached AnalyticsResponse
+			if json.Unmarshal(raw, &cached) == nil {
+				c.JSON(http.StatusOK, gin.H{"data": cached})
+				return
+			}
+		}
+	}
+
+	res := BuildAnalytics(uid)
+	if cache.RDB != nil {
+		if raw, err := json.Marshal(res); err == nil {
+			cache.RDB.Set(ctx, key, raw, analyticsTTL)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"data": res})
+}
@@ -3,15 +3,14 @@ package answer
 import (
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 
 	"med-platform/internal/common/db"
 	"med-platform/internal/question"
+	"med-platform/internal/review"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 )
 
 // ---------------------------------------------------------
@@ -83,9 +82,14 @@ func (h *Handler) Submit(c *gin.Context) {
 	resultData := make(map[uint]map[string]interface{})
 
 	for _, q := range questions {
-		userChoice := strings.TrimSpace(strings.ToUpper(targetAnswers[q.ID]))
-		correctChoice := strings.TrimSpace(strings.ToUpper(q.Correct))
-		isCorrect := (userChoice == correctChoice)
+		// 主观题：先返回参考答案与评分要点，用户自评后 (SelfRate) 才记分
+		if question.IsSubjective(q.Type) {
+			resultData[q.ID] = subjectiveFeedback(q, targetAnswers[q.ID])
+			continue
+		}
+
+		result := Grade(q, targetAnswers[q.ID])
+		userChoice, correctChoice, isCorrect := result.UserChoice, result.CorrectChoice, result.IsCorrect
 
 		if userID > 0 {
 			// 准备流水记录 (带上冗余的 CategoryID 优化统计性能)
@@ -95,6 +99,8 @@ func (h *Handler) Submit(c *gin.Context) {
 				CategoryID: q.CategoryID,
 				Choice:     userChoice,
 				IsCorrect:  isCorrect,
+				Score:      result.Score,
+				QuestionRevision: q.Revision,
 			})
 
 			// 准备错题本记录
@@ -110,6 +116,7 @@ func (h *Handler) Submit(c *gin.Context) {
 
 		resultData[q.ID] = map[string]interface{}{
 			"is_correct":     isCorrect,
+			"score":          result.Score,
 			"user_choice":    userChoice,
 			"correct_answer": correctChoice,
 			"analysis":       q.Analysis,
@@ -124,16 +131,7 @@ func (h *Handler) Submit(c *gin.Context) {
 		}
 
 		// 错题本 Upsert 逻辑
-		if len(mistakes) > 0 {
-			db.DB.Clauses(clause.OnConflict{
-				Columns:   []clause.Column{{Name: "user_id"}, {Name: "question_id"}}, // 联合唯一索引
-				DoUpdates: clause.Assignments(map[string]interface{}{
-					"choice":      gorm.Expr("EXCLUDED.choice"), 
-					"wrong_count": gorm.Expr("user_mistakes.wrong_count + 1"), 
-					"updated_at":  time.Now(),
-				}),
-			}).Create(&mistakes)
-		}
+		h.repo.UpsertMistakes(mistakes)
 	}
 
 	if len(targetAnswers) == 1 && req.Choice != "" {
@@ -182,6 +180,8 @@ func (h *Handler) RemoveMistake(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "移除失败"})
 		return
 	}
+	// 复习队列里取消错题标记 (不再参与"毕业"统计)
+	review.NewRepository().ReleaseMistakes(userID, uint(qID))
 	c.JSON(http.StatusOK, gin.H{"message": "已移出错题本"})
 }
 
@@ -446,26 +446,11 @@ func (h *Handler) GetDashboardStats(c *gin.Context) {
 	for i := 13; i >= 0; i-- {
 		fullDate := time.Now().AddDate(0, 0, -i).Format("2006-01-02")
 		count := activityMap[fullDate]
-		level := 0
-		if count > 0 { level = 1 }
-		if count > 20 { level = 2 }
-		if count > 50 { level = 3 }
-		if count > 100 { level = 4 }
-		response.ActivityMap = append(response.ActivityMap, DailyActivity{Date: fullDate, Count: count, Level: level})
-	}
-
-	// 3. 计算连续打卡天数
-	streak := 0
-	if activityMap[todayStr] > 0 {
-		streak++
-		for i := 1; i < 365; i++ {
-			d := time.Now().AddDate(0, 0, -i).Format("2006-01-02")
-			var cnt int64
-			db.DB.Model(&question.UserDailyStat{}).Where("user_id = ? AND date_str = ?", uid, d).Count(&cnt)
-			if cnt > 0 { streak++ } else { break }
-		}
+		response.ActivityMap = append(response.ActivityMap, DailyActivity{Date: fullDate, Count: count, Level: activityLevel(count)})
 	}
-	response.ConsecutiveDays = streak
+
+	// 3. 计算连续打卡天数 (一条窗口查询，见 analytics.go)
+	response.ConsecutiveDays = computeStreak(db.DB, uid, todayStr).Current
 
 	// 4. 今日排行榜 (前 5 名)
 	rows, err := db.DB.Raw(`
@@ -499,6 +484,13 @@ func (h *Handler) GetStats(c *gin.Context) {
 	h.GetDashboardStats(c)
 }
 
+// JudgeChoice 客观题判题 (只关心对错时使用；需要部分得分请直接用 Grade)
+// 💡 普通刷题 (Submit) 与模考交卷 (exam) 共用判分引擎 (见 scoring.go)，保证两边判分一致
+func JudgeChoice(q question.Question, choice string) (userChoice string, correctChoice string, isCorrect bool) {
+	r := Grade(q, choice)
+	return r.UserChoice, r.CorrectChoice, r.IsCorrect
+}
+
 func (h *Handler) getUserID(c *gin.Context) uint {
 	if v, exists := c.Get("userID"); exists {
 		if id, ok := v.(uint); ok { return id }
@@ -19,6 +19,11 @@ type AnswerRecord struct {
 	
 	Choice     string         `json:"choice"`
 	IsCorrect  bool           `json:"is_correct"`
+	Score      float64        `gorm:"type:decimal(5,4);default:0" json:"score"` // 得分 0~1 (X 型题可部分得分)
+
+	// 📜 作答时题目的版本号：答案改过之后，据此判断哪些记录需要重新判分
+	QuestionRevision int `gorm:"default:1;not null" json:"question_revision"`
+
 	CreatedAt  time.Time      `json:"created_at"`
 	UpdatedAt  time.Time      `json:"updated_at"`
 	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
@@ -40,7 +45,11 @@ type AnswerHistory struct {
 	QuestionID uint      `gorm:"index" json:"question_id"`
 	Choice     string    `json:"choice"`
 	IsCorrect  bool      `json:"is_correct"`
+	Score      float64   `gorm:"type:decimal(5,4);default:0" json:"score"`
 	CreatedAt  time.Time `gorm:"index" json:"created_at"` // 核心查询依据
+
+	// 📜 作答时的题目版本号
+	QuestionRevision int `gorm:"default:1;not null" json:"question_revision"`
 }
 
 func (AnswerHistory) TableName() string {
@@ -0,0 +1,285 @@
+package answer
+
+import (
+	"fmt"
+	"time"
+
+	"med-platform/internal/common/db"
+	"med-platform/internal/common/logger"
+	"med-platform/internal/common/service"
+	"med-platform/internal/itemstat"
+	"med-platform/internal/question"
+	"med-platform/internal/review"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RegradeInterval 兜底轮询间隔 (正常情况下改完答案会立即唤醒，不必等它)
+const RegradeInterval = time.Minute
+
+// regradeBatchSize 扫描作答记录的批大小
+const regradeBatchSize = 1000
+
+// StartRegradeTask 启动重判守护任务：标准答案变更后，按新答案重算已有作答
+// 请在 main.go 中调用: answer.StartRegradeTask()
+func StartRegradeTask() {
+	// 上次没跑完的任务重新排队 (重判按题目当前答案计算，重复执行结果一致)
+	db.DB.Model(&question.RegradeJob{}).Where("status = ?", question.RegradeRunning).Update("status", question.RegradePending)
+
+	go func() {
+		ticker := time.NewTicker(RegradeInterval)
+		defer ticker.Stop()
+		for {
+			runPendingRegrades()
+			select {
+			case <-ticker.C:
+			case <-question.RegradeQueued:
+			}
+		}
+	}()
+}
+
+// runPendingRegrades 依次执行所有排队中的重判任务
+func runPendingRegrades() {
+	for {
+		var job question.RegradeJob
+		if err := db.DB.Where("status = ?", question.RegradePending).Order("id asc").First(&job).Error; err != nil {
+			return
+		}
+		runRegrade(&job)
+	}
+}
+
+// regradeRow 需要重判的一条作答 (当前记录或历史轨迹)
+type regradeRow struct {
+	ID        uint
+	UserID    uint
+	Choice    string
+	IsCorrect bool
+	Score     float64
+}
+
+// 改判结论 (用于通知文案)
+const (
+	verdictCorrect = "correct" // 当前记录改判为正确
+	verdictWrong   = "wrong"   // 当前记录改判为错误
+	verdictHistory = ""        // 只改了历史轨迹 (当前记录已重置或本就一致)
+)
+
+// regradeResult 一次重判的结果
+type regradeResult struct {
+	records   int
+	histories int
+	users     map[uint]string // 受影响用户 -> 当前记录的判分结论 (verdictXxx)
+}
+
+func runRegrade(job *question.RegradeJob) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			logger.Log.Error("重判任务异常退出", zap.Uint("job_id", job.ID), zap.Any("panic", rec))
+			finishRegrade(job, question.RegradeFailed, fmt.Sprintf("重判异常退出: %v", rec))
+		}
+	}()
+	db.DB.Model(job).Update("status", question.RegradeRunning)
+
+	var q question.Question
+	if err := db.DB.Unscoped().First(&q, job.QuestionID).Error; err != nil {
+		finishRegrade(job, question.RegradeFailed, "题目不存在")
+		return
+	}
+	if question.IsSubjective(q.Type) {
+		// 主观题的对错来自用户自评，参考答案改了也不替用户改判
+		finishRegrade(job, question.RegradeSuccess, "主观题按自评记分，无需重判")
+		return
+	}
+
+	var res regradeResult
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		res, err = regradeQuestion(tx, q)
+		return err
+	})
+	if err != nil {
+		logger.Log.Error("重判失败", zap.Uint("job_id", job.ID), zap.Uint("question_id", q.ID), zap.Error(err))
+		finishRegrade(job, question.RegradeFailed, "重判失败: "+err.Error())
+		return
+	}
+
+	job.RecordsChanged = res.records
+	job.HistoriesChanged = res.histories
+	job.Affected = len(res.users)
+	notifyRegraded(job, q, res.users)
+	for uid := range res.users {
+		question.InvalidateTreeProgress(uid)
+	}
+	if job.Affected > 0 {
+		itemstat.RefreshSource(q.Source)
+	}
+	service.WriteAudit(job.EditorID, "question.regrade", "question", q.ID, job.Affected,
+		fmt.Sprintf("标准答案 %s → %s (v%d)：改判当前记录 %d 条、历史轨迹 %d 条", job.OldCorrect, job.NewCorrect, job.Revision, res.records, res.histories))
+	finishRegrade(job, question.RegradeSuccess, fmt.Sprintf("已重判，影响 %d 位用户", job.Affected))
+}
+
+func finishRegrade(job *question.RegradeJob, status, message string) {
+	now := time.Now()
+	job.Status = status
+	job.Message = message
+	job.FinishedAt = &now
+	if err := db.DB.Save(job).Error; err != nil {
+		logger.Log.Error("保存重判任务结果失败", zap.Uint("job_id", job.ID), zap.Error(err))
+	}
+}
+
+// regradeQuestion 按题目当前答案重算全部当前记录与历史轨迹，并修正受影响用户的错题本
+func regradeQuestion(tx *gorm.DB, q question.Question) (regradeResult, error) {
+	res := regradeResult{users: map[uint]string{}}
+
+	// 1. 当前记录 (决定答题卡颜色与正确率)
+	changed, err := regradeTable(tx, &AnswerRecord{}, q, func(r regradeRow, correct bool) {
+		res.users[r.UserID] = verdictWrong
+		if correct {
+			res.users[r.UserID] = verdictCorrect
+		}
+	})
+	if err != nil {
+		return res, err
+	}
+	res.records = changed
+
+	// 2. 历史轨迹 (学习曲线、掌握度、项目分析都基于它)
+	changed, err = regradeTable(tx, &AnswerHistory{}, q, func(r regradeRow, _ bool) {
+		if _, ok := res.users[r.UserID]; !ok {
+			res.users[r.UserID] = verdictHistory
+		}
+	})
+	if err != nil {
+		return res, err
+	}
+	res.histories = changed
+	if len(res.users) == 0 {
+		return res, nil
+	}
+
+	userIDs := make([]uint, 0, len(res.users))
+	for uid := range res.users {
+		userIDs = append(userIDs, uid)
+	}
+	var current []regradeRow
+	if err := tx.Model(&AnswerRecord{}).Select("user_id, choice, is_correct").
+		Where("question_id = ? AND user_id IN ?", q.ID, userIDs).Scan(&current).Error; err != nil {
+		return res, err
+	}
+
+	// 3. 错题本：按新答案统计每人的答错次数，有错的补上 / 覆盖次数，没错的移出
+	type wrongStat struct {
+		UserID uint
+		Wrong  int
+		Choice string
+	}
+	var stats []wrongStat
+	if err := tx.Raw(`
+		SELECT user_id, COUNT(*) AS wrong, (ARRAY_AGG(choice ORDER BY created_at DESC))[1] AS choice
+		FROM answer_histories
+		WHERE question_id = ? AND user_id IN ? AND NOT is_correct
+		GROUP BY user_id
+	`, q.ID, userIDs).Scan(&stats).Error; err != nil {
+		return res, err
+	}
+	wrong := make(map[uint]wrongStat, len(stats))
+	for _, s := range stats {
+		wrong[s.UserID] = s
+	}
+	// 没有历史轨迹的老数据，以当前记录为准
+	for _, r := range current {
+		if _, ok := wrong[r.UserID]; !ok && !r.IsCorrect {
+			wrong[r.UserID] = wrongStat{UserID: r.UserID, Wrong: 1, Choice: r.Choice}
+		}
+	}
+
+	var mistakes []UserMistake
+	var cleared []uint
+	for _, uid := range userIDs {
+		if s, ok := wrong[uid]; ok {
+			mistakes = append(mistakes, UserMistake{UserID: uid, QuestionID: q.ID, Choice: s.Choice, WrongCount: s.Wrong})
+		} else {
+			cleared = append(cleared, uid)
+		}
+	}
+	if len(mistakes) > 0 {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "question_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"choice", "wrong_count"}),
+		}).CreateInBatches(&mistakes, 500).Error; err != nil {
+			return res, err
+		}
+	}
+	if len(cleared) > 0 {
+		if err := tx.Where("question_id = ? AND user_id IN ?", q.ID, cleared).Delete(&UserMistake{}).Error; err != nil {
+			return res, err
+		}
+		// 复习队列同步取消错题标记 (与手动移出错题本一致)
+		if err := tx.Model(&review.ReviewState{}).Where("question_id = ? AND user_id IN ?", q.ID, cleared).
+			Update("from_mistake", false).Error; err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// regradeTable 按 id 分批扫描某题的作答，对错或得分与库里不一致的改过来，返回改判条数
+func regradeTable(tx *gorm.DB, model interface{}, q question.Question, onChange func(r regradeRow, correct bool)) (int, error) {
+	changed := 0
+	lastID := uint(0)
+	for {
+		var rows []regradeRow
+		if err := tx.Model(model).Select("id, user_id, choice, is_correct, score").
+			Where("question_id = ? AND id > ?", q.ID, lastID).
+			Order("id asc").Limit(regradeBatchSize).Scan(&rows).Error; err != nil {
+			return changed, err
+		}
+		if len(rows) == 0 {
+			return changed, nil
+		}
+
+		// 按新的 (对错, 得分) 分组批量改写
+		type grade struct {
+			correct bool
+			score   float64
+		}
+		groups := make(map[grade][]uint)
+		for _, r := range rows {
+			res := Grade(q, r.Choice)
+			if res.IsCorrect == r.IsCorrect && res.Score == r.Score {
+				continue
+			}
+			g := grade{res.IsCorrect, res.Score}
+			groups[g] = append(groups[g], r.ID)
+			onChange(r, res.IsCorrect)
+			changed++
+		}
+		for g, ids := range groups {
+			if err := tx.Model(model).Where("id IN ?", ids).
+				Updates(map[string]interface{}{"is_correct": g.correct, "score": g.score}).Error; err != nil {
+				return changed, err
+			}
+		}
+		lastID = rows[len(rows)-1].ID
+	}
+}
+
+// notifyRegraded 通知受影响的用户 (标题里写清改判结果，正文是题干摘要)
+func notifyRegraded(job *question.RegradeJob, q question.Question, users map[uint]string) {
+	for uid, v := range users {
+		verdict := "你的作答记录已重新判分"
+		switch v {
+		case verdictCorrect:
+			verdict = "你的作答已改判为正确"
+		case verdictWrong:
+			verdict = "你的作答已改判为错误"
+		}
+		title := fmt.Sprintf("答案更正：标准答案 %s → %s，%s", job.OldCorrect, job.NewCorrect, verdict)
+		service.SendNotification(uid, job.EditorID, "question", q.ID, q.Stem, title)
+	}
+}
@@ -1,122 +1,191 @@
-package answer
-
-import (
-	"time"
-
-	"med-platform/internal/common/db"
-	"med-platform/internal/question"
-
-	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
-)
-
-type Repository struct{}
-
-func NewRepository() *Repository {
-	return &Repository{}
-}
-
-// BatchCreateOrUpdate 批量保存或更新作答流水（支持组合大题一次性提交）
-// 🔥 核心优化：
-// 1. 批量处理，极大减少数据库往返次数 (RTT)
-// 2. 将 N 次的每日统计表事务锁竞争，合并为 1 次批量加 N
-// 3. 同步写入 AnswerHistory（历史轨迹），为后续学习曲线分析做准备
-func (r *Repository) BatchCreateOrUpdate(records []*AnswerRecord) error {
-	if len(records) == 0 {
-		return nil
-	}
-
-	return db.DB.Transaction(func(tx *gorm.DB) error {
-		userID := records[0].UserID
-		today := time.Now().Format("2006-01-02")
-		
-		for _, record := range records {
-			// -------------------------------------------------------
-			// 1. 更新当前状态表 (AnswerRecord) - 决定答题卡的颜色
-			// -------------------------------------------------------
-			var existing AnswerRecord
-			err := tx.Where("user_id = ? AND question_id = ?", record.UserID, record.QuestionID).First(&existing).Error
-
-			if err != nil {
-				if err == gorm.ErrRecordNotFound {
-					// 没做过 -> 插入新记录
-					if err := tx.Create(record).Error; err != nil {
-						return err
-					}
-				} else {
-					return err
-				}
-			} else {
-				// 做过 -> 覆盖旧的选项、对错状态
-				existing.Choice = record.Choice
-				existing.IsCorrect = record.IsCorrect
-				if err := tx.Save(&existing).Error; err != nil {
-					return err
-				}
-			}
-
-			// -------------------------------------------------------
-			// 2. 追加历史轨迹表 (AnswerHistory) - 记录用户的每一次手跳
-			// -------------------------------------------------------
-			// 历史表是 Append-Only（只增不改）的，所以直接 Create
-			history := AnswerHistory{
-				UserID:     record.UserID,
-				QuestionID: record.QuestionID,
-				Choice:     record.Choice,
-				IsCorrect:  record.IsCorrect,
-			}
-			if err := tx.Create(&history).Error; err != nil {
-				return err
-			}
-		}
-
-		// -------------------------------------------------------
-		// 3. 批量更新每日刷题统计 (user_daily_stats)
-		// -------------------------------------------------------
-		// 逻辑：直接增加本次提交的题目总数 (len)
-		stat := question.UserDailyStat{
-			UserID:  userID,
-			DateStr: today,
-			Count:   len(records), 
-		}
-		
-		if err := tx.Clauses(clause.OnConflict{
-			Columns:   []clause.Column{{Name: "user_id"}, {Name: "date_str"}},
-			DoUpdates: clause.Assignments(map[string]interface{}{
-				"count": gorm.Expr("user_daily_stats.count + ?", len(records)), // 🔥 一次性加 N
-			}),
-		}).Create(&stat).Error; err != nil {
-			return err
-		}
-
-		return nil
-	})
-}
-
-// Delete 物理删除单条作答当前记录 (用于重做单题)
-// 💡 优化：重做只删除"当前状态表(Record)"，"历史轨迹(History)"和"每日统计(Stats)"将永久保留
-func (r *Repository) Delete(userID, questionID uint) error {
-	return db.DB.Unscoped().
-		Where("user_id = ? AND question_id = ?", userID, questionID).
-		Delete(&AnswerRecord{}).Error
-}
-
-// ResetCategory 物理删除某章节下的所有当前记录 (用于重做本章)
-func (r *Repository) ResetCategory(userID uint, categoryPath string) error {
-	var qIDs []uint
-	err := db.DB.Table("questions").
-		Where("category_path LIKE ?", categoryPath+"%").
-		Pluck("id", &qIDs).Error
-	
-	if err != nil {
-		return err
-	}
-	
-	if len(qIDs) == 0 {
-		return nil 
-	}
-
-	return db.DB.Unscoped().
-		Where("user_id = ? AND question_id IN ?", userID, qIDs).
-		Delete(&AnswerRecord{}).Error
+package answer
+
+import (
+	"time"
+
+	"med-platform/internal/common/db"
+	"med-platform/internal/leaderboard"
+	"med-platform/internal/question"
+	"med-platform/internal/review"
+	"med-platform/internal/studygroup"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct{}
+
+func NewRepository() *Repository {
+	return &Repository{}
+}
+
+// BatchCreateOrUpdate 批量保存或更新作答流水（支持组合大题一次性提交）
+// 🔥 核心优化：
+// 1. 批量处理，极大减少数据库往返次数 (RTT)
+// 2. 将 N 次的每日统计表事务锁竞争，合并为 1 次批量加 N
+// 3. 同步写入 AnswerHistory（历史轨迹），为后续学习曲线分析做准备
+// 4. 同一事务内推进间隔复习的记忆状态 (SM-2)，错题答对够次数自动毕业
+func (r *Repository) BatchCreateOrUpdate(records []*AnswerRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	return r.SaveBatch(records, nil, nil)
+}
+
+// SaveBatch 在同一事务里写入作答流水、错题本，并执行调用方的收尾更新 finish (如模考交卷写成绩)
+// 任一步失败整体回滚；提交成功后才让进度缓存失效、推送小组挑战进度、更新排行榜
+func (r *Repository) SaveBatch(records []*AnswerRecord, mistakes []UserMistake, finish func(tx *gorm.DB) error) error {
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := saveRecords(tx, records); err != nil {
+			return err
+		}
+		if err := upsertMistakes(tx, mistakes); err != nil {
+			return err
+		}
+		if finish != nil {
+			return finish(tx)
+		}
+		return nil
+	})
+	if err == nil && len(records) > 0 {
+		question.InvalidateTreeProgress(records[0].UserID) // 目录树进度缓存失效
+		studygroup.OnAnswered(records[0].UserID)            // 推送小组挑战进度
+		leaderboard.OnAnswered(records[0].UserID, len(records))
+	}
+	return err
+}
+
+// saveRecords 在事务 tx 内完成 BatchCreateOrUpdate 的全部写入
+func saveRecords(tx *gorm.DB, records []*AnswerRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	userID := records[0].UserID
+	today := time.Now().Format("2006-01-02")
+	
+	for _, record := range records {
+		// -------------------------------------------------------
+		// 1. 更新当前状态表 (AnswerRecord) - 决定答题卡的颜色
+		// -------------------------------------------------------
+		var existing AnswerRecord
+		err := tx.Where("user_id = ? AND question_id = ?", record.UserID, record.QuestionID).First(&existing).Error
+
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				// 没做过 -> 插入新记录
+				if err := tx.Create(record).Error; err != nil {
+					return err
+				}
+			} else {
+				return err
+			}
+		} else {
+			// 做过 -> 覆盖旧的选项、对错状态
+			existing.Choice = record.Choice
+			existing.IsCorrect = record.IsCorrect
+			existing.Score = record.Score
+			existing.QuestionRevision = record.QuestionRevision
+			if err := tx.Save(&existing).Error; err != nil {
+				return err
+			}
+		}
+
+		// -------------------------------------------------------
+		// 2. 追加历史轨迹表 (AnswerHistory) - 记录用户的每一次手跳
+		// -------------------------------------------------------
+		// 历史表是 Append-Only（只增不改）的，所以直接 Create
+		history := AnswerHistory{
+			UserID:     record.UserID,
+			QuestionID: record.QuestionID,
+			Choice:     record.Choice,
+			IsCorrect:  record.IsCorrect,
+			Score:      record.Score,
+			QuestionRevision: record.QuestionRevision,
+		}
+		if err := tx.Create(&history).Error; err != nil {
+			return err
+		}
+	}
+
+	// -------------------------------------------------------
+	// 3. 批量更新每日刷题统计 (user_daily_stats)
+	// -------------------------------------------------------
+	// 逻辑：直接增加本次提交的题目总数 (len)
+	stat := question.UserDailyStat{
+		UserID:  userID,
+		DateStr: today,
+		Count:   len(records), 
+	}
+	
+	if err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "date_str"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"count": gorm.Expr("user_daily_stats.count + ?", len(records)), // 🔥 一次性加 N
+		}),
+	}).Create(&stat).Error; err != nil {
+		return err
+	}
+
+	// -------------------------------------------------------
+	// 4. 推进间隔复习的记忆状态 (review_states)
+	// -------------------------------------------------------
+	outcomes := make([]review.Outcome, 0, len(records))
+	for _, record := range records {
+		outcomes = append(outcomes, review.Outcome{
+			UserID:     record.UserID,
+			QuestionID: record.QuestionID,
+			IsCorrect:  record.IsCorrect,
+		})
+	}
+	return review.ApplyOutcomes(tx, outcomes)
+}
+
+// UpsertMistakes 批量写入错题本
+// 已存在的错题：覆盖最近一次的错误选项、错题次数 +1，并刷新 updated_at 让它浮到最前
+func (r *Repository) UpsertMistakes(mistakes []UserMistake) error {
+	return upsertMistakes(db.DB, mistakes)
+}
+
+func upsertMistakes(tx *gorm.DB, mistakes []UserMistake) error {
+	if len(mistakes) == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "question_id"}}, // 联合唯一索引
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"choice":      gorm.Expr("EXCLUDED.choice"),
+			"wrong_count": gorm.Expr("user_mistakes.wrong_count + 1"),
+			"updated_at":  time.Now(),
+		}),
+	}).Create(&mistakes).Error
+}
+
+// Delete 物理删除单条作答当前记录 (用于重做单题)
+// 💡 优化：重做只删除"当前状态表(Record)"，"历史轨迹(History)"和"每日统计(Stats)"将永久保留
+func (r *Repository) Delete(userID, questionID uint) error {
+	defer question.InvalidateTreeProgress(userID)
+	return db.DB.Unscoped().
+		Where("user_id = ? AND question_id = ?", userID, questionID).
+		Delete(&AnswerRecord{}).Error
+}
+
+// ResetCategory 物理删除某章节下的所有当前记录 (用于重做本章)
+func (r *Repository) ResetCategory(userID uint, categoryPath string) error {
+	var qIDs []uint
+	err := db.DB.Table("questions").
+		Where("category_path LIKE ?", categoryPath+"%").
+		Pluck("id", &qIDs).Error
+	
+	if err != nil {
+		return err
+	}
+	
+	if len(qIDs) == 0 {
+		return nil 
+	}
+
+	defer question.InvalidateTreeProgress(userID)
+	return db.DB.Unscoped().
+		Where("user_id = ? AND question_id IN ?", userID, qIDs).
+		Delete(&AnswerRecord{}).Error
 }
\ No newline at end of file
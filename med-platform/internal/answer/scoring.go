@@ -0,0 +1,161 @@
+package answer
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"med-platform/internal/common/db"
+	"med-platform/internal/common/logger"
+	"med-platform/internal/question"
+	"med-platform/internal/sysconfig"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// =========================================================
+// 🧮 判分引擎：按题型选择判分规则
+// =========================================================
+
+// X 型题 (多选) 部分得分策略，取自系统配置 KeyScoringXPolicy
+const (
+	PolicyAllOrNothing = "all_or_nothing" // 完全一致才得分 (默认)
+	PolicyPerOption    = "per_option"     // 少选按选对的比例得分，错选不得分
+	PolicyDeduct       = "deduct"         // 每选对一项加分、每错选一项扣分，最低 0 分
+)
+
+// Result 一次判分的结果
+type Result struct {
+	UserChoice    string  // 归一后的作答
+	CorrectChoice string  // 归一后的标准答案
+	IsCorrect     bool    // 是否完全正确 (错题本、正确率都以它为准)
+	Score         float64 // 得分 0~1 (部分得分时介于两者之间)
+}
+
+// Scorer 某类题型的判分规则
+type Scorer interface {
+	Score(q question.Question, choice string) Result
+}
+
+// scorers 题型代码 (question.TypeCode) -> 判分规则；未登记的题型按单选处理
+var scorers = map[string]Scorer{
+	"X": multiSelectScorer{},
+}
+
+var defaultScorer Scorer = singleChoiceScorer{}
+
+// RegisterScorer 登记 / 替换某个题型的判分规则
+func RegisterScorer(typeCode string, s Scorer) {
+	scorers[typeCode] = s
+}
+
+// Grade 按题型判分 (普通刷题、模考交卷、改答案重判共用)
+func Grade(q question.Question, choice string) Result {
+	code := question.TypeCode(q.Type)
+	if question.IsSubjective(q.Type) {
+		code = CodeSubjective
+	}
+	if s, ok := scorers[code]; ok {
+		return s.Score(q, choice)
+	}
+	return defaultScorer.Score(q, choice)
+}
+
+// singleChoiceScorer 单选 / 配伍等：统一大小写与首尾空白后整体比对
+type singleChoiceScorer struct{}
+
+func (singleChoiceScorer) Score(q question.Question, choice string) Result {
+	r := Result{
+		UserChoice:    strings.TrimSpace(strings.ToUpper(choice)),
+		CorrectChoice: strings.TrimSpace(strings.ToUpper(q.Correct)),
+	}
+	r.IsCorrect = r.UserChoice != "" && r.UserChoice == r.CorrectChoice
+	if r.IsCorrect {
+		r.Score = 1
+	}
+	return r
+}
+
+// multiSelectScorer X 型题：作答与答案都归一为有序、去重的选项集合 ("BA" 与 "A,B" 都视为 "AB")
+type multiSelectScorer struct{}
+
+func (multiSelectScorer) Score(q question.Question, choice string) Result {
+	return scoreMultiSelect(normalizeOptions(choice), normalizeOptions(q.Correct), sysconfig.GetConfig(sysconfig.KeyScoringXPolicy))
+}
+
+// scoreMultiSelect 按部分得分策略给归一后的作答打分
+func scoreMultiSelect(picked, key, policy string) Result {
+	r := Result{UserChoice: picked, CorrectChoice: key}
+	if picked == "" || key == "" {
+		return r
+	}
+	r.IsCorrect = picked == key
+	if r.IsCorrect {
+		r.Score = 1
+		return r
+	}
+
+	hit, miss := 0, 0
+	for _, letter := range picked {
+		if strings.ContainsRune(key, letter) {
+			hit++
+		} else {
+			miss++
+		}
+	}
+	total := float64(len(key))
+	switch policy {
+	case PolicyPerOption:
+		if miss == 0 {
+			r.Score = float64(hit) / total
+		}
+	case PolicyDeduct:
+		r.Score = math.Max(0, float64(hit-miss)/total)
+	}
+	r.Score = math.Round(r.Score*10000) / 10000
+	return r
+}
+
+// normalizeOptions 提取 A~F 选项字母，去重后按字母排序
+func normalizeOptions(s string) string {
+	seen := make(map[rune]bool)
+	var letters []rune
+	for _, ch := range strings.ToUpper(s) {
+		if ch >= 'A' && ch <= 'F' && !seen[ch] {
+			seen[ch] = true
+			letters = append(letters, ch)
+		}
+	}
+	sort.Slice(letters, func(i, j int) bool { return letters[i] < letters[j] })
+	return string(letters)
+}
+
+// BackfillScores 得分字段上线前的老数据：答对的补记满分 (答错默认 0 分无需处理)
+// 一次性迁移：完成后写入系统配置 MIGRATION_SCORE_BACKFILLED，之后启动直接跳过，不再整表更新
+// 请在 main.go 数据库迁移、sysconfig.InitConfig 之后调用: answer.BackfillScores()
+func BackfillScores() {
+	if sysconfig.GetConfig(sysconfig.KeyScoreBackfilled) != "" {
+		return
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		for _, table := range []string{"answer_records", "answer_histories"} {
+			res := tx.Exec("UPDATE " + table + " SET score = 1 WHERE is_correct AND score = 0")
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected > 0 {
+				logger.Log.Info("已补记作答得分", zap.String("table", table), zap.Int64("rows", res.RowsAffected))
+			}
+		}
+		return tx.Create(&sysconfig.SysConfig{
+			Key:         sysconfig.KeyScoreBackfilled,
+			Value:       time.Now().Format("2006-01-02 15:04:05"),
+			Description: "作答得分补记迁移的完成时间 (删除后下次启动会重新补记)",
+		}).Error
+	})
+	if err != nil {
+		logger.Log.Error("补记作答得分失败", zap.Error(err))
+	}
+}
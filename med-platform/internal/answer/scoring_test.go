@@ -0,0 +1,79 @@
+package answer
+
+import (
+	"testing"
+
+	"med-platform/internal/question"
+)
+
+func TestNormalizeOptions(t *testing.T) {
+	tests := map[string]string{
+		"":        "",
+		"a":       "A",
+		"DBA":     "ABD",
+		"b, a":    "AB",
+		"AAB":     "AB",
+		"AG":      "A",
+		"对":       "",
+		"f e d c": "CDEF",
+	}
+	for in, want := range tests {
+		if got := normalizeOptions(in); got != want {
+			t.Errorf("normalizeOptions(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestScoreMultiSelect(t *testing.T) {
+	tests := []struct {
+		name    string
+		picked  string
+		policy  string
+		correct bool
+		score   float64
+	}{
+		{"全对", "ABD", PolicyAllOrNothing, true, 1},
+		{"全对不看策略", "ABD", PolicyDeduct, true, 1},
+		{"少选 全对才得分", "AB", PolicyAllOrNothing, false, 0},
+		{"少选 按比例", "AB", PolicyPerOption, false, 0.6667},
+		{"少选 倒扣", "AB", PolicyDeduct, false, 0.6667},
+		{"错选 全对才得分", "ABC", PolicyAllOrNothing, false, 0},
+		{"错选 按比例不得分", "ABC", PolicyPerOption, false, 0},
+		{"错选 倒扣", "ABC", PolicyDeduct, false, 0.3333},
+		{"倒扣最低 0 分", "CE", PolicyDeduct, false, 0},
+		{"未作答", "", PolicyPerOption, false, 0},
+		{"未知策略按全对才得分", "AB", "", false, 0},
+	}
+	for _, tt := range tests {
+		r := scoreMultiSelect(tt.picked, "ABD", tt.policy)
+		if r.IsCorrect != tt.correct || r.Score != tt.score {
+			t.Errorf("%s: correct=%v score=%v, want %v %v", tt.name, r.IsCorrect, r.Score, tt.correct, tt.score)
+		}
+		if r.UserChoice != tt.picked || r.CorrectChoice != "ABD" {
+			t.Errorf("%s: choice=%q key=%q", tt.name, r.UserChoice, r.CorrectChoice)
+		}
+	}
+}
+
+func TestGrade(t *testing.T) {
+	tests := []struct {
+		name    string
+		q       question.Question
+		choice  string
+		user    string
+		correct bool
+		score   float64
+	}{
+		{"单选忽略大小写和空白", question.Question{Type: "A1型题", Correct: "C"}, " c ", "C", true, 1},
+		{"单选答错", question.Question{Type: "A2型题", Correct: "C"}, "B", "B", false, 0},
+		{"单选未作答", question.Question{Type: "A1型题", Correct: "C"}, "", "", false, 0},
+		{"X 型题顺序无关", question.Question{Type: "X型题", Correct: "A,C"}, "ca", "AC", true, 1},
+		{"X 型题少选", question.Question{Type: "X型题", Correct: "AC"}, "A", "A", false, 0},
+	}
+	for _, tt := range tests {
+		r := Grade(tt.q, tt.choice)
+		if r.UserChoice != tt.user || r.IsCorrect != tt.correct || r.Score != tt.score {
+			t.Errorf("%s: got %+v, want choice=%q correct=%v score=%v", tt.name, r, tt.user, tt.correct, tt.score)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package answer
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"med-platform/internal/common/db"
+	"med-platform/internal/question"
+	"med-platform/internal/sysconfig"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =========================================================
+// 📝 主观题：提交作答 -> 对照参考答案 -> 按要点自评
+// =========================================================
+
+// CodeSubjective 主观题在判分引擎里的题型代码
+const CodeSubjective = "SUBJECTIVE"
+
+// SubjectivePassScore 自评覆盖的要点达到这个比例视为答对 (低于它进错题本)
+const SubjectivePassScore = 0.6
+
+// subjectiveMaxRunes 作答文本上限
+const subjectiveMaxRunes = 5000
+
+// pointMatchRatio 要点中的词片有这个比例出现在作答里，即认为覆盖了该要点
+const pointMatchRatio = 0.5
+
+func init() {
+	RegisterScorer(CodeSubjective, subjectiveScorer{})
+}
+
+// subjectiveScorer 关键词覆盖率自动评分：只给参考分，不判对错 (对错以用户自评为准)
+type subjectiveScorer struct{}
+
+func (subjectiveScorer) Score(q question.Question, text string) Result {
+	score, _ := KeywordCoverage(question.RubricPoints(q.Correct), text)
+	return Result{
+		UserChoice:    clipAnswer(text),
+		CorrectChoice: q.Correct,
+		Score:         score,
+	}
+}
+
+// autoScoreEnabled 关键词自动评分开关 (系统配置 SUBJECTIVE_AUTO_SCORE=off 可关闭)
+func autoScoreEnabled() bool {
+	return sysconfig.GetConfig(sysconfig.KeySubjectiveAutoScore) != "off"
+}
+
+// KeywordCoverage 逐条要点检查作答是否覆盖，返回覆盖比例与每条要点的命中情况
+// 💡 中文按相邻两字切片、英文数字按整词比对，不依赖分词词典
+func KeywordCoverage(points []string, text string) (float64, []bool) {
+	matched := make([]bool, len(points))
+	if len(points) == 0 {
+		return 0, matched
+	}
+	answer := strings.ToLower(text)
+	hits := 0
+	for i, p := range points {
+		grams := pointGrams(p)
+		if len(grams) == 0 {
+			continue
+		}
+		found := 0
+		for _, g := range grams {
+			if strings.Contains(answer, g) {
+				found++
+			}
+		}
+		if float64(found)/float64(len(grams)) >= pointMatchRatio {
+			matched[i] = true
+			hits++
+		}
+	}
+	return math.Round(float64(hits)/float64(len(points))*10000) / 10000, matched
+}
+
+// pointGrams 要点的比对片段：汉字两两相邻成片 (单字保留)，英文数字连续成词
+func pointGrams(point string) []string {
+	seen := make(map[string]bool)
+	var grams []string
+	add := func(g string) {
+		if g != "" && !seen[g] {
+			seen[g] = true
+			grams = append(grams, g)
+		}
+	}
+	var han []rune
+	var word []rune
+	flush := func() {
+		if len(han) == 1 {
+			add(string(han))
+		}
+		for i := 0; i+1 < len(han); i++ {
+			add(string(han[i : i+2]))
+		}
+		add(strings.ToLower(string(word)))
+		han, word = han[:0], word[:0]
+	}
+	for _, r := range point {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			if len(word) > 0 {
+				flush()
+			}
+			han = append(han, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if len(han) > 0 {
+				flush()
+			}
+			word = append(word, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return grams
+}
+
+func clipAnswer(text string) string {
+	text = strings.TrimSpace(text)
+	if runes := []rune(text); len(runes) > subjectiveMaxRunes {
+		text = string(runes[:subjectiveMaxRunes])
+	}
+	return text
+}
+
+// subjectiveFeedback 提交主观题后返回给前端的对照信息 (此时还未记分，等用户自评)
+func subjectiveFeedback(q question.Question, text string) map[string]interface{} {
+	points := question.RubricPoints(q.Correct)
+	data := map[string]interface{}{
+		"subjective":     true,
+		"user_choice":    clipAnswer(text),
+		"correct_answer": q.Correct,
+		"analysis":       q.Analysis,
+		"rubric":         points,
+	}
+	if autoScoreEnabled() {
+		score, matched := KeywordCoverage(points, text)
+		data["auto_score"] = score
+		data["matched"] = matched
+	}
+	return data
+}
+
+// SelfRate 主观题自评：勾选自己答到的要点，按覆盖比例记分，与客观题一样进入作答记录、错题本和统计
+// POST /questions/:id/self-rate  {"answer": "...", "hits": [0, 2]}
+// 参考答案拆不出要点时，改为直接给出 {"correct": true/false}
+func (h *Handler) SelfRate(c *gin.Context) {
+	qID, _ := strconv.Atoi(c.Param("id"))
+	var req struct {
+		Answer  string `json:"answer"`
+		Hits    []int  `json:"hits"`
+		Correct *bool  `json:"correct"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID := h.getUserID(c)
+
+	var q question.Question
+	if err := db.DB.First(&q, qID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "题目不存在"})
+		return
+	}
+	if !question.IsSubjective(q.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "只有主观题可以自评"})
+		return
+	}
+
+	points := question.RubricPoints(q.Correct)
+	var score float64
+	if len(points) > 0 {
+		hit := make(map[int]bool)
+		for _, i := range req.Hits {
+			if i < 0 || i >= len(points) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "要点序号无效"})
+				return
+			}
+			hit[i] = true
+		}
+		score = float64(len(hit)) / float64(len(points))
+	} else if req.Correct != nil {
+		if *req.Correct {
+			score = 1
+		}
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请勾选答到的要点"})
+		return
+	}
+	score = math.Round(score*10000) / 10000
+	isCorrect := score >= SubjectivePassScore
+	answer := clipAnswer(req.Answer)
+
+	if err := h.repo.BatchCreateOrUpdate([]*AnswerRecord{{
+		UserID:           userID,
+		QuestionID:       q.ID,
+		CategoryID:       q.CategoryID,
+		Choice:           answer,
+		IsCorrect:        isCorrect,
+		Score:            score,
+		QuestionRevision: q.Revision,
+	}}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存答题记录失败"})
+		return
+	}
+	if !isCorrect {
+		h.repo.UpsertMistakes([]UserMistake{{UserID: userID, QuestionID: q.ID, Choice: answer, WrongCount: 1}})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"is_correct":     isCorrect,
+		"score":          score,
+		"correct_answer": q.Correct,
+		"rubric":         points,
+	})
+}
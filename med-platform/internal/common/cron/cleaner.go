@@ -5,6 +5,7 @@ import (
 	"med-platform/internal/common/db"    // 引入 DB
 	"med-platform/internal/common/logger"
 	"med-platform/internal/common/model" // 引入 Model
+	"med-platform/internal/common/service"
 	"os"
 	"path/filepath"
 	"time"
@@ -17,6 +18,7 @@ const (
 	MaxFileAge      = 24 * time.Hour      // 临时文件保留 24 小时
 	MaxNotifAge     = 90 * 24 * time.Hour // 🔥 通知保留 90 天 (3个月)
 	CleanInterval   = 1 * time.Hour       // 检查频率 (每小时唤醒一次)
+	TrashPurgeBatch = 500                 // 回收站过期条目每批处理的条数
 )
 
 // StartBackgroundTasks 启动所有后台清理任务 (非阻塞)
@@ -43,6 +45,7 @@ func StartBackgroundTasks() {
 func runTasks() {
 	cleanTempFiles()
 	cleanExpiredNotifications()
+	purgeExpiredTrash()
 }
 
 // 任务1：清理临时文件
@@ -104,4 +107,33 @@ func cleanExpiredNotifications() {
 			zap.String("截止日期", deadline.Format("2006-01-02")),
 		)
 	}
-}
\ No newline at end of file
+}
+
+// 🗑️ 任务3：彻底清除超过保留期的回收站条目 (按 ID 分批，积压很多时也不会一次全读进内存)
+func purgeExpiredTrash() {
+	now := time.Now()
+	purged := 0
+	var lastID uint
+	for {
+		var entries []model.TrashEntry
+		if err := db.DB.Where("status = ? AND expires_at < ? AND id > ?", model.TrashStatusTrashed, now, lastID).
+			Order("id asc").Limit(TrashPurgeBatch).Find(&entries).Error; err != nil {
+			logger.Log.Error("查询过期回收站条目失败", zap.Error(err))
+			break
+		}
+		for i := range entries {
+			if err := service.PurgeTrashEntry(&entries[i]); err != nil {
+				logger.Log.Error("清除回收站条目失败", zap.Uint("entry_id", entries[i].ID), zap.Error(err))
+				continue
+			}
+			purged++
+		}
+		if len(entries) < TrashPurgeBatch {
+			break
+		}
+		lastID = entries[len(entries)-1].ID
+	}
+	if purged > 0 {
+		logger.Log.Info("🗑️ 过期回收站条目已彻底清除", zap.Int("条目数", purged))
+	}
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+)
+
+// AuditLog 后台操作审计 (谁、什么时候、对什么做了什么、影响多少人)
+// 💡 放在公共 model 包，任何业务包都能写入而不产生循环引用
+type AuditLog struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+
+	OperatorID uint   `gorm:"index" json:"operator_id"` // 0 表示系统自动执行
+	Action     string `gorm:"type:varchar(50);index" json:"action"`
+	TargetType string `gorm:"type:varchar(50)" json:"target_type"` // "question" ...
+	TargetID   uint   `gorm:"index" json:"target_id"`
+	Affected   int    `json:"affected"` // 受影响的用户数
+	Detail     string `gorm:"type:text" json:"detail"`
+}
+
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
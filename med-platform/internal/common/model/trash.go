@@ -0,0 +1,70 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// 回收站条目类型
+const (
+	TrashBank     = "bank"     // 整个题库
+	TrashCategory = "category" // 章节 (含子章节)
+	TrashQuestion = "question" // 单题 / 批量题目
+)
+
+// 回收站条目状态
+const (
+	TrashStatusTrashed  = "trashed"  // 在回收站中，可恢复
+	TrashStatusRestored = "restored" // 已恢复
+	TrashStatusPurged   = "purged"   // 已彻底清除
+)
+
+// TrashImpact 删除影响面 (删除时统计，供恢复前评估)
+type TrashImpact struct {
+	Questions     int64 `json:"questions"`
+	Categories    int64 `json:"categories"`
+	AnswerRecords int64 `json:"answer_records"`
+	Notes         int64 `json:"notes"`
+	Favorites     int64 `json:"favorites"`
+	Mistakes      int64 `json:"mistakes"`
+	Feedbacks     int64 `json:"feedbacks"`
+	Users         int64 `json:"users"` // 有作答 / 笔记 / 收藏的用户数
+}
+
+// TrashEntry 回收站条目：一次删除操作 (题目软删除，关联数据整行转存到 TrashRow)
+// 💡 放在公共 model 包，定时清理任务 (common/cron) 不必依赖题库业务包
+type TrashEntry struct {
+	ID           uint   `gorm:"primarykey" json:"id"`
+	Kind         string `gorm:"type:varchar(20);index" json:"kind"`
+	Source       string `gorm:"type:varchar(100);index" json:"source"`
+	CategoryPath string `gorm:"type:varchar(255)" json:"category_path,omitempty"`
+	Title        string `gorm:"type:varchar(255)" json:"title"` // 列表展示用的名称
+
+	QuestionIDs []uint      `gorm:"type:jsonb;serializer:json" json:"-"` // 被软删除的题目 (含子题)
+	Impact      TrashImpact `gorm:"type:jsonb;serializer:json" json:"impact"`
+
+	Status     string     `gorm:"type:varchar(20);index" json:"status"`
+	DeletedBy  uint       `json:"deleted_by"`
+	RestoredBy uint       `json:"restored_by,omitempty"`
+	ExpiresAt  time.Time  `gorm:"index" json:"expires_at"` // 超过保留期由定时任务彻底清除
+	RestoredAt *time.Time `json:"restored_at,omitempty"`
+	PurgedAt   *time.Time `json:"purged_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (TrashEntry) TableName() string {
+	return "trash_entries"
+}
+
+// TrashRow 转存的一行关联数据 (整行存为 jsonb，恢复时原样写回，主键不变)
+type TrashRow struct {
+	ID      uint           `gorm:"primarykey" json:"id"`
+	EntryID uint           `gorm:"index" json:"entry_id"`
+	Origin  string         `gorm:"type:varchar(50);index" json:"origin"` // 原表名
+	Data    datatypes.JSON `gorm:"type:jsonb" json:"data"`
+}
+
+func (TrashRow) TableName() string {
+	return "trash_rows"
+}
@@ -0,0 +1,24 @@
+package service
+
+import (
+	"med-platform/internal/common/db"
+	"med-platform/internal/common/logger"
+	"med-platform/internal/common/model"
+
+	"go.uber.org/zap"
+)
+
+// WriteAudit 写入一条审计记录 (写失败只记日志，不影响业务本身)
+func WriteAudit(operatorID uint, action, targetType string, targetID uint, affected int, detail string) {
+	entry := model.AuditLog{
+		OperatorID: operatorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Affected:   affected,
+		Detail:     detail,
+	}
+	if err := db.DB.Create(&entry).Error; err != nil {
+		logger.Log.Error("写入审计记录失败", zap.String("action", action), zap.Uint("target_id", targetID), zap.Error(err))
+	}
+}
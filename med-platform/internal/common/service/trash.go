@@ -0,0 +1,34 @@
+package service
+
+import (
+	"time"
+
+	"med-platform/internal/common/db"
+	"med-platform/internal/common/model"
+
+	"gorm.io/gorm"
+)
+
+// PurgeTrashEntry 彻底清除回收站条目：删掉转存的关联数据与软删除的题目，之后无法再恢复
+// 💡 定时任务 purgeExpiredTrash (过期清理) 与后台"立即清除"共用
+func PurgeTrashEntry(entry *model.TrashEntry) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("entry_id = ?", entry.ID).Delete(&model.TrashRow{}).Error; err != nil {
+			return err
+		}
+		if len(entry.QuestionIDs) > 0 {
+			// 只删仍处于软删除状态的题目 (防止误删已被其他操作恢复的题)，连同其统计、版本历史与作答轨迹
+			purged := `SELECT id FROM questions WHERE id IN @ids AND deleted_at IS NOT NULL`
+			for _, table := range []string{"question_item_stats", "question_revisions", "answer_histories"} {
+				if err := tx.Exec(`DELETE FROM `+table+` WHERE question_id IN (`+purged+`)`, map[string]interface{}{"ids": entry.QuestionIDs}).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Exec(`DELETE FROM questions WHERE id IN ? AND deleted_at IS NOT NULL`, entry.QuestionIDs).Error; err != nil {
+				return err
+			}
+		}
+		now := time.Now()
+		return tx.Model(entry).Updates(map[string]interface{}{"status": model.TrashStatusPurged, "purged_at": now}).Error
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"mime/multipart"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -24,6 +25,17 @@ const (
 	TempDir          = "temp" // 临时池
 )
 
+// MaxAudioSize 题目音频 (听诊音等) 上限
+const MaxAudioSize = 20 * 1024 * 1024 // 20MB
+
+// AllowedAudioExtensions 允许的音频格式
+var AllowedAudioExtensions = map[string]bool{
+	".mp3": true,
+	".wav": true,
+	".m4a": true,
+	".ogg": true,
+}
+
 // AllowedExtensions 允许的图片格式
 var AllowedExtensions = map[string]bool{
 	".jpg":  true,
@@ -180,4 +192,80 @@ func ConfirmImages(imagePaths []string, subDir string) []string {
 		}
 	}
 	return finalPaths
-}
\ No newline at end of file
+}
+
+// SaveMediaWithHash 按指定格式白名单保存上传文件到临时目录 (哈希命名，同一文件只存一份)
+// 用于题目图片 / 音频等，提交保存时再用 ConfirmImages 固化到正式目录
+func SaveMediaWithHash(c *gin.Context, fileKey string, allowed map[string]bool, maxSize int64) (string, error) {
+	file, err := c.FormFile(fileKey)
+	if err != nil {
+		return "", errors.New("无法获取上传文件")
+	}
+	if file.Size > maxSize {
+		return "", fmt.Errorf("文件大小超过限制 (最大 %.2f MB)", float64(maxSize)/1024/1024)
+	}
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	if !allowed[ext] {
+		return "", errors.New("不支持的文件格式")
+	}
+	if ok, err := contentMatches(file, ext); err != nil {
+		return "", errors.New("文件解析失败")
+	} else if !ok {
+		return "", errors.New("文件内容与扩展名不符")
+	}
+
+	hashName, err := CalculateFileHash(file)
+	if err != nil {
+		return "", errors.New("文件解析失败")
+	}
+	tempDestDir := fmt.Sprintf("%s/%s", UploadRootDir, TempDir)
+	tempDestPath := fmt.Sprintf("%s/%s%s", tempDestDir, hashName, ext)
+	if _, err := os.Stat(tempDestDir); os.IsNotExist(err) {
+		os.MkdirAll(tempDestDir, 0755)
+	}
+	if _, err := os.Stat(tempDestPath); err == nil {
+		return tempDestPath[1:], nil
+	}
+	if err := c.SaveUploadedFile(file, tempDestPath); err != nil {
+		return "", errors.New("文件保存失败")
+	}
+	return tempDestPath[1:], nil
+}
+
+// contentMatches 读取文件头判断实际内容与扩展名是否相符 (防止改扩展名上传任意文件)
+func contentMatches(fileHeader *multipart.FileHeader, ext string) (bool, error) {
+	src, err := fileHeader.Open()
+	if err != nil {
+		return false, err
+	}
+	defer src.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(src, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	head = head[:n]
+
+	mime := http.DetectContentType(head)
+	switch ext {
+	case ".jpg", ".jpeg":
+		return mime == "image/jpeg", nil
+	case ".png":
+		return mime == "image/png", nil
+	case ".gif":
+		return mime == "image/gif", nil
+	case ".webp":
+		return mime == "image/webp", nil
+	case ".mp3":
+		// 不带 ID3 标签的 MP3 直接以帧同步字 (11 个 1) 开头
+		return mime == "audio/mpeg" || (len(head) >= 2 && head[0] == 0xFF && head[1]&0xE0 == 0xE0), nil
+	case ".wav":
+		return mime == "audio/wave", nil
+	case ".ogg":
+		return mime == "application/ogg", nil
+	case ".m4a":
+		return len(head) >= 12 && string(head[4:8]) == "ftyp", nil
+	}
+	return false, nil
+}
@@ -0,0 +1,56 @@
+package exam
+
+import (
+	"time"
+
+	"med-platform/internal/common/logger"
+
+	"go.uber.org/zap"
+)
+
+// AutoSubmitInterval 自动交卷巡检频率
+const AutoSubmitInterval = 1 * time.Minute
+
+// GradingTimeout 判分正常几秒内完成，停在判分中超过这么久视为进程中途退出，重新交卷
+const GradingTimeout = 5 * time.Minute
+
+// StartAutoSubmitTask 启动自动交卷守护任务
+// 用户中途关掉页面、不再请求接口时，依靠它把超时的试卷收卷判分
+// 请在 main.go 中调用: exam.StartAutoSubmitTask()
+func StartAutoSubmitTask() {
+	repo := NewRepository()
+
+	go func() {
+		ticker := time.NewTicker(AutoSubmitInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			stuck, err := repo.RequeueStuckGrading(time.Now().Add(-GradingTimeout))
+			if err != nil {
+				logger.Log.Error("重置卡住的判分失败", zap.Error(err))
+			}
+			for _, id := range stuck {
+				if _, err := repo.Finalize(id, true); err != nil {
+					logger.Log.Error("重新交卷失败", zap.Uint("exam_id", id), zap.Error(err))
+				}
+			}
+			if len(stuck) > 0 {
+				logger.Log.Warn("⚠️ 判分中断的试卷已重新交卷", zap.Int("数量", len(stuck)))
+			}
+
+			ids, err := repo.ListExpired(time.Now().Add(-SubmitGrace))
+			if err != nil {
+				logger.Log.Error("查询超时试卷失败", zap.Error(err))
+				continue
+			}
+			for _, id := range ids {
+				if _, err := repo.Finalize(id, true); err != nil {
+					logger.Log.Error("自动交卷失败", zap.Uint("exam_id", id), zap.Error(err))
+				}
+			}
+			if len(ids) > 0 {
+				logger.Log.Info("⏰ 超时试卷已自动交卷", zap.Int("数量", len(ids)))
+			}
+		}
+	}()
+}
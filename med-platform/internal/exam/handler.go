@@ -0,0 +1,396 @@
+package exam
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"med-platform/internal/common/db"
+	"med-platform/internal/question"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	MaxPaperQuestions = 200             // 单张试卷最多抽取的大题数
+	MaxDurationMin    = 600             // 最长考试时长 (分钟)
+	SubmitGrace       = 5 * time.Second // 网络延迟宽限：截止后 5 秒内的保存仍然有效
+)
+
+type Handler struct {
+	repo *Repository
+}
+
+func NewHandler() *Handler {
+	return &Handler{repo: NewRepository()}
+}
+
+// CreateExamReq 发起模考
+type CreateExamReq struct {
+	Title           string         `json:"title"`
+	Source          string         `json:"source" binding:"required"`
+	Category        string         `json:"category"` // 章节范围 (可选，为空则整库出题)
+	TypeMix         map[string]int `json:"type_mix" binding:"required"`
+	Difficulties    []string       `json:"difficulties"`
+	MinDiff         *float64       `json:"min_diff"`
+	MaxDiff         *float64       `json:"max_diff"`
+	DurationMinutes int            `json:"duration_minutes" binding:"required"`
+}
+
+// ==========================================
+// 1. 组卷并开考
+// ==========================================
+func (h *Handler) CreateExam(c *gin.Context) {
+	var req CreateExamReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID := c.MustGet("userID").(uint)
+
+	if req.DurationMinutes <= 0 || req.DurationMinutes > MaxDurationMin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "考试时长需在 1 ~ 600 分钟之间"})
+		return
+	}
+	totalWanted := 0
+	for _, n := range req.TypeMix {
+		if n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "题型数量不能为负数"})
+			return
+		}
+		totalWanted += n
+	}
+	if totalWanted == 0 || totalWanted > MaxPaperQuestions {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "单张试卷题量需在 1 ~ 200 道之间"})
+		return
+	}
+
+	// 1. 鉴权：指定章节直接校验；整库出题则只在已授权的科目里抽题
+	var scopes []string
+	if req.Category != "" {
+		if !question.CheckAccess(c, req.Source, req.Category) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "FORBIDDEN", "message": "🔒 您尚未获得该科目的访问授权"})
+			return
+		}
+		scopes = []string{req.Category}
+	} else {
+		scopes = question.AccessibleRoots(c, req.Source)
+		if len(scopes) == 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "FORBIDDEN", "message": "🔒 您尚未获得该题库任何科目的访问授权"})
+			return
+		}
+	}
+
+	// 2. 按蓝图组卷
+	bp := PaperBlueprint{
+		TypeMix:      req.TypeMix,
+		Difficulties: req.Difficulties,
+		MinDiff:      req.MinDiff,
+		MaxDiff:      req.MaxDiff,
+	}
+	ids, picked, err := h.repo.AssemblePaper(req.Source, scopes, bp)
+	if err != nil {
+		if err == ErrEmptyPaper {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "组卷失败"})
+		return
+	}
+
+	paper, err := h.repo.LoadPaper(ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "加载试卷失败"})
+		return
+	}
+
+	// 3. 创建会话，开始计时
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = req.Source + " 模拟考试"
+	}
+	bpJSON, _ := json.Marshal(bp)
+	now := time.Now()
+	session := &ExamSession{
+		UserID:       userID,
+		Title:        title,
+		Source:       req.Source,
+		CategoryPath: req.Category,
+		Blueprint:    bpJSON,
+		QuestionIDs:  ids,
+		ItemCount:    len(gradableItems(paper)),
+		Answers:      map[string]string{},
+		DurationSec:  req.DurationMinutes * 60,
+		StartedAt:    now,
+		Deadline:     now.Add(time.Duration(req.DurationMinutes) * time.Minute),
+		Status:       StatusOngoing,
+	}
+	if err := h.repo.Create(session); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建考试失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "组卷成功，考试开始计时",
+		"data":    h.renderSession(session, paper),
+		"picked":  picked, // 各题型实际抽到的数量，题量不足时前端可提示
+	})
+}
+
+// ==========================================
+// 2. 我的考试列表
+// ==========================================
+func (h *Handler) ListMyExams(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 50 {
+		pageSize = 10
+	}
+
+	// 顺手把已超时未交的卷子收掉，保证列表状态准确
+	h.finalizeExpiredOf(userID)
+
+	var total int64
+	var list []ExamSession
+	query := db.DB.Model(&ExamSession{}).Where("user_id = ?", userID)
+	query.Count(&total)
+	if err := query.Omit("report", "answers").Order("id desc").
+		Offset((page - 1) * pageSize).Limit(pageSize).Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取考试记录失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": list, "total": total, "page": page})
+}
+
+// ==========================================
+// 3. 获取试卷 (作答中隐藏答案与解析)
+// ==========================================
+func (h *Handler) GetExam(c *gin.Context) {
+	session, ok := h.loadSession(c)
+	if !ok {
+		return
+	}
+
+	paper, err := h.repo.LoadPaper(session.QuestionIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "加载试卷失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": h.renderSession(session, paper)})
+}
+
+// ==========================================
+// 4. 暂存作答 (交卷前不判分)
+// ==========================================
+func (h *Handler) SaveAnswers(c *gin.Context) {
+	var req struct {
+		Answers map[string]string `json:"answers" binding:"required"` // {"101": "A", "102": "BD"}
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, ok := h.loadSession(c)
+	if !ok {
+		return
+	}
+	if session.Status != StatusOngoing {
+		c.JSON(http.StatusConflict, gin.H{"error": "考试时间已到，试卷已自动交卷", "status": session.Status})
+		return
+	}
+
+	// 只接受本张试卷里的题目，防止夹带其他题目刷记录
+	allowed := make(map[string]bool)
+	paper, _ := h.repo.LoadPaper(session.QuestionIDs)
+	for _, item := range gradableItems(paper) {
+		allowed[strconv.Itoa(int(item.ID))] = true
+	}
+	filtered := make(map[string]string)
+	for k, v := range req.Answers {
+		if allowed[k] {
+			filtered[k] = v
+		}
+	}
+
+	if err := h.repo.SaveAnswers(session, filtered); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "作答已保存",
+		"answered":      len(session.Answers),
+		"remaining_sec": remainingSec(session),
+	})
+}
+
+// ==========================================
+// 5. 交卷
+// ==========================================
+func (h *Handler) SubmitExam(c *gin.Context) {
+	session, ok := h.loadSession(c)
+	if !ok {
+		return
+	}
+
+	if session.Status == StatusOngoing {
+		finished, err := h.repo.Finalize(session.ID, false)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "交卷失败，请重试"})
+			return
+		}
+		session = finished
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "交卷成功", "data": session})
+}
+
+// ==========================================
+// 6. 成绩报告
+// ==========================================
+func (h *Handler) GetReport(c *gin.Context) {
+	session, ok := h.loadSession(c)
+	if !ok {
+		return
+	}
+	if session.Status != StatusSubmitted {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "考试尚未结束，交卷后才能查看成绩"})
+		return
+	}
+
+	var report ExamReport
+	_ = json.Unmarshal(session.Report, &report)
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"id":             session.ID,
+			"title":          session.Title,
+			"source":         session.Source,
+			"started_at":     session.StartedAt,
+			"submitted_at":   session.SubmittedAt,
+			"auto_submitted": session.AutoSubmitted,
+			"report":         report,
+		},
+	})
+}
+
+// ---------------------------------------------------------
+// 辅助函数
+// ---------------------------------------------------------
+
+// loadSession 读取当前用户的考试会话；若已超时则先执行自动交卷
+func (h *Handler) loadSession(c *gin.Context) (*ExamSession, bool) {
+	userID := c.MustGet("userID").(uint)
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	session, err := h.repo.Get(uint(id), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "考试不存在"})
+		return nil, false
+	}
+
+	if session.Status == StatusOngoing && time.Now().After(session.Deadline.Add(SubmitGrace)) {
+		finished, err := h.repo.Finalize(session.ID, true)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "自动交卷失败，请稍后重试"})
+			return nil, false
+		}
+		session = finished
+	}
+	return session, true
+}
+
+func (h *Handler) finalizeExpiredOf(userID uint) {
+	var ids []uint
+	db.DB.Model(&ExamSession{}).
+		Where("user_id = ? AND status = ? AND deadline < ?", userID, StatusOngoing, time.Now().Add(-SubmitGrace)).
+		Pluck("id", &ids)
+	for _, id := range ids {
+		h.repo.Finalize(id, true)
+	}
+}
+
+func remainingSec(s *ExamSession) int {
+	if s.Status != StatusOngoing {
+		return 0
+	}
+	left := int(time.Until(s.Deadline).Seconds())
+	if left < 0 {
+		return 0
+	}
+	return left
+}
+
+// renderSession 组装试卷 JSON：作答中只给题干/选项，交卷后补上答案、解析与判分结果
+func (h *Handler) renderSession(s *ExamSession, paper []question.Question) gin.H {
+	finished := s.Status == StatusSubmitted
+
+	results := make(map[uint]ItemResult)
+	if finished {
+		var report ExamReport
+		_ = json.Unmarshal(s.Report, &report)
+		for _, item := range report.Items {
+			results[item.QuestionID] = item
+		}
+	}
+
+	renderOne := func(q question.Question, withOptions bool) map[string]interface{} {
+		var opts map[string]string
+		if withOptions && len(q.Options) > 0 {
+			_ = json.Unmarshal(q.Options, &opts)
+		}
+		item := map[string]interface{}{
+			"id":            q.ID,
+			"type":          q.Type,
+			"stem":          q.Stem,
+			"options":       opts,
+			"category_path": q.CategoryPath,
+			"user_choice":   s.Answers[strconv.Itoa(int(q.ID))],
+		}
+		if finished {
+			item["correct"] = q.Correct
+			item["analysis"] = q.Analysis
+			if r, ok := results[q.ID]; ok {
+				item["is_correct"] = r.IsCorrect
+				item["pending_self_rate"] = r.Pending
+			}
+		}
+		return item
+	}
+
+	var questions []map[string]interface{}
+	for _, q := range paper {
+		item := renderOne(q, true)
+		var children []map[string]interface{}
+		for _, child := range q.Children {
+			// B1 的选项挂在父题上，子题不重复下发
+			children = append(children, renderOne(child, !strings.Contains(q.Type, "B1")))
+		}
+		item["children"] = children
+		questions = append(questions, item)
+	}
+
+	return gin.H{
+		"id":             s.ID,
+		"title":          s.Title,
+		"source":         s.Source,
+		"category_path":  s.CategoryPath,
+		"status":         s.Status,
+		"item_count":     s.ItemCount,
+		"answered_count": len(s.Answers),
+		"duration_sec":   s.DurationSec,
+		"started_at":     s.StartedAt,
+		"deadline":       s.Deadline,
+		"remaining_sec":  remainingSec(s),
+		"submitted_at":   s.SubmittedAt,
+		"score":          s.Score,
+		"questions":      questions,
+	}
+}
@@ -0,0 +1,103 @@
+package exam
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// 模考会话状态
+const (
+	StatusOngoing   = "ongoing"   // 作答中
+	StatusGrading   = "grading"   // 交卷判分中 (并发交卷的互斥锁)
+	StatusSubmitted = "submitted" // 已交卷，可查看成绩报告
+)
+
+// ExamSession 模考会话：一次组卷 + 服务端限时作答 + 交卷出报告
+// 💡 与普通刷题的区别：交卷前答案只暂存在会话里，不判分、不返回解析
+type ExamSession struct {
+	ID     uint   `gorm:"primaryKey" json:"id"`
+	UserID uint   `gorm:"index;not null" json:"user_id"`
+	Title  string `gorm:"type:varchar(100)" json:"title"`
+
+	// 组卷条件快照 (题库 + 章节范围 + 题型配比/难度)
+	Source       string         `gorm:"type:varchar(100);index" json:"source"`
+	CategoryPath string         `gorm:"type:varchar(255)" json:"category_path"`
+	Blueprint    datatypes.JSON `gorm:"type:jsonb" json:"blueprint"`
+
+	// 试卷：按顺序排列的大题 ID (独立单题 / A3、A4、B1 的父题)
+	QuestionIDs []uint `gorm:"serializer:json" json:"question_ids"`
+	ItemCount   int    `json:"item_count"` // 可判分的小题总数 (组合题按子题计)
+
+	// 作答暂存区：{"101": "A", "102": "BD"}，交卷时才统一判分
+	Answers map[string]string `gorm:"serializer:json" json:"-"`
+
+	// 服务端计时
+	DurationSec   int        `json:"duration_sec"`
+	StartedAt     time.Time  `json:"started_at"`
+	Deadline      time.Time  `gorm:"index" json:"deadline"`
+	SubmittedAt   *time.Time `json:"submitted_at"`
+	Status        string     `gorm:"type:varchar(20);index;default:'ongoing'" json:"status"`
+	GradingAt     *time.Time `json:"-"`                                   // 进入判分的时间，卡在判分中过久的由巡检重新交卷
+	AutoSubmitted bool       `gorm:"default:false" json:"auto_submitted"` // 是否为到时自动交卷
+
+	// 成绩
+	AnsweredCount int            `json:"answered_count"`
+	CorrectCount  int            `json:"correct_count"`
+	Score         float64        `gorm:"type:decimal(5,2)" json:"score"` // 百分制
+	Report        datatypes.JSON `gorm:"type:jsonb" json:"report,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (ExamSession) TableName() string {
+	return "exam_sessions"
+}
+
+// PaperBlueprint 组卷蓝图 (存入 ExamSession.Blueprint 留档)
+type PaperBlueprint struct {
+	// 题型配比：{"A1": 20, "A2": 10, "A3": 2, "B1": 2, "X": 5}
+	// 组合题 (A3/A4/B1) 的数量按"组"计
+	TypeMix map[string]int `json:"type_mix"`
+
+	// 难度筛选：按导入时的难度标签 和/或 难度系数区间
+	Difficulties []string `json:"difficulties,omitempty"`
+	MinDiff      *float64 `json:"min_diff,omitempty"`
+	MaxDiff      *float64 `json:"max_diff,omitempty"`
+}
+
+// ExamReport 交卷后生成的成绩报告
+type ExamReport struct {
+	TotalItems    int            `json:"total_items"`
+	AnsweredItems int            `json:"answered_items"`
+	CorrectItems  int            `json:"correct_items"`
+	PendingItems  int            `json:"pending_items"` // 已作答、待自评的主观题 (不计入对错与得分)
+	Score         float64        `json:"score"`         // 只按客观题计算
+	UsedSec       int            `json:"used_sec"`
+	Subjects      []BreakdownRow `json:"subjects"`   // 按科目 (一级目录) 汇总
+	Categories    []BreakdownRow `json:"categories"` // 按完整章节路径汇总
+	Types         []BreakdownRow `json:"types"`      // 按题型汇总
+	Items         []ItemResult   `json:"items"`
+}
+
+// BreakdownRow 分项统计
+type BreakdownRow struct {
+	Name     string  `json:"name"`
+	Total    int     `json:"total"`
+	Answered int     `json:"answered"`
+	Correct  int     `json:"correct"`
+	Pending  int     `json:"pending"`  // 待自评的主观题
+	Accuracy float64 `json:"accuracy"` // 正确数 / (总题数 - 待自评) * 100
+}
+
+// ItemResult 单个小题的判分明细
+type ItemResult struct {
+	QuestionID    uint    `json:"question_id"`
+	ParentID      uint    `json:"parent_id,omitempty"`
+	UserChoice    string  `json:"user_choice"`
+	CorrectAnswer string  `json:"correct_answer"`
+	IsCorrect     bool    `json:"is_correct"`
+	Score         float64 `json:"score"`                       // 得分 0~1
+	Pending       bool    `json:"pending_self_rate,omitempty"` // 主观题：交卷后由用户对照要点自评
+}
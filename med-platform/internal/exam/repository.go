@@ -0,0 +1,439 @@
+package exam
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"med-platform/internal/answer"
+	"med-platform/internal/common/db"
+	"med-platform/internal/question"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 组合题型：数量按"组"计，判分按子题计
+var groupTypes = []string{"A3", "A4", "B1"}
+
+// 题型出卷顺序 (与导入排序口径一致：A1 → A2 → A3 → A4 → B1 → X)
+var typeOrder = map[string]int{"A1": 1, "A2": 2, "A3": 3, "A4": 4, "B1": 5, "X": 6}
+
+var ErrEmptyPaper = errors.New("符合条件的题目为空，无法组卷")
+
+type Repository struct {
+	answerRepo *answer.Repository
+}
+
+func NewRepository() *Repository {
+	return &Repository{answerRepo: answer.NewRepository()}
+}
+
+func isGroupType(t string) bool {
+	for _, g := range groupTypes {
+		if strings.Contains(strings.ToUpper(t), g) {
+			return true
+		}
+	}
+	return false
+}
+
+// ---------------------------------------------------------
+// 1. 组卷
+// ---------------------------------------------------------
+
+// AssemblePaper 按蓝图从题库随机抽题
+// categoryPaths 为允许抽题的章节前缀 (已经过鉴权过滤)，为空表示整库
+// 返回按题型顺序排列的大题 ID，以及各题型实际抽到的数量 (题量不足时小于配比)
+func (r *Repository) AssemblePaper(source string, categoryPaths []string, bp PaperBlueprint) ([]uint, map[string]int, error) {
+	types := make([]string, 0, len(bp.TypeMix))
+	for t, n := range bp.TypeMix {
+		if n > 0 {
+			types = append(types, strings.ToUpper(strings.TrimSpace(t)))
+		}
+	}
+	sort.SliceStable(types, func(i, j int) bool {
+		wi, wj := typeOrder[types[i]], typeOrder[types[j]]
+		if wi == 0 {
+			wi = 99
+		}
+		if wj == 0 {
+			wj = 99
+		}
+		if wi != wj {
+			return wi < wj
+		}
+		return types[i] < types[j]
+	})
+
+	var paper []uint
+	picked := make(map[string]int)
+	seen := make(map[uint]bool)
+
+	for _, t := range types {
+		want := bp.TypeMix[t]
+		query := db.DB.Model(&question.Question{}).
+			Where("source = ?", source).
+			Where("parent_id IS NULL OR parent_id = 0").
+			Where("UPPER(type) LIKE ?", "%"+t+"%")
+
+		if len(categoryPaths) > 0 {
+			scope := db.DB.Where("category_path LIKE ?", categoryPaths[0]+"%")
+			for _, p := range categoryPaths[1:] {
+				scope = scope.Or("category_path LIKE ?", p+"%")
+			}
+			query = query.Where(scope)
+		}
+		query = applyDifficulty(query, t, bp)
+
+		var ids []uint
+		if err := query.Order("RANDOM()").Limit(want).Pluck("id", &ids).Error; err != nil {
+			return nil, nil, err
+		}
+		for _, id := range ids {
+			// "A1" 与 "A1/A2" 这类复合题型标签可能被重复命中，去重
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			paper = append(paper, id)
+			picked[t]++
+		}
+	}
+
+	if len(paper) == 0 {
+		return nil, picked, ErrEmptyPaper
+	}
+	return paper, picked, nil
+}
+
+// applyDifficulty 难度筛选：组合题的父题没有难度数据，改为要求"至少一道子题命中"
+func applyDifficulty(query *gorm.DB, qType string, bp PaperBlueprint) *gorm.DB {
+	if len(bp.Difficulties) == 0 && bp.MinDiff == nil && bp.MaxDiff == nil {
+		return query
+	}
+
+	col := ""
+	if isGroupType(qType) {
+		col = "ch."
+	}
+	var conds []string
+	var args []interface{}
+	if len(bp.Difficulties) > 0 {
+		conds = append(conds, col+"difficulty IN ?")
+		args = append(args, bp.Difficulties)
+	}
+	if bp.MinDiff != nil {
+		conds = append(conds, col+"diff_value >= ?")
+		args = append(args, *bp.MinDiff)
+	}
+	if bp.MaxDiff != nil {
+		conds = append(conds, col+"diff_value <= ?")
+		args = append(args, *bp.MaxDiff)
+	}
+	cond := strings.Join(conds, " AND ")
+
+	if col != "" {
+		return query.Where("EXISTS (SELECT 1 FROM questions ch WHERE ch.parent_id = questions.id AND ch.deleted_at IS NULL AND "+cond+")", args...)
+	}
+	return query.Where(cond, args...)
+}
+
+// LoadPaper 按试卷顺序加载大题 (含子题)
+func (r *Repository) LoadPaper(ids []uint) ([]question.Question, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var list []question.Question
+	err := db.DB.Preload("Children", func(tx *gorm.DB) *gorm.DB { return tx.Order("id asc") }).
+		Where("id IN ?", ids).Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+
+	pos := make(map[uint]int, len(ids))
+	for i, id := range ids {
+		pos[id] = i
+	}
+	sort.SliceStable(list, func(i, j int) bool { return pos[list[i].ID] < pos[list[j].ID] })
+	return list, nil
+}
+
+// gradableItems 展开试卷中所有需要判分的小题 (独立单题本身 / 组合题的子题)
+func gradableItems(paper []question.Question) []question.Question {
+	var items []question.Question
+	for _, q := range paper {
+		if len(q.Children) > 0 {
+			items = append(items, q.Children...)
+		} else {
+			items = append(items, q)
+		}
+	}
+	return items
+}
+
+// ---------------------------------------------------------
+// 2. 会话读写
+// ---------------------------------------------------------
+
+func (r *Repository) Create(s *ExamSession) error {
+	return db.DB.Create(s).Error
+}
+
+func (r *Repository) Get(id, userID uint) (*ExamSession, error) {
+	var s ExamSession
+	err := db.DB.Where("id = ? AND user_id = ?", id, userID).First(&s).Error
+	return &s, err
+}
+
+// SaveAnswers 合并保存作答暂存区 (空字符串表示清空该题作答)
+// 🔥 行锁 (SELECT ... FOR UPDATE) 内读-改-写，并发的自动保存按顺序合并，不会互相覆盖
+// 只允许在作答中的会话上操作，锁内再判断状态，防止与交卷并发
+func (r *Repository) SaveAnswers(s *ExamSession, answers map[string]string) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		var locked ExamSession
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "status", "answers").First(&locked, s.ID).Error; err != nil {
+			return err
+		}
+		if locked.Status != StatusOngoing {
+			return errors.New("试卷已交卷，无法继续作答")
+		}
+
+		if locked.Answers == nil {
+			locked.Answers = make(map[string]string)
+		}
+		for k, v := range answers {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				delete(locked.Answers, k)
+			} else {
+				locked.Answers[k] = v
+			}
+		}
+		payload, _ := json.Marshal(locked.Answers)
+		if err := tx.Model(&ExamSession{}).Where("id = ?", s.ID).Update("answers", string(payload)).Error; err != nil {
+			return err
+		}
+		s.Answers = locked.Answers
+		return nil
+	})
+}
+
+// ListExpired 查出所有已超时但仍处于作答中的会话 (自动交卷任务使用)
+func (r *Repository) ListExpired(now time.Time) ([]uint, error) {
+	var ids []uint
+	err := db.DB.Model(&ExamSession{}).
+		Where("status = ? AND deadline < ?", StatusOngoing, now).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// RequeueStuckGrading 判分途中进程退出的会话会一直停在 grading：
+// 进入判分早于 before 的退回作答中，返回其 ID 交给巡检重新交卷
+func (r *Repository) RequeueStuckGrading(before time.Time) ([]uint, error) {
+	var ids []uint
+	stuck := db.DB.Model(&ExamSession{}).Where("status = ? AND (grading_at IS NULL OR grading_at < ?)", StatusGrading, before)
+	if err := stuck.Pluck("id", &ids).Error; err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	err := db.DB.Model(&ExamSession{}).Where("id IN ? AND status = ?", ids, StatusGrading).
+		Update("status", StatusOngoing).Error
+	return ids, err
+}
+
+// ---------------------------------------------------------
+// 3. 交卷判分
+// ---------------------------------------------------------
+
+// Finalize 交卷：判分 → 写入作答流水/错题本 → 生成成绩报告
+// auto=true 表示到时自动交卷，交卷时间记为截止时间
+// 🔥 先用条件更新把状态从 ongoing 抢占为 grading，保证手动交卷与自动交卷只会有一个真正执行
+func (r *Repository) Finalize(id uint, auto bool) (*ExamSession, error) {
+	claim := db.DB.Model(&ExamSession{}).
+		Where("id = ? AND status = ?", id, StatusOngoing).
+		Updates(map[string]interface{}{"status": StatusGrading, "grading_at": time.Now()})
+	if claim.Error != nil {
+		return nil, claim.Error
+	}
+
+	var s ExamSession
+	if err := db.DB.First(&s, id).Error; err != nil {
+		return nil, err
+	}
+	if claim.RowsAffected == 0 {
+		// 已被别的请求交卷，直接返回当前状态
+		return &s, nil
+	}
+
+	release := func() {
+		db.DB.Model(&ExamSession{}).Where("id = ? AND status = ?", id, StatusGrading).Update("status", StatusOngoing)
+	}
+
+	paper, err := r.LoadPaper(s.QuestionIDs)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	var records []*answer.AnswerRecord
+	var mistakes []answer.UserMistake
+	report := ExamReport{}
+	subjects := newBreakdown()
+	categories := newBreakdown()
+	types := newBreakdown()
+	earned := 0.0 // 累计得分 (X 型题可能部分得分)
+
+	for _, item := range gradableItems(paper) {
+		raw := s.Answers[strconv.Itoa(int(item.ID))]
+		answered := strings.TrimSpace(raw) != ""
+		// 主观题不自动判对错：报告里标记为待自评，交卷后走 SelfRate 记分，不进错题本
+		pending := answered && question.IsSubjective(item.Type)
+		result := answer.Grade(item, raw)
+		userChoice, correctChoice, isCorrect := result.UserChoice, result.CorrectChoice, result.IsCorrect
+		if !answered || pending {
+			isCorrect = false
+			result.Score = 0
+		}
+		earned += result.Score
+
+		report.TotalItems++
+		if pending {
+			report.AnsweredItems++
+			report.PendingItems++
+		} else if answered {
+			report.AnsweredItems++
+			records = append(records, &answer.AnswerRecord{
+				UserID:           s.UserID,
+				QuestionID:       item.ID,
+				CategoryID:       item.CategoryID,
+				Choice:           userChoice,
+				IsCorrect:        isCorrect,
+				Score:            result.Score,
+				QuestionRevision: item.Revision,
+			})
+			if !isCorrect {
+				mistakes = append(mistakes, answer.UserMistake{
+					UserID:     s.UserID,
+					QuestionID: item.ID,
+					Choice:     userChoice,
+					WrongCount: 1,
+				})
+			}
+		}
+		if isCorrect {
+			report.CorrectItems++
+		}
+
+		subject := item.Category
+		if subject == "" {
+			subject = strings.TrimSpace(strings.Split(item.CategoryPath, ">")[0])
+		}
+		subjects.add(subject, answered, isCorrect, pending)
+		categories.add(item.CategoryPath, answered, isCorrect, pending)
+		types.add(item.Type, answered, isCorrect, pending)
+
+		var parentID uint
+		if item.ParentID != nil {
+			parentID = *item.ParentID
+		}
+		report.Items = append(report.Items, ItemResult{
+			QuestionID:    item.ID,
+			ParentID:      parentID,
+			UserChoice:    userChoice,
+			CorrectAnswer: correctChoice,
+			IsCorrect:     isCorrect,
+			Score:         result.Score,
+			Pending:       pending,
+		})
+	}
+
+	now := time.Now()
+	submittedAt := now
+	if auto && s.Deadline.Before(now) {
+		submittedAt = s.Deadline
+	}
+	if graded := report.TotalItems - report.PendingItems; graded > 0 {
+		report.Score = math.Round(earned/float64(graded)*10000) / 100
+	}
+	report.UsedSec = int(submittedAt.Sub(s.StartedAt).Seconds())
+	report.Subjects = subjects.rows()
+	report.Categories = categories.rows()
+	report.Types = types.rows()
+
+	reportJSON, _ := json.Marshal(report)
+	s.Status = StatusSubmitted
+	s.SubmittedAt = &submittedAt
+	s.AutoSubmitted = auto
+	s.AnsweredCount = report.AnsweredItems
+	s.CorrectCount = report.CorrectItems
+	s.Score = report.Score
+	s.Report = reportJSON
+
+	// 与普通刷题走同一条写入链路，保证 AnswerHistory / UserDailyStat 口径一致
+	// 作答流水、错题本与成绩写在同一事务里：任一步失败整体回滚，会话退回作答中等待重试
+	err = r.answerRepo.SaveBatch(records, mistakes, func(tx *gorm.DB) error {
+		return tx.Model(&ExamSession{}).Where("id = ?", s.ID).Updates(map[string]interface{}{
+			"status":         s.Status,
+			"submitted_at":   s.SubmittedAt,
+			"auto_submitted": s.AutoSubmitted,
+			"answered_count": s.AnsweredCount,
+			"correct_count":  s.CorrectCount,
+			"score":          s.Score,
+			"report":         s.Report,
+		}).Error
+	})
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return &s, nil
+}
+
+// breakdown 分项统计累加器 (保持首次出现的顺序)
+type breakdown struct {
+	order []string
+	rowsM map[string]*BreakdownRow
+}
+
+func newBreakdown() *breakdown {
+	return &breakdown{rowsM: make(map[string]*BreakdownRow)}
+}
+
+func (b *breakdown) add(name string, answered, correct, pending bool) {
+	if name == "" {
+		name = "未分类"
+	}
+	row, ok := b.rowsM[name]
+	if !ok {
+		row = &BreakdownRow{Name: name}
+		b.rowsM[name] = row
+		b.order = append(b.order, name)
+	}
+	row.Total++
+	if answered {
+		row.Answered++
+	}
+	if correct {
+		row.Correct++
+	}
+	if pending {
+		row.Pending++
+	}
+}
+
+func (b *breakdown) rows() []BreakdownRow {
+	list := make([]BreakdownRow, 0, len(b.order))
+	for _, name := range b.order {
+		row := *b.rowsM[name]
+		if graded := row.Total - row.Pending; graded > 0 {
+			row.Accuracy = math.Round(float64(row.Correct)/float64(graded)*1000) / 10
+		}
+		list = append(list, row)
+	}
+	return list
+}
@@ -0,0 +1,207 @@
+package itemstat
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// 统计口径参数
+const (
+	GroupRatio        = 0.27 // 高/低分组比例 (Kelley 27% 法)
+	MinRespondents    = 20   // 作答人数不足时只记录数据，不打质量标记
+	MinAbilityItems   = 10   // 用户在本题库至少做过 N 道题，才参与高低分组排名
+	LowDiscrimination = 0.2  // D < 0.2 视为区分度差 (Ebel 标准)
+	DiffMismatchGap   = 0.2  // 实测通过率与人工难度系数相差超过 0.2 视为标注失准
+)
+
+// response 一条首次作答
+type response struct {
+	UserID     uint
+	QuestionID uint
+	Choice     string
+	IsCorrect  bool
+}
+
+// itemMeta 参与分析的题目信息 (B1 子题的选项挂在父题上)
+type itemMeta struct {
+	ID            uint
+	Source        string
+	CategoryPath  string
+	Type          string
+	Correct       string
+	DiffValue     float64
+	Options       datatypes.JSON
+	ParentOptions datatypes.JSON
+}
+
+// normalizeChoice 统一选项格式：只保留 A-F 字母，大写、去重、排序
+func normalizeChoice(s string) string {
+	seen := make(map[rune]bool)
+	var letters []string
+	for _, r := range strings.ToUpper(s) {
+		if r >= 'A' && r <= 'F' && !seen[r] {
+			seen[r] = true
+			letters = append(letters, string(r))
+		}
+	}
+	sort.Strings(letters)
+	return strings.Join(letters, "")
+}
+
+// optionLetters 题目的全部选项字母 (题目自带选项优先，其次父题选项)
+func (m itemMeta) optionLetters() []string {
+	raw := m.Options
+	if len(raw) == 0 || string(raw) == "null" {
+		raw = m.ParentOptions
+	}
+	var opts map[string]string
+	_ = json.Unmarshal(raw, &opts)
+
+	set := make(map[string]bool)
+	for k := range opts {
+		if k = normalizeChoice(k); len(k) == 1 {
+			set[k] = true
+		}
+	}
+	for _, r := range normalizeChoice(m.Correct) {
+		set[string(r)] = true
+	}
+
+	letters := make([]string, 0, len(set))
+	for k := range set {
+		letters = append(letters, k)
+	}
+	sort.Strings(letters)
+	return letters
+}
+
+// analyzeItem 对单道题做项目分析
+// ability 为用户在本题库的能力值 (首次作答正确率)，未进入排名的用户不参与高低分组
+func analyzeItem(meta itemMeta, resps []response, ability map[uint]float64, now time.Time) QuestionItemStat {
+	stat := QuestionItemStat{
+		QuestionID:   meta.ID,
+		Source:       meta.Source,
+		CategoryPath: meta.CategoryPath,
+		Type:         meta.Type,
+		KeyedDiff:    meta.DiffValue,
+		Respondents:  len(resps),
+		ComputedAt:   now,
+	}
+	if len(resps) == 0 {
+		stat.OptionStats = datatypes.JSON("{}")
+		return stat
+	}
+
+	// 1. 难度 p 值
+	correct := 0
+	for _, r := range resps {
+		if r.IsCorrect {
+			correct++
+		}
+	}
+	stat.PValue = round4(float64(correct) / float64(len(resps)))
+
+	// 2. 高低分组 (按能力值排序，取首尾 27%)
+	var ranked []response
+	for _, r := range resps {
+		if _, ok := ability[r.UserID]; ok {
+			ranked = append(ranked, r)
+		}
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		ai, aj := ability[ranked[i].UserID], ability[ranked[j].UserID]
+		if ai != aj {
+			return ai > aj
+		}
+		return ranked[i].UserID < ranked[j].UserID
+	})
+	n := int(math.Round(float64(len(ranked)) * GroupRatio))
+	var upper, lower []response
+	if n > 0 {
+		upper, lower = ranked[:n], ranked[len(ranked)-n:]
+	}
+	stat.UpperN, stat.LowerN = len(upper), len(lower)
+	if n > 0 {
+		stat.Discrimination = round4(passRate(upper) - passRate(lower))
+	}
+
+	// 3. 选项分析 (多选题按"是否勾选该字母"分别统计)
+	key := normalizeChoice(meta.Correct)
+	letters := meta.optionLetters()
+	options := make(map[string]OptionStat, len(letters))
+	for _, l := range letters {
+		options[l] = OptionStat{
+			IsKey:     strings.Contains(key, l),
+			Count:     countPick(resps, l),
+			Rate:      round4(pickRate(resps, l)),
+			UpperRate: round4(pickRate(upper, l)),
+			LowerRate: round4(pickRate(lower, l)),
+		}
+	}
+	stat.OptionStats, _ = json.Marshal(options)
+
+	// 4. 质量标记 (样本量足够才下结论)
+	if stat.Respondents >= MinRespondents {
+		if n > 0 {
+			stat.LowDiscrimination = stat.Discrimination < LowDiscrimination
+
+			// 疑似答案错误：高分组中最受欢迎的干扰项，比最不受欢迎的正确选项还多
+			minKey, bestDistractor, hint := math.MaxFloat64, -1.0, ""
+			for _, l := range letters {
+				o := options[l]
+				if o.IsKey {
+					minKey = math.Min(minKey, o.UpperRate)
+				} else if o.UpperRate > bestDistractor {
+					bestDistractor, hint = o.UpperRate, l
+				}
+			}
+			if key != "" && hint != "" && bestDistractor > minKey {
+				stat.Miskeyed = true
+				stat.MiskeyHint = hint
+			}
+		}
+		if meta.DiffValue > 0 {
+			stat.DiffMismatch = math.Abs(stat.PValue-meta.DiffValue) > DiffMismatchGap
+		}
+	}
+	return stat
+}
+
+func passRate(group []response) float64 {
+	if len(group) == 0 {
+		return 0
+	}
+	c := 0
+	for _, r := range group {
+		if r.IsCorrect {
+			c++
+		}
+	}
+	return float64(c) / float64(len(group))
+}
+
+func countPick(group []response, letter string) int {
+	c := 0
+	for _, r := range group {
+		if strings.Contains(normalizeChoice(r.Choice), letter) {
+			c++
+		}
+	}
+	return c
+}
+
+func pickRate(group []response, letter string) float64 {
+	if len(group) == 0 {
+		return 0
+	}
+	return float64(countPick(group, letter)) / float64(len(group))
+}
+
+func round4(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
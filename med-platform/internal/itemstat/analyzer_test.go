@@ -0,0 +1,130 @@
+package itemstat
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+func TestNormalizeChoice(t *testing.T) {
+	tests := map[string]string{
+		"":      "",
+		"a":     "A",
+		"DBA":   "ABD",
+		"b, a":  "AB",
+		"AAB":   "AB",
+		"AG":    "A",
+		"对":     "",
+		"e f c": "CEF",
+	}
+	for in, want := range tests {
+		if got := normalizeChoice(in); got != want {
+			t.Errorf("normalizeChoice(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestOptionLetters(t *testing.T) {
+	tests := []struct {
+		name string
+		meta itemMeta
+		want []string
+	}{
+		{"自带选项", itemMeta{Options: datatypes.JSON(`{"A":"x","B":"y","C":"z"}`), Correct: "B"}, []string{"A", "B", "C"}},
+		{"B1 子题取父题选项", itemMeta{Options: datatypes.JSON(`null`), ParentOptions: datatypes.JSON(`{"A":"x","B":"y","E":"z"}`), Correct: "E"}, []string{"A", "B", "E"}},
+		{"答案字母补进选项", itemMeta{Options: datatypes.JSON(`{"A":"x"}`), Correct: "AD"}, []string{"A", "D"}},
+	}
+	for _, tt := range tests {
+		if got := tt.meta.optionLetters(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: optionLetters = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// respondents 造 n 个作答者：能力值随 user_id 递增，user_id > n/2 的选 high，其余选 low
+func respondents(n int, key, high, low string) ([]response, map[uint]float64) {
+	resps := make([]response, 0, n)
+	ability := make(map[uint]float64, n)
+	for i := 1; i <= n; i++ {
+		choice := low
+		if i > n/2 {
+			choice = high
+		}
+		resps = append(resps, response{UserID: uint(i), QuestionID: 1, Choice: choice, IsCorrect: choice == key})
+		ability[uint(i)] = float64(i) / float64(n)
+	}
+	return resps, ability
+}
+
+func TestAnalyzeItem(t *testing.T) {
+	now := time.Now()
+	meta := itemMeta{ID: 1, Correct: "A", Options: datatypes.JSON(`{"A":"x","B":"y","C":"z"}`)}
+
+	tests := []struct {
+		name      string
+		n         int
+		high, low string
+		diffValue float64
+		pValue    float64
+		disc      float64
+		lowDisc   bool
+		miskeyed  bool
+		hint      string
+		mismatch  bool
+	}{
+		{"区分度好", 100, "A", "B", 0.5, 0.5, 1, false, false, "", false},
+		{"高分组都选干扰项", 100, "B", "A", 0, 0.5, -1, true, true, "B", false},
+		{"难度标注失准", 100, "A", "B", 0.9, 0.5, 1, false, false, "", true},
+		{"样本不足不打标记", 10, "B", "A", 0.9, 0.5, -1, false, false, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := meta
+			m.DiffValue = tt.diffValue
+			resps, ability := respondents(tt.n, "A", tt.high, tt.low)
+			stat := analyzeItem(m, resps, ability, now)
+
+			if stat.Respondents != tt.n || stat.PValue != tt.pValue || stat.Discrimination != tt.disc {
+				t.Errorf("respondents=%d p=%v D=%v, want %d %v %v", stat.Respondents, stat.PValue, stat.Discrimination, tt.n, tt.pValue, tt.disc)
+			}
+			if stat.LowDiscrimination != tt.lowDisc || stat.Miskeyed != tt.miskeyed || stat.MiskeyHint != tt.hint || stat.DiffMismatch != tt.mismatch {
+				t.Errorf("flags low=%v miskeyed=%v hint=%q mismatch=%v, want %v %v %q %v",
+					stat.LowDiscrimination, stat.Miskeyed, stat.MiskeyHint, stat.DiffMismatch, tt.lowDisc, tt.miskeyed, tt.hint, tt.mismatch)
+			}
+		})
+	}
+}
+
+func TestAnalyzeItemGroupsAndOptions(t *testing.T) {
+	meta := itemMeta{ID: 1, Correct: "A", Options: datatypes.JSON(`{"A":"x","B":"y","C":"z"}`)}
+	resps, ability := respondents(100, "A", "A", "B")
+	// 没有能力值的用户计入 p 值，但不参与高低分组
+	resps = append(resps, response{UserID: 1000, QuestionID: 1, Choice: "C"})
+
+	stat := analyzeItem(meta, resps, ability, time.Now())
+	if stat.UpperN != 27 || stat.LowerN != 27 {
+		t.Errorf("upper=%d lower=%d, want 27 27", stat.UpperN, stat.LowerN)
+	}
+	var options map[string]OptionStat
+	if err := json.Unmarshal(stat.OptionStats, &options); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]OptionStat{
+		"A": {IsKey: true, Count: 50, Rate: 0.495, UpperRate: 1, LowerRate: 0},
+		"B": {Count: 50, Rate: 0.495, UpperRate: 0, LowerRate: 1},
+		"C": {Count: 1, Rate: 0.0099},
+	}
+	if !reflect.DeepEqual(options, want) {
+		t.Errorf("options = %+v, want %+v", options, want)
+	}
+}
+
+func TestAnalyzeItemEmpty(t *testing.T) {
+	stat := analyzeItem(itemMeta{ID: 1, Correct: "A"}, nil, nil, time.Now())
+	if stat.Respondents != 0 || string(stat.OptionStats) != "{}" {
+		t.Errorf("empty stat = %+v", stat)
+	}
+}
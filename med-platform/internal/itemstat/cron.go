@@ -0,0 +1,78 @@
+package itemstat
+
+import (
+	"sync/atomic"
+	"time"
+
+	"med-platform/internal/common/logger"
+
+	"go.uber.org/zap"
+)
+
+// AnalysisInterval 项目分析重算频率 (作答数据变化缓慢，每天一次足够)
+const AnalysisInterval = 24 * time.Hour
+
+// running 防止定时任务与后台手动触发同时跑
+var running int32
+
+// StartItemAnalysisTask 启动题目质量分析守护任务
+// 请在 main.go 中调用: itemstat.StartItemAnalysisTask()
+func StartItemAnalysisTask() {
+	go func() {
+		// 启动缓冲，避开服务刚启动时的流量
+		time.Sleep(1 * time.Minute)
+		RunAnalysis()
+
+		ticker := time.NewTicker(AnalysisInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			RunAnalysis()
+		}
+	}()
+}
+
+// RunAnalysis 逐个题库重算一遍；已有任务在跑时直接返回 false
+func RunAnalysis() bool {
+	if !atomic.CompareAndSwapInt32(&running, 0, 1) {
+		return false
+	}
+	defer atomic.StoreInt32(&running, 0)
+
+	repo := NewRepository()
+	sources, err := repo.ListSources()
+	if err != nil {
+		logger.Log.Error("项目分析：读取题库列表失败", zap.Error(err))
+		return true
+	}
+
+	start := time.Now()
+	total := 0
+	for _, source := range sources {
+		n, err := repo.AnalyzeSource(source)
+		if err != nil {
+			logger.Log.Error("项目分析失败", zap.String("题库", source), zap.Error(err))
+			continue
+		}
+		total += n
+	}
+	logger.Log.Info("📊 题目质量分析完成", zap.Int("题目数", total), zap.Duration("耗时", time.Since(start)))
+	return true
+}
+
+// RefreshSource 单独重算一个题库 (例如改答案重判后)；全量分析正在跑时跳过，由它兜底
+func RefreshSource(source string) {
+	if source == "" || !atomic.CompareAndSwapInt32(&running, 0, 1) {
+		return
+	}
+	go func() {
+		defer atomic.StoreInt32(&running, 0)
+		if _, err := NewRepository().AnalyzeSource(source); err != nil {
+			logger.Log.Error("项目分析失败", zap.String("题库", source), zap.Error(err))
+		}
+	}()
+}
+
+// IsRunning 当前是否有分析任务在跑
+func IsRunning() bool {
+	return atomic.LoadInt32(&running) == 1
+}
@@ -0,0 +1,74 @@
+package itemstat
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	repo *Repository
+}
+
+func NewHandler() *Handler {
+	return &Handler{repo: NewRepository()}
+}
+
+// AdminList 题目质量分析列表
+// GET /admin/item-stats?source=&category=&type=&flag=miskeyed&min_respondents=20&sort=p_value&order=asc
+// flag 可选：miskeyed (疑似答案错误) / low_discrimination (区分度低) / diff_mismatch (难度标注失准) / any
+func (h *Handler) AdminList(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	f := ListFilter{
+		Source:   c.Query("source"),
+		Category: c.Query("category"),
+		Type:     c.Query("type"),
+		Flag:     c.Query("flag"),
+		SortBy:   c.Query("sort"),
+		Desc:     c.Query("order") == "desc",
+	}
+	f.MinRespondents, _ = strconv.Atoi(c.Query("min_respondents"))
+	if v, err := strconv.ParseFloat(c.Query("min_p"), 64); err == nil {
+		f.MinPValue = &v
+	}
+	if v, err := strconv.ParseFloat(c.Query("max_p"), 64); err == nil {
+		f.MaxPValue = &v
+	}
+
+	list, total, err := h.repo.List(f, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取题目分析失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list, "total": total, "page": page, "running": IsRunning()})
+}
+
+// AdminGet 单题的项目分析详情
+func (h *Handler) AdminGet(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	stat, err := h.repo.Get(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "该题暂无分析数据"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": stat})
+}
+
+// AdminRecompute 手动触发一次重算 (后台异步执行)
+func (h *Handler) AdminRecompute(c *gin.Context) {
+	if IsRunning() {
+		c.JSON(http.StatusConflict, gin.H{"error": "分析任务正在进行中，请稍后刷新"})
+		return
+	}
+	go RunAnalysis()
+	c.JSON(http.StatusOK, gin.H{"message": "已开始重算，完成后刷新列表即可"})
+}
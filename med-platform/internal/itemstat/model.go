@@ -0,0 +1,53 @@
+package itemstat
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// QuestionItemStat 题目质量分析 (经典测量理论的项目分析结果，每道可判分小题一行)
+// 由后台任务定期根据真实作答数据重算，与导入时人工填写的 Difficulty/DiffValue 并存对照
+type QuestionItemStat struct {
+	QuestionID uint `gorm:"primaryKey;autoIncrement:false" json:"question_id"`
+
+	// 冗余题目归属，方便后台列表直接筛选，不必每次 JOIN
+	Source       string `gorm:"type:varchar(100);index" json:"source"`
+	CategoryPath string `gorm:"type:varchar(255);index" json:"category_path"`
+	Type         string `gorm:"type:varchar(20)" json:"type"`
+
+	Respondents int `gorm:"index" json:"respondents"` // 参与统计的作答人数 (每人只取首次作答)
+
+	// 难度：实测通过率 p (0~1)，越大越容易；KeyedDiff 为人工标注的难度系数快照
+	PValue    float64 `gorm:"type:decimal(5,4)" json:"p_value"`
+	KeyedDiff float64 `gorm:"type:decimal(3,2)" json:"keyed_diff"`
+
+	// 区分度：D = 高分组通过率 - 低分组通过率 (高/低分组各取能力排名前后 27%)
+	Discrimination float64 `gorm:"type:decimal(5,4)" json:"discrimination"`
+	UpperN         int     `json:"upper_n"`
+	LowerN         int     `json:"lower_n"`
+
+	// 选项分析：{"A": {...}, "B": {...}}
+	OptionStats datatypes.JSON `gorm:"type:jsonb" json:"option_stats"`
+
+	// 质量标记
+	Miskeyed          bool   `gorm:"default:false;index" json:"miskeyed"`           // 疑似答案错误：高分组里某个干扰项比正确答案更受欢迎
+	MiskeyHint        string `gorm:"type:varchar(10)" json:"miskey_hint,omitempty"` // 最"抢眼"的干扰项
+	LowDiscrimination bool   `gorm:"default:false;index" json:"low_discrimination"` // 区分度过低 (D < 0.2)
+	DiffMismatch      bool   `gorm:"default:false;index" json:"diff_mismatch"`      // 实测难度与人工标注偏差过大
+
+	ComputedAt time.Time `json:"computed_at"`
+}
+
+func (QuestionItemStat) TableName() string {
+	return "question_item_stats"
+}
+
+// OptionStat 单个选项的被选情况
+type OptionStat struct {
+	IsKey     bool    `json:"is_key"`     // 是否为正确答案
+	Count     int     `json:"count"`      // 选择人数
+	Rate      float64 `json:"rate"`       // 选择率 (全体)
+	UpperRate float64 `json:"upper_rate"` // 高分组选择率
+	LowerRate float64 `json:"lower_rate"` // 低分组选择率
+}
@@ -0,0 +1,252 @@
+package itemstat
+
+import (
+	"time"
+
+	"med-platform/internal/common/db"
+
+	"gorm.io/gorm/clause"
+)
+
+// upsertBatchSize 结果分批写库，避免单条 SQL 过大
+const upsertBatchSize = 200
+
+type Repository struct{}
+
+func NewRepository() *Repository {
+	return &Repository{}
+}
+
+// firstResponsesSQL 某题库下"每人每题的首次作答"
+// 💡 只取首次：第二次以后用户已经看过答案解析，选项分布会严重失真
+// answer_histories 上线前的老数据只有 answer_records，作为兜底补进来
+const firstResponsesSQL = `
+	SELECT * FROM (
+		SELECT DISTINCT ON (h.user_id, h.question_id) h.user_id, h.question_id, h.choice, h.is_correct
+		FROM answer_histories h
+		JOIN questions q ON q.id = h.question_id
+		WHERE q.source = @source AND q.deleted_at IS NULL
+		ORDER BY h.user_id, h.question_id, h.created_at ASC, h.id ASC
+	) first_try
+	UNION ALL
+	SELECT ar.user_id, ar.question_id, ar.choice, ar.is_correct
+	FROM answer_records ar
+	JOIN questions q ON q.id = ar.question_id
+	WHERE q.source = @source AND q.deleted_at IS NULL AND ar.deleted_at IS NULL
+		AND NOT EXISTS (SELECT 1 FROM answer_histories h2 WHERE h2.user_id = ar.user_id AND h2.question_id = ar.question_id)
+`
+
+// ListSources 所有有题目的题库
+func (r *Repository) ListSources() ([]string, error) {
+	var sources []string
+	err := db.DB.Table("questions").Where("deleted_at IS NULL AND source <> ''").
+		Distinct("source").Pluck("source", &sources).Error
+	return sources, err
+}
+
+// AnalyzeSource 重算一个题库下全部客观题的项目分析，返回写入的题目数
+func (r *Repository) AnalyzeSource(source string) (int, error) {
+	// 截到秒：与数据库里的时间戳精确相等，第 5 步按它清理残留
+	now := time.Now().Truncate(time.Second)
+	args := map[string]interface{}{"source": source}
+
+	// 1. 可分析的题目：有标准答案 (A-F 字母) 的小题；B1 子题带上父题的选项
+	var metas []itemMeta
+	err := db.DB.Raw(`
+		SELECT q.id, q.source, q.category_path, q.type, q.correct, q.diff_value, q.options, p.options AS parent_options
+		FROM questions q
+		LEFT JOIN questions p ON p.id = q.parent_id
+		WHERE q.source = @source AND q.deleted_at IS NULL
+			AND UPPER(TRIM(q.correct)) ~ '^[A-F]+$'
+			AND NOT EXISTS (SELECT 1 FROM questions c WHERE c.parent_id = q.id AND c.deleted_at IS NULL)
+	`, args).Scan(&metas).Error
+	if err != nil {
+		return 0, err
+	}
+	metaMap := make(map[uint]itemMeta, len(metas))
+	for _, m := range metas {
+		metaMap[m.ID] = m
+	}
+
+	// 2. 能力值：用户在本题库的首次作答正确率 (题量太少的不参与排名)
+	type abilityRow struct {
+		UserID  uint
+		Total   int
+		Correct int
+	}
+	var abilities []abilityRow
+	err = db.DB.Raw(`
+		SELECT user_id, COUNT(*) AS total, SUM(CASE WHEN is_correct THEN 1 ELSE 0 END) AS correct
+		FROM (`+firstResponsesSQL+`) t
+		GROUP BY user_id
+		HAVING COUNT(*) >= @min_items
+	`, map[string]interface{}{"source": source, "min_items": MinAbilityItems}).Scan(&abilities).Error
+	if err != nil {
+		return 0, err
+	}
+	ability := make(map[uint]float64, len(abilities))
+	for _, a := range abilities {
+		ability[a.UserID] = float64(a.Correct) / float64(a.Total)
+	}
+
+	// 3. 按题目顺序流式读取作答，逐题分析，避免整库作答一次性进内存
+	rows, err := db.DB.Raw(`SELECT * FROM (`+firstResponsesSQL+`) t ORDER BY question_id`, args).Rows()
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var batch []QuestionItemStat
+	written := 0
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := db.DB.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "question_id"}},
+			UpdateAll: true,
+		}).Create(&batch).Error
+		written += len(batch)
+		batch = batch[:0]
+		return err
+	}
+
+	analyzed := make(map[uint]bool, len(metas))
+	var current []response
+	emit := func() error {
+		if len(current) == 0 {
+			return nil
+		}
+		qid := current[0].QuestionID
+		if meta, ok := metaMap[qid]; ok {
+			batch = append(batch, analyzeItem(meta, current, ability, now))
+			analyzed[qid] = true
+		}
+		current = current[:0]
+		if len(batch) >= upsertBatchSize {
+			return flush()
+		}
+		return nil
+	}
+
+	for rows.Next() {
+		var resp response
+		if err := db.DB.ScanRows(rows, &resp); err != nil {
+			return written, err
+		}
+		if len(current) > 0 && current[0].QuestionID != resp.QuestionID {
+			if err := emit(); err != nil {
+				return written, err
+			}
+		}
+		current = append(current, resp)
+	}
+	if err := emit(); err != nil {
+		return written, err
+	}
+
+	// 4. 还没人做过的题也落一行 (人数为 0)，后台列表能看到"零作答"的题
+	for _, m := range metas {
+		if !analyzed[m.ID] {
+			batch = append(batch, analyzeItem(m, nil, ability, now))
+			if len(batch) >= upsertBatchSize {
+				if err := flush(); err != nil {
+					return written, err
+				}
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return written, err
+	}
+
+	// 5. 清理已删除 / 改成主观题的残留结果
+	err = db.DB.Where("source = ? AND computed_at < ?", source, now).Delete(&QuestionItemStat{}).Error
+	return written, err
+}
+
+// ListFilter 后台列表筛选条件
+type ListFilter struct {
+	Source         string
+	Category       string
+	Type           string
+	Flag           string // miskeyed / low_discrimination / diff_mismatch / any
+	MinRespondents int
+	MaxPValue      *float64
+	MinPValue      *float64
+	SortBy         string // p_value / discrimination / respondents
+	Desc           bool
+}
+
+// StatItem 列表条目 (附题干摘要)
+type StatItem struct {
+	QuestionItemStat
+	Stem     string `json:"stem"`
+	Correct  string `json:"correct"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+// List 分页查询项目分析结果
+func (r *Repository) List(f ListFilter, page, pageSize int) ([]StatItem, int64, error) {
+	var list []StatItem
+	var total int64
+
+	query := db.DB.Table("question_item_stats AS s").
+		Joins("JOIN questions q ON q.id = s.question_id AND q.deleted_at IS NULL")
+
+	if f.Source != "" {
+		query = query.Where("s.source = ?", f.Source)
+	}
+	if f.Category != "" {
+		query = query.Where("s.category_path LIKE ?", f.Category+"%")
+	}
+	if f.Type != "" {
+		query = query.Where("s.type LIKE ?", "%"+f.Type+"%")
+	}
+	switch f.Flag {
+	case "miskeyed":
+		query = query.Where("s.miskeyed = ?", true)
+	case "low_discrimination":
+		query = query.Where("s.low_discrimination = ?", true)
+	case "diff_mismatch":
+		query = query.Where("s.diff_mismatch = ?", true)
+	case "any":
+		query = query.Where("(s.miskeyed = ? OR s.low_discrimination = ? OR s.diff_mismatch = ?)", true, true, true)
+	}
+	if f.MinRespondents > 0 {
+		query = query.Where("s.respondents >= ?", f.MinRespondents)
+	}
+	if f.MinPValue != nil {
+		query = query.Where("s.p_value >= ?", *f.MinPValue)
+	}
+	if f.MaxPValue != nil {
+		query = query.Where("s.p_value <= ?", *f.MaxPValue)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	sortCol := "s.question_id"
+	switch f.SortBy {
+	case "p_value", "discrimination", "respondents":
+		sortCol = "s." + f.SortBy
+	}
+	order := sortCol + " ASC"
+	if f.Desc {
+		order = sortCol + " DESC"
+	}
+
+	err := query.Select("s.*, q.stem, q.correct, q.parent_id").
+		Order(order).Order("s.question_id ASC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Scan(&list).Error
+	return list, total, err
+}
+
+// Get 单题的项目分析结果
+func (r *Repository) Get(questionID uint) (*QuestionItemStat, error) {
+	var stat QuestionItemStat
+	err := db.DB.First(&stat, "question_id = ?", questionID).Error
+	return &stat, err
+}
@@ -0,0 +1,101 @@
+package leaderboard
+
+import (
+	"net/http"
+	"strconv"
+
+	"med-platform/internal/common/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	repo *Repository
+}
+
+func NewHandler() *Handler {
+	return &Handler{repo: NewRepository()}
+}
+
+// Get 排行榜
+// GET /leaderboard?metric=count|accuracy|streak&window=day|week|month|all&scope=all|school|major|grade&page=1&page_size=10
+// 列表只展示前 100 名；me 为当前用户自己的名次 (不受 100 名限制，未上榜为 0)
+func (h *Handler) Get(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	b := Board{
+		Metric: c.DefaultQuery("metric", MetricCount),
+		Window: c.DefaultQuery("window", WindowWeek),
+		Scope:  c.DefaultQuery("scope", ScopeAll),
+	}
+	switch b.Metric {
+	case MetricCount, MetricAccuracy:
+	case MetricStreak:
+		b.Window = WindowAll // 连续打卡与窗口无关
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的排行指标"})
+		return
+	}
+	if _, ok := minAccuracyAttempts[b.Window]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的时间范围"})
+		return
+	}
+
+	// 同校 / 同专业 / 同年级：取自己的资料
+	if b.Scope != ScopeAll {
+		var profile struct {
+			School string
+			Major  string
+			Grade  string
+		}
+		db.DB.Table("users").Select("school, major, grade").Where("id = ?", userID).Scan(&profile)
+		switch b.Scope {
+		case ScopeSchool:
+			b.ScopeValue = profile.School
+		case ScopeMajor:
+			b.ScopeValue = profile.Major
+		case ScopeGrade:
+			b.ScopeValue = profile.Grade
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的排行范围"})
+			return
+		}
+		if b.ScopeValue == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "请先在个人资料中完善学校、专业和年级"})
+			return
+		}
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 50 {
+		pageSize = 10
+	}
+	offset := (page - 1) * pageSize
+	limit := pageSize
+	if offset+limit > MaxListRank {
+		limit = MaxListRank - offset
+	}
+	if limit < 0 {
+		limit = 0
+	}
+
+	entries, total, me, err := h.repo.Query(b, userID, offset, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取排行榜失败"})
+		return
+	}
+	resp := gin.H{
+		"data":     entries,
+		"page":     page,
+		"total":    total,
+		"has_more": len(entries) == limit && offset+limit < MaxListRank && int64(offset+limit) < total,
+		"me":       me,
+	}
+	if b.Metric == MetricAccuracy {
+		resp["min_attempts"] = minAccuracyAttempts[b.Window]
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,60 @@
+package leaderboard
+
+// 指标
+const (
+	MetricCount    = "count"    // 做题量
+	MetricAccuracy = "accuracy" // 正确率 (需达到最低作答量)
+	MetricStreak   = "streak"   // 当前连续打卡天数
+)
+
+// 时间窗口 (连续打卡不区分窗口)
+const (
+	WindowDay   = "day"
+	WindowWeek  = "week"
+	WindowMonth = "month"
+	WindowAll   = "all"
+)
+
+// 范围：全站，或与自己同校 / 同专业 / 同年级
+const (
+	ScopeAll    = "all"
+	ScopeSchool = "school"
+	ScopeMajor  = "major"
+	ScopeGrade  = "grade"
+)
+
+// MaxListRank 榜单只展示前 100 名 (自己的名次不受限)
+const MaxListRank = 100
+
+// minAccuracyAttempts 正确率榜的最低作答量，防止做 3 题全对的刷到榜首
+var minAccuracyAttempts = map[string]int{
+	WindowDay:   20,
+	WindowWeek:  50,
+	WindowMonth: 100,
+	WindowAll:   300,
+}
+
+// Board 一张榜单的查询条件
+type Board struct {
+	Metric     string
+	Window     string
+	Scope      string
+	ScopeValue string // 范围为学校 / 专业 / 年级时，取当前用户的资料
+}
+
+// Entry 榜单上的一行
+type Entry struct {
+	Rank     int     `json:"rank"`
+	UserID   uint    `json:"user_id"`
+	Username string  `json:"username"`
+	Nickname string  `json:"nickname"`
+	Avatar   string  `json:"avatar"`
+	School   string  `json:"school"`
+	Score    float64 `json:"score"` // 题量 / 正确率 (%) / 连续天数
+}
+
+// MyRank 当前用户在榜单中的位置 (未上榜时 Rank 为 0)
+type MyRank struct {
+	Rank  int     `json:"rank"`
+	Score float64 `json:"score"`
+}
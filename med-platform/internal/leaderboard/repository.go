@@ -0,0 +1,335 @@
+package leaderboard
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"med-platform/internal/common/cache"
+	"med-platform/internal/common/db"
+	"med-platform/internal/question"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// boardTTL Redis 中榜单的有效期，过期后下次读取时按 SQL 重建
+// 作答时已增量更新 (见 OnAnswered)，定期重建只为纠正并发写入带来的偏差
+const boardTTL = 30 * time.Minute
+
+// tieSpan 写入 ZSET 的分数 = 分数×10 (保留一位小数) × tieSpan + 名次补偿
+// 同分时 user_id 小的排在前面，与 SQL 降级时的 ORDER BY score DESC, user_id 一致
+const tieSpan = 1 << 28
+
+func encodeScore(score float64, userID uint) float64 {
+	return math.Round(score*10)*tieSpan + float64(tieSpan-1-int64(userID))
+}
+
+func decodeScore(z float64) float64 {
+	return math.Floor(z/tieSpan) / 10
+}
+
+// emptyKey 空榜标记：ZSET 不能为空，没人上榜时另存一个标记，避免每次读取都跑 SQL
+func emptyKey(key string) string {
+	return key + ":empty"
+}
+
+type Repository struct{}
+
+func NewRepository() *Repository {
+	return &Repository{}
+}
+
+// periodStart 窗口起始日 (周从周一算起)
+func periodStart(window string, now time.Time) time.Time {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch window {
+	case WindowDay:
+		return today
+	case WindowWeek:
+		return today.AddDate(0, 0, -int((today.Weekday()+6)%7))
+	case WindowMonth:
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	}
+	return time.Time{}
+}
+
+// redisKey 榜单的 ZSET key，带上周期标识，跨天 / 跨周后自然换新榜
+func (b Board) redisKey(now time.Time) string {
+	period := "all"
+	if b.Metric == MetricStreak {
+		period = now.Format("2006-01-02")
+	} else if b.Window != WindowAll {
+		period = periodStart(b.Window, now).Format("2006-01-02")
+	}
+	return fmt.Sprintf("lb:%s:%s:%s:%s:%s", b.Metric, b.Window, period, b.Scope, b.ScopeValue)
+}
+
+// scoreSQL 计算榜单分数的 SQL：返回 (user_id, score) 两列，只含分数 > 0 且在范围内的用户
+// userID 非 0 时只算这一个用户 (作答后增量更新用)
+func (b Board) scoreSQL(now time.Time, userID uint) (string, map[string]interface{}) {
+	start := periodStart(b.Window, now)
+	args := map[string]interface{}{
+		"since":    start.Format("2006-01-02"),
+		"since_at": start,
+		"min":      minAccuracyAttempts[b.Window],
+		"today":    now.Format("2006-01-02"),
+		"scope":    b.ScopeValue,
+		"user":     userID,
+	}
+
+	var score string
+	switch b.Metric {
+	case MetricAccuracy:
+		score = `SELECT user_id, ROUND(100.0 * COUNT(*) FILTER (WHERE is_correct) / COUNT(*), 1)::float8 AS score
+			FROM answer_histories WHERE created_at >= @since_at
+			GROUP BY user_id HAVING COUNT(*) >= @min`
+	case MetricStreak:
+		// 与个人分析同一口径：截至今天的那段连续区间
+		filter := "TRUE"
+		if userID > 0 {
+			filter = "user_id = @user"
+		}
+		score = `SELECT user_id, days::float8 AS score FROM (` + question.StreakIslandsSQL(filter) + `) i
+			WHERE end_day = CAST(@today AS date)`
+	default:
+		if b.Window == WindowAll {
+			// 全部时间 = 近一年每日统计 + 已归档的陈年总量
+			score = `SELECT user_id, SUM(n)::float8 AS score FROM (
+					SELECT user_id, count AS n FROM user_daily_stats
+					UNION ALL SELECT user_id, total_count FROM user_archived_stats
+				) t GROUP BY user_id`
+		} else {
+			score = `SELECT user_id, SUM(count)::float8 AS score FROM user_daily_stats
+				WHERE date_str >= @since GROUP BY user_id`
+		}
+	}
+
+	where := "s.score > 0"
+	switch b.Scope {
+	case ScopeSchool:
+		where += " AND u.school = @scope"
+	case ScopeMajor:
+		where += " AND u.major = @scope"
+	case ScopeGrade:
+		where += " AND u.grade = @scope"
+	}
+	if userID > 0 {
+		where += " AND s.user_id = @user"
+	}
+	return `SELECT s.user_id, s.score FROM (` + score + `) s JOIN users u ON u.id = s.user_id WHERE ` + where, args
+}
+
+// Query 取榜单第 offset 名起的 limit 行，以及 userID 自己的名次
+// Redis 可用时读 ZSET (不存在则按 SQL 重建)；否则直接用窗口函数在库里排名
+func (r *Repository) Query(b Board, userID uint, offset, limit int) ([]Entry, int64, MyRank, error) {
+	now := time.Now()
+	if cache.RDB != nil {
+		entries, total, me, err := r.queryRedis(b, userID, offset, limit, now)
+		if err == nil {
+			return entries, total, me, nil
+		}
+		// Redis 出错时降级到 SQL
+	}
+	return r.querySQL(b, userID, offset, limit, now)
+}
+
+func (r *Repository) queryRedis(b Board, userID uint, offset, limit int, now time.Time) ([]Entry, int64, MyRank, error) {
+	ctx := context.Background()
+	key := b.redisKey(now)
+	if err := r.ensureBoard(ctx, b, key, now); err != nil {
+		return nil, 0, MyRank{}, err
+	}
+
+	var me MyRank
+	total, err := cache.RDB.ZCard(ctx, key).Result()
+	if err != nil {
+		return nil, 0, me, err
+	}
+	member := strconv.Itoa(int(userID))
+	if rank, err := cache.RDB.ZRevRank(ctx, key, member).Result(); err == nil {
+		me.Rank = int(rank) + 1
+		z, _ := cache.RDB.ZScore(ctx, key, member).Result()
+		me.Score = decodeScore(z)
+	}
+
+	var entries []Entry
+	if limit > 0 {
+		zs, err := cache.RDB.ZRevRangeWithScores(ctx, key, int64(offset), int64(offset+limit-1)).Result()
+		if err != nil {
+			return nil, 0, me, err
+		}
+		for i, z := range zs {
+			id, _ := strconv.Atoi(fmt.Sprint(z.Member))
+			entries = append(entries, Entry{Rank: offset + i + 1, UserID: uint(id), Score: decodeScore(z.Score)})
+		}
+	}
+	return fillUsers(entries), total, me, nil
+}
+
+// ensureBoard 榜单不存在 (或已过期) 时用 SQL 重建：先写临时 key 再整体替换，读的人不会看到半张榜
+func (r *Repository) ensureBoard(ctx context.Context, b Board, key string, now time.Time) error {
+	if n, err := cache.RDB.Exists(ctx, key, emptyKey(key)).Result(); err != nil || n > 0 {
+		return err
+	}
+	sql, args := b.scoreSQL(now, 0)
+	var rows []struct {
+		UserID uint
+		Score  float64
+	}
+	if err := db.DB.Raw(sql, args).Scan(&rows).Error; err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return cache.RDB.Set(ctx, emptyKey(key), 1, boardTTL).Err()
+	}
+	members := make([]redis.Z, 0, len(rows))
+	for _, row := range rows {
+		members = append(members, redis.Z{Score: encodeScore(row.Score, row.UserID), Member: strconv.Itoa(int(row.UserID))})
+	}
+	tmp := key + ":building"
+	pipe := cache.RDB.TxPipeline()
+	pipe.Del(ctx, tmp)
+	pipe.ZAdd(ctx, tmp, members...)
+	pipe.Rename(ctx, tmp, key)
+	pipe.Expire(ctx, key, boardTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (r *Repository) querySQL(b Board, userID uint, offset, limit int, now time.Time) ([]Entry, int64, MyRank, error) {
+	sql, args := b.scoreSQL(now, 0)
+	ranked := `WITH r AS (SELECT user_id, score, ROW_NUMBER() OVER (ORDER BY score DESC, user_id) AS rank FROM (` + sql + `) b) `
+
+	var me MyRank
+	var total int64
+	if err := db.DB.Raw(ranked+`SELECT COUNT(*) FROM r`, args).Scan(&total).Error; err != nil {
+		return nil, 0, me, err
+	}
+	args["me"] = userID
+	db.DB.Raw(ranked+`SELECT rank, score FROM r WHERE user_id = @me`, args).Scan(&me)
+
+	var entries []Entry
+	if limit > 0 {
+		args["offset"], args["limit"] = offset, limit
+		if err := db.DB.Raw(ranked+`SELECT rank, user_id, score FROM r ORDER BY rank LIMIT @limit OFFSET @offset`, args).
+			Scan(&entries).Error; err != nil {
+			return nil, 0, me, err
+		}
+	}
+	return fillUsers(entries), total, me, nil
+}
+
+// fillUsers 补上头像昵称等展示信息
+func fillUsers(entries []Entry) []Entry {
+	if len(entries) == 0 {
+		return []Entry{}
+	}
+	ids := make([]uint, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.UserID)
+	}
+	var users []struct {
+		ID       uint
+		Username string
+		Nickname string
+		Avatar   string
+		School   string
+	}
+	db.DB.Table("users").Select("id, username, nickname, avatar, school").Where("id IN ?", ids).Scan(&users)
+	byID := make(map[uint]int, len(users))
+	for i, u := range users {
+		byID[u.ID] = i
+	}
+	for i := range entries {
+		if j, ok := byID[entries[i].UserID]; ok {
+			u := users[j]
+			entries[i].Username, entries[i].Nickname, entries[i].Avatar, entries[i].School = u.Username, u.Nickname, u.Avatar, u.School
+			if entries[i].Nickname == "" {
+				entries[i].Nickname = u.Username
+			}
+		}
+	}
+	return entries
+}
+
+// updateScript 只改已缓存的榜单：榜单不存在时不写 (否则会凭空生成只有一个人的残榜)，
+// 顺带删掉空榜标记，下次读取按 SQL 重建
+// ARGV: 操作 incr / set / rem、成员、增量、新上榜时的分数
+var updateScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	redis.call('DEL', KEYS[2])
+	return 0
+end
+if ARGV[1] == 'rem' then
+	return redis.call('ZREM', KEYS[1], ARGV[2])
+end
+if ARGV[1] == 'incr' and redis.call('ZSCORE', KEYS[1], ARGV[2]) then
+	return redis.call('ZINCRBY', KEYS[1], ARGV[3], ARGV[2])
+end
+return redis.call('ZADD', KEYS[1], ARGV[4], ARGV[2])
+`)
+
+// OnAnswered 作答流水写入后调用 (answer 包)：异步把该用户的新成绩写进已缓存的各张榜单
+// 做题量直接累加 n 题；正确率与连续打卡按该用户重新计算后覆盖 (不达标则移出榜单)
+func OnAnswered(userID uint, n int) {
+	if cache.RDB == nil || userID == 0 || n <= 0 {
+		return
+	}
+	go func() {
+		ctx := context.Background()
+		now := time.Now()
+		var profile struct {
+			School string
+			Major  string
+			Grade  string
+		}
+		db.DB.Table("users").Select("school, major, grade").Where("id = ?", userID).Scan(&profile)
+		scopes := []Board{{Scope: ScopeAll}}
+		for _, s := range []Board{{Scope: ScopeSchool, ScopeValue: profile.School}, {Scope: ScopeMajor, ScopeValue: profile.Major}, {Scope: ScopeGrade, ScopeValue: profile.Grade}} {
+			if s.ScopeValue != "" {
+				scopes = append(scopes, s)
+			}
+		}
+
+		member := strconv.Itoa(int(userID))
+		pipe := cache.RDB.Pipeline()
+		for _, metric := range []string{MetricCount, MetricAccuracy, MetricStreak} {
+			windows := []string{WindowDay, WindowWeek, WindowMonth, WindowAll}
+			if metric == MetricStreak {
+				windows = []string{WindowAll}
+			}
+			for _, window := range windows {
+				keys := make([]string, 0, len(scopes))
+				for _, s := range scopes {
+					keys = append(keys, Board{Metric: metric, Window: window, Scope: s.Scope, ScopeValue: s.ScopeValue}.redisKey(now))
+				}
+				args := []interface{}{"incr", member, float64(n) * 10 * tieSpan, encodeScore(float64(n), userID)}
+				if metric != MetricCount {
+					// 重新计算前先看有没有缓存着的榜单，没有就不必查库
+					if exists, err := cache.RDB.Exists(ctx, keys...).Result(); err != nil || exists == 0 {
+						continue
+					}
+					score := Board{Metric: metric, Window: window, Scope: ScopeAll}.userScore(now, userID)
+					args = []interface{}{"set", member, 0, encodeScore(score, userID)}
+					if score <= 0 {
+						args[0] = "rem"
+					}
+				}
+				for _, key := range keys {
+					updateScript.Eval(ctx, pipe, []string{key, emptyKey(key)}, args...)
+				}
+			}
+		}
+		pipe.Exec(ctx)
+	}()
+}
+
+// userScore 单个用户在这张榜上的分数 (未达标为 0)
+func (b Board) userScore(now time.Time, userID uint) float64 {
+	sql, args := b.scoreSQL(now, userID)
+	var score float64
+	db.DB.Raw(`SELECT COALESCE(MAX(score), 0) FROM (`+sql+`) s`, args).Scan(&score)
+	return score
+}
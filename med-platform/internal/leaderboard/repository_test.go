@@ -0,0 +1,46 @@
+package leaderboard
+
+import "testing"
+
+func TestEncodeDecodeScore(t *testing.T) {
+	tests := []struct {
+		score  float64
+		userID uint
+		want   float64
+	}{
+		{0, 1, 0},
+		{1, 1, 1},
+		{87.5, 42, 87.5},
+		{87.54, 42, 87.5},
+		{87.56, 42, 87.6},
+		{12345, tieSpan - 1, 12345},
+		{3.3, 0, 3.3},
+	}
+	for _, tt := range tests {
+		if got := decodeScore(encodeScore(tt.score, tt.userID)); got != tt.want {
+			t.Errorf("decodeScore(encodeScore(%v, %d)) = %v, want %v", tt.score, tt.userID, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeScoreOrder(t *testing.T) {
+	type entry struct {
+		score  float64
+		userID uint
+	}
+	tests := []struct {
+		name          string
+		first, second entry
+	}{
+		{"分数高的在前", entry{10, 999}, entry{9.9, 1}},
+		{"同分 user_id 小的在前", entry{50, 3}, entry{50, 4}},
+		{"保留一位小数后同分，按 user_id", entry{50.01, 2}, entry{50.04, 7}},
+		{"最大 user_id 也不越过下一档分数", entry{0.1, tieSpan - 1}, entry{0, 0}},
+	}
+	for _, tt := range tests {
+		a, b := encodeScore(tt.first.score, tt.first.userID), encodeScore(tt.second.score, tt.second.userID)
+		if a <= b {
+			t.Errorf("%s: encodeScore%v = %v, 应大于 encodeScore%v = %v", tt.name, tt.first, a, tt.second, b)
+		}
+	}
+}
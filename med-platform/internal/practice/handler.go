@@ -0,0 +1,208 @@
+package practice
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"med-platform/internal/question"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	DefaultBatchSize = 10
+	MaxBatchSize     = 50
+	MaxFocusNodes    = 5 // 一批题最多覆盖几个薄弱章节，避免东一榔头西一棒槌
+)
+
+type Handler struct {
+	repo *Repository
+}
+
+func NewHandler() *Handler {
+	return &Handler{repo: NewRepository()}
+}
+
+// SmartItem 智能练习推荐的一道大题
+type SmartItem struct {
+	QuestionID   uint     `json:"question_id"`
+	Type         string   `json:"type"`
+	CategoryPath string   `json:"category_path"`
+	DiffValue    float64  `json:"diff_value"`
+	NodeMastery  float64  `json:"node_mastery"`
+	Reason       string   `json:"reason"`  // 一句话理由，前端直接展示
+	Reasons      []string `json:"reasons"` // 分条理由
+}
+
+// resolveScopes 解析出题范围并鉴权：指定章节直接校验；否则只在已授权的科目里出题
+func resolveScopes(c *gin.Context, source, category string) ([]string, bool) {
+	if category != "" {
+		if !question.CheckAccess(c, source, category) {
+			return nil, false
+		}
+		return []string{category}, true
+	}
+	scopes := question.AccessibleRoots(c, source)
+	return scopes, len(scopes) > 0
+}
+
+// ==========================================
+// 1. 智能练习：下一批题
+// ==========================================
+// GET /practice/smart?source=&category=&count=10&exclude=1,2,3
+// exclude 传入本轮已推送过的大题 ID，用于"再来一批"
+func (h *Handler) NextBatch(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	source := c.Query("source")
+	category := c.Query("category")
+	if source == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请指定题库"})
+		return
+	}
+
+	count, _ := strconv.Atoi(c.DefaultQuery("count", strconv.Itoa(DefaultBatchSize)))
+	if count <= 0 {
+		count = DefaultBatchSize
+	}
+	if count > MaxBatchSize {
+		count = MaxBatchSize
+	}
+
+	exclude := make(map[uint]bool)
+	for _, s := range strings.Split(c.Query("exclude"), ",") {
+		if id, err := strconv.Atoi(strings.TrimSpace(s)); err == nil && id > 0 {
+			exclude[uint(id)] = true
+		}
+	}
+
+	scopes, ok := resolveScopes(c, source, category)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "FORBIDDEN", "message": "🔒 您尚未获得该科目的访问授权"})
+		return
+	}
+
+	nodes, pool, err := h.repo.LoadMastery(userID, source, scopes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "计算掌握度失败"})
+		return
+	}
+
+	// 1. 挑出最薄弱的几个章节，按薄弱程度分配题量
+	weak := weakLeaves(nodes, pool)
+	if len(weak) > MaxFocusNodes {
+		weak = weak[:MaxFocusNodes]
+	}
+	var sumPriority float64
+	for _, n := range weak {
+		sumPriority += n.priority()
+	}
+
+	// 2. 逐个章节挑题；前面的章节题不够时，名额顺延给后面的章节
+	items := make([]SmartItem, 0, count)
+	remaining := count
+	for i, n := range weak {
+		if remaining <= 0 {
+			break
+		}
+		quota := remaining
+		if i < len(weak)-1 && sumPriority > 0 {
+			quota = int(float64(count)*n.priority()/sumPriority + 0.5)
+			if quota < 1 {
+				quota = 1
+			}
+			if quota > remaining {
+				quota = remaining
+			}
+		}
+
+		picked, err := h.repo.PickFromNode(userID, source, n, quota, exclude)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "挑选题目失败"})
+			return
+		}
+		for _, p := range picked {
+			exclude[p.ID] = true
+			items = append(items, buildItem(n, p))
+		}
+		remaining -= len(picked)
+	}
+
+	resp := gin.H{
+		"data":       items,
+		"total":      len(items),
+		"weak_nodes": weak,
+		"subjects":   nodesAtLevel(nodes, 1),
+	}
+	if len(items) == 0 {
+		resp["message"] = "🎉 当前范围内的章节都已掌握，可以去模考检验一下"
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// buildItem 组装推荐理由：章节层面 (为什么练这章) + 题目层面 (为什么是这道)
+func buildItem(n *NodeMastery, p candidate) SmartItem {
+	var reasons []string
+	switch {
+	case n.Attempted == 0:
+		reasons = append(reasons, fmt.Sprintf("%s 尚未练习", n.Path))
+	case n.Accuracy < MasteredThreshold*100:
+		reasons = append(reasons, fmt.Sprintf("%s 正确率偏低 (近期 %.0f%%)", n.Path, n.Accuracy))
+	default:
+		reasons = append(reasons, fmt.Sprintf("%s 覆盖不足 (已做 %d/%d)", n.Path, n.Attempted, n.Total))
+	}
+
+	if p.status == "wrong" {
+		reasons = append(reasons, "上次答错，再巩固一遍")
+	} else {
+		reasons = append(reasons, "尚未做过的新题")
+	}
+	reasons = append(reasons, fmt.Sprintf("难度系数 %.2f，贴合当前掌握度 %.0f%%", p.DiffValue, n.Mastery))
+
+	return SmartItem{
+		QuestionID:   p.ID,
+		Type:         p.Type,
+		CategoryPath: n.Path,
+		DiffValue:    p.DiffValue,
+		NodeMastery:  n.Mastery,
+		Reason:       strings.Join(reasons, "；"),
+		Reasons:      reasons,
+	}
+}
+
+// ==========================================
+// 2. 掌握度画像
+// ==========================================
+// GET /practice/mastery?source=&category=
+// 返回范围内每一级目录的掌握度 (先按层级、再按路径排序，前端可直接还原成树)
+func (h *Handler) GetMastery(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	source := c.Query("source")
+	if source == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请指定题库"})
+		return
+	}
+
+	scopes, ok := resolveScopes(c, source, c.Query("category"))
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "FORBIDDEN", "message": "🔒 您尚未获得该科目的访问授权"})
+		return
+	}
+
+	nodes, _, err := h.repo.LoadMastery(userID, source, scopes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "计算掌握度失败"})
+		return
+	}
+
+	list := make([]*NodeMastery, 0, len(nodes))
+	for level := 1; ; level++ {
+		layer := nodesAtLevel(nodes, level)
+		if len(layer) == 0 {
+			break
+		}
+		list = append(list, layer...)
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list, "total": len(list)})
+}
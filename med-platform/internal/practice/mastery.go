@@ -0,0 +1,156 @@
+package practice
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// 掌握度估计参数
+const (
+	HistoryWindowDays = 180 // 只看近半年的作答轨迹
+	HalfLifeDays      = 14  // 遗忘半衰期：14 天前的一次作答，权重减半
+	PriorStrength     = 3.0 // 先验强度：相当于 3 次"五五开"的虚拟作答，防止做 1 题就下结论
+	PriorMastery      = 0.5
+
+	MasteredThreshold = 0.80 // 掌握度达到 80% ...
+	MasteredCoverage  = 0.50 // ... 且本章至少做过一半的题，才算"已掌握"
+)
+
+// NodeMastery 某个目录节点的掌握度画像
+type NodeMastery struct {
+	Path      string  `json:"path"`
+	Level     int     `json:"level"`
+	Total     int     `json:"total"`     // 本节点可判分的小题总数
+	Attempted int     `json:"attempted"` // 当前有作答记录的小题数
+	Correct   int     `json:"correct"`   // 当前答对的小题数
+	Coverage  float64 `json:"coverage"`  // 覆盖率 (%)
+	Accuracy  float64 `json:"accuracy"`  // 近期加权正确率 (%)，无作答时为 0
+	Mastery   float64 `json:"mastery"`   // 掌握度估计 (%)
+	Mastered  bool    `json:"mastered"`
+
+	avgDiff   float64 // 题池平均难度系数 (通过率)
+	evidence  float64 // 加权作答量
+	weightedC float64 // 加权答对量
+}
+
+// poolRow 题池统计 (按完整章节路径)
+type poolRow struct {
+	CategoryPath string
+	Total        int
+	SumDiff      float64
+}
+
+// recordRow 当前作答状态统计 (AnswerRecord)
+type recordRow struct {
+	CategoryPath string
+	Attempted    int
+	Correct      int
+}
+
+// historyRow 近期作答轨迹统计 (AnswerHistory，已按时间衰减与难度加权)
+type historyRow struct {
+	CategoryPath string
+	Weight       float64
+	WeightedC    float64
+}
+
+// buildMastery 把按叶子章节聚合的三类统计，逐级累加到每一层目录节点上
+// 💡 掌握度 = (加权答对量 + 先验) / (加权作答量 + 先验强度)
+// 加权规则：越近的作答权重越高；答对难题、答错易题的信号更强 (由 SQL 计算)
+func buildMastery(pool []poolRow, records []recordRow, history []historyRow) map[string]*NodeMastery {
+	nodes := make(map[string]*NodeMastery)
+
+	each := func(path string, fn func(n *NodeMastery)) {
+		parts := strings.Split(path, " > ")
+		for i := range parts {
+			prefix := strings.Join(parts[:i+1], " > ")
+			n, ok := nodes[prefix]
+			if !ok {
+				n = &NodeMastery{Path: prefix, Level: i + 1}
+				nodes[prefix] = n
+			}
+			fn(n)
+		}
+	}
+
+	for _, p := range pool {
+		row := p
+		each(row.CategoryPath, func(n *NodeMastery) {
+			n.Total += row.Total
+			n.avgDiff += row.SumDiff
+		})
+	}
+	for _, r := range records {
+		row := r
+		each(row.CategoryPath, func(n *NodeMastery) {
+			n.Attempted += row.Attempted
+			n.Correct += row.Correct
+		})
+	}
+	for _, h := range history {
+		row := h
+		each(row.CategoryPath, func(n *NodeMastery) {
+			n.evidence += row.Weight
+			n.weightedC += row.WeightedC
+		})
+	}
+
+	for _, n := range nodes {
+		if n.Total > 0 {
+			n.avgDiff /= float64(n.Total)
+			n.Coverage = round1(math.Min(1, float64(n.Attempted)/float64(n.Total)) * 100)
+		}
+		if n.evidence > 0 {
+			n.Accuracy = round1(n.weightedC / n.evidence * 100)
+		}
+		mastery := (n.weightedC + PriorStrength*PriorMastery) / (n.evidence + PriorStrength)
+		n.Mastery = round1(mastery * 100)
+		n.Mastered = mastery >= MasteredThreshold && n.Coverage >= MasteredCoverage*100
+	}
+	return nodes
+}
+
+// priority 节点的薄弱程度 (越大越该练)：掌握度低为主，覆盖率低为辅
+func (n *NodeMastery) priority() float64 {
+	return (1 - n.Mastery/100) + 0.3*(1-n.Coverage/100)
+}
+
+// targetDiff 根据掌握度挑选合适难度：越薄弱越先给通过率高的题，掌握后再上难题
+func (n *NodeMastery) targetDiff() float64 {
+	return 0.85 - 0.35*(n.Mastery/100)
+}
+
+// weakLeaves 找出未掌握的叶子章节，按薄弱程度从高到低排序
+func weakLeaves(nodes map[string]*NodeMastery, pool []poolRow) []*NodeMastery {
+	var list []*NodeMastery
+	for _, p := range pool {
+		if n := nodes[p.CategoryPath]; n != nil && !n.Mastered && n.Total > 0 {
+			list = append(list, n)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		pi, pj := list[i].priority(), list[j].priority()
+		if pi != pj {
+			return pi > pj
+		}
+		return list[i].Path < list[j].Path
+	})
+	return list
+}
+
+// nodesAtLevel 取某一层级的节点 (例如 1 = 科目)，按路径排序
+func nodesAtLevel(nodes map[string]*NodeMastery, level int) []*NodeMastery {
+	var list []*NodeMastery
+	for _, n := range nodes {
+		if n.Level == level {
+			list = append(list, n)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Path < list[j].Path })
+	return list
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,170 @@
+package practice
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"med-platform/internal/common/db"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct{}
+
+func NewRepository() *Repository {
+	return &Repository{}
+}
+
+// scopeFilter 限定题库 + 章节范围 (多个前缀 OR 连接)，q 为 questions 表别名
+func scopeFilter(query *gorm.DB, source string, scopes []string) *gorm.DB {
+	query = query.Where("q.source = ? AND q.deleted_at IS NULL", source)
+	if len(scopes) > 0 {
+		conds := make([]string, 0, len(scopes))
+		args := make([]interface{}, 0, len(scopes))
+		for _, s := range scopes {
+			conds = append(conds, "q.category_path LIKE ?")
+			args = append(args, s+"%")
+		}
+		query = query.Where("("+strings.Join(conds, " OR ")+")", args...)
+	}
+	return query
+}
+
+// LoadMastery 计算用户在指定范围内每个目录节点的掌握度
+// 💡 三路数据：题池 (questions) + 当前状态 (answer_records) + 近期轨迹 (answer_history)
+func (r *Repository) LoadMastery(userID uint, source string, scopes []string) (map[string]*NodeMastery, []poolRow, error) {
+	// 1. 题池：只统计可判分的小题 (组合题的父题本身不作答)
+	var pool []poolRow
+	err := scopeFilter(db.DB.Table("questions AS q"), source, scopes).
+		Where("NOT EXISTS (SELECT 1 FROM questions c WHERE c.parent_id = q.id AND c.deleted_at IS NULL)").
+		Select("q.category_path, COUNT(*) AS total, SUM(COALESCE(NULLIF(q.diff_value, 0), 0.5)) AS sum_diff").
+		Group("q.category_path").
+		Scan(&pool).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 2. 当前作答状态 (覆盖率)
+	var records []recordRow
+	err = scopeFilter(db.DB.Table("answer_records AS ar").Joins("JOIN questions q ON q.id = ar.question_id"), source, scopes).
+		Where("ar.user_id = ? AND ar.deleted_at IS NULL", userID).
+		Select("q.category_path, COUNT(*) AS attempted, SUM(CASE WHEN ar.is_correct THEN 1 ELSE 0 END) AS correct").
+		Group("q.category_path").
+		Scan(&records).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 3. 近期作答轨迹 (正确率)
+	// 时间衰减：POWER(0.5, 距今天数 / 半衰期)
+	// 难度加权 (d 为难度系数，即通过率)：答对时 1.5 - d (难题答对更说明问题)，答错时 0.5 + d (易题答错更说明问题)
+	decay := "POWER(0.5, EXTRACT(EPOCH FROM (NOW() - h.created_at)) / 86400.0 / ?)"
+	diff := "COALESCE(NULLIF(q.diff_value, 0), 0.5)"
+	weight := "(" + decay + " * CASE WHEN h.is_correct THEN 1.5 - " + diff + " ELSE 0.5 + " + diff + " END)"
+
+	var history []historyRow
+	err = scopeFilter(db.DB.Table("answer_histories AS h").Joins("JOIN questions q ON q.id = h.question_id"), source, scopes).
+		Where("h.user_id = ?", userID).
+		Where("h.created_at > NOW() - (? * INTERVAL '1 day')", HistoryWindowDays).
+		Select("q.category_path, SUM"+weight+" AS weight, SUM(CASE WHEN h.is_correct THEN "+weight+" ELSE 0 END) AS weighted_c",
+			HalfLifeDays, HalfLifeDays).
+		Group("q.category_path").
+		Scan(&history).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return buildMastery(pool, records, history), pool, nil
+}
+
+// candidate 某章节下的候选大题
+type candidate struct {
+	ID        uint
+	Type      string
+	DiffValue float64
+	status    string // wrong / new
+	fit       float64
+}
+
+// PickFromNode 在一个薄弱章节里挑题：
+// 优先上次答错的题，其次没做过的新题；已全部答对的题跳过；同档内挑难度最贴合当前掌握度的
+func (r *Repository) PickFromNode(userID uint, source string, node *NodeMastery, limit int, exclude map[uint]bool) ([]candidate, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	// 组合题父题没有难度系数，用子题平均值代替
+	var roots []candidate
+	err := db.DB.Table("questions AS q").
+		Where("q.source = ? AND q.category_path = ? AND q.deleted_at IS NULL AND q.parent_id IS NULL", source, node.Path).
+		Select(`q.id, q.type, COALESCE(NULLIF(q.diff_value, 0),
+			(SELECT AVG(NULLIF(c.diff_value, 0)) FROM questions c WHERE c.parent_id = q.id AND c.deleted_at IS NULL), 0.5) AS diff_value`).
+		Scan(&roots).Error
+	if err != nil || len(roots) == 0 {
+		return nil, err
+	}
+
+	// 汇总每道大题 (含子题) 的当前作答状态
+	type rec struct {
+		RootID    uint
+		Total     int
+		Wrong     int
+		Attempted int
+	}
+	var recs []rec
+	rootIDs := make([]uint, 0, len(roots))
+	for _, c := range roots {
+		rootIDs = append(rootIDs, c.ID)
+	}
+	err = db.DB.Table("questions AS q").
+		Joins("LEFT JOIN answer_records ar ON ar.question_id = q.id AND ar.user_id = ? AND ar.deleted_at IS NULL", userID).
+		Where("q.deleted_at IS NULL").
+		Where("((q.id IN ? AND NOT EXISTS (SELECT 1 FROM questions c WHERE c.parent_id = q.id)) OR q.parent_id IN ?)", rootIDs, rootIDs).
+		Select(`COALESCE(q.parent_id, q.id) AS root_id, COUNT(*) AS total,
+			SUM(CASE WHEN ar.id IS NOT NULL AND NOT ar.is_correct THEN 1 ELSE 0 END) AS wrong,
+			COUNT(ar.id) AS attempted`).
+		Group("root_id").
+		Scan(&recs).Error
+	if err != nil {
+		return nil, err
+	}
+	recMap := make(map[uint]rec, len(recs))
+	for _, rc := range recs {
+		recMap[rc.RootID] = rc
+	}
+
+	target := node.targetDiff()
+	var picked []candidate
+	for _, c := range roots {
+		if exclude[c.ID] {
+			continue
+		}
+		rc := recMap[c.ID]
+		switch {
+		case rc.Wrong > 0:
+			c.status = "wrong"
+		case rc.Attempted > 0 && rc.Attempted >= rc.Total:
+			continue // 已全部答对
+		default:
+			c.status = "new"
+		}
+		c.fit = math.Abs(c.DiffValue - target)
+		picked = append(picked, c)
+	}
+
+	// 同档内先打乱再稳定排序，避免每次都推同几道题
+	rand.Shuffle(len(picked), func(i, j int) { picked[i], picked[j] = picked[j], picked[i] })
+	sort.SliceStable(picked, func(i, j int) bool {
+		if picked[i].status != picked[j].status {
+			return picked[i].status == "wrong"
+		}
+		return math.Round(picked[i].fit*10) < math.Round(picked[j].fit*10)
+	})
+
+	if len(picked) > limit {
+		picked = picked[:limit]
+	}
+	return picked, nil
+}
@@ -0,0 +1,419 @@
+package practice
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"med-platform/internal/common/db"
+	"med-platform/internal/question"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// =========================================================
+// 🧩 自组练习：按条件组合出一套题，可保存、重复练、分享给同学
+// 💡 条件里"没做过 / 做错 / 收藏 / 错 N 次"都是相对当前练习的人，分享出去按对方自己的记录出题
+// =========================================================
+
+const (
+	DefaultSetLimit = 50
+	MaxSetLimit     = 200
+	MaxSetScopes    = 20
+)
+
+// SetScope 出题范围：题库 + 章节 (章节为空 = 该题库下所有已授权科目)
+type SetScope struct {
+	Source   string `json:"source"`
+	Category string `json:"category"`
+}
+
+// SetFilters 组卷条件
+type SetFilters struct {
+	Scopes        []SetScope `json:"scopes"`
+	Types         []string   `json:"types"`
+	DiffMin       float64    `json:"diff_min"` // 难度系数下限 (0 = 不限)
+	DiffMax       float64    `json:"diff_max"` // 难度系数上限 (0 = 不限)
+	OnlyUndone    bool       `json:"only_undone"`
+	OnlyWrong     bool       `json:"only_wrong"` // 在错题本中
+	OnlyFavorite  bool       `json:"only_favorite"`
+	MinWrongCount int        `json:"min_wrong_count"` // 错题本中错了至少 N 次
+	Random        bool       `json:"random"`          // 随机抽取 (否则按章节顺序)
+	Limit         int        `json:"limit"`
+}
+
+// PracticeSet 保存的自组练习
+type PracticeSet struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	Title     string     `gorm:"size:100" json:"title"`
+	Filters   SetFilters `gorm:"type:jsonb;serializer:json" json:"filters"`
+	ShareCode string     `gorm:"size:16;uniqueIndex" json:"share_code"`
+	Shared    bool       `gorm:"default:false" json:"shared"` // 打开后凭分享链接可查看 / 练习 / 复制
+	RunCount  int        `gorm:"default:0" json:"run_count"`
+	LastRunAt *time.Time `json:"last_run_at"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (PracticeSet) TableName() string {
+	return "practice_sets"
+}
+
+// SetItem 练习骨架中的一道大题 (与错题本 / 收藏夹骨架同样的结构，题目内容走 /questions/:id)
+type SetItem struct {
+	ID   uint   `json:"id"`
+	Type string `json:"type"`
+}
+
+// normalize 校验并补默认值
+func (f *SetFilters) normalize() error {
+	if len(f.Scopes) == 0 {
+		return fmt.Errorf("请至少选择一个题库")
+	}
+	if len(f.Scopes) > MaxSetScopes {
+		return fmt.Errorf("范围最多选择 %d 个", MaxSetScopes)
+	}
+	for i := range f.Scopes {
+		f.Scopes[i].Source = strings.TrimSpace(f.Scopes[i].Source)
+		f.Scopes[i].Category = strings.TrimSpace(f.Scopes[i].Category)
+		if f.Scopes[i].Source == "" {
+			return fmt.Errorf("请选择题库")
+		}
+	}
+	if f.DiffMin < 0 || f.DiffMax < 0 || (f.DiffMax > 0 && f.DiffMin > f.DiffMax) {
+		return fmt.Errorf("难度范围不正确")
+	}
+	if f.MinWrongCount < 0 {
+		f.MinWrongCount = 0
+	}
+	if f.Limit <= 0 {
+		f.Limit = DefaultSetLimit
+	}
+	if f.Limit > MaxSetLimit {
+		f.Limit = MaxSetLimit
+	}
+	return nil
+}
+
+// allowedScopes 逐个范围鉴权 (checkAccess 口径)：指定章节直接校验，只选题库的展开成已授权的科目
+// 返回可出题的 (题库, 路径前缀) 与被锁定的范围数
+func allowedScopes(c *gin.Context, scopes []SetScope) ([]SetScope, int) {
+	var allowed []SetScope
+	locked := 0
+	for _, s := range scopes {
+		if s.Category != "" {
+			if question.CheckAccess(c, s.Source, s.Category) {
+				allowed = append(allowed, s)
+			} else {
+				locked++
+			}
+			continue
+		}
+		roots := question.AccessibleRoots(c, s.Source)
+		if len(roots) == 0 {
+			locked++
+		}
+		for _, root := range roots {
+			allowed = append(allowed, SetScope{Source: s.Source, Category: root})
+		}
+	}
+	return allowed, locked
+}
+
+// runSet 按条件出题 (大题粒度：组合题按父题出现一次)
+func runSet(userID uint, scopes []SetScope, f SetFilters) ([]SetItem, int64, error) {
+	groupExpr := "CASE WHEN q.parent_id IS NOT NULL AND q.parent_id > 0 THEN q.parent_id ELSE q.id END"
+	query := db.DB.Table("questions q").
+		Where("q.deleted_at IS NULL").
+		Where("NOT EXISTS (SELECT 1 FROM questions c WHERE c.parent_id = q.id AND c.deleted_at IS NULL)") // 只看可作答的小题
+
+	conds := make([]string, 0, len(scopes))
+	args := make([]interface{}, 0, len(scopes)*3)
+	for _, s := range scopes {
+		conds = append(conds, "(q.source = ? AND (q.category_path = ? OR q.category_path LIKE ?))")
+		args = append(args, s.Source, s.Category, s.Category+question.CategoryPathSep+"%")
+	}
+	query = query.Where("("+strings.Join(conds, " OR ")+")", args...)
+
+	if len(f.Types) > 0 {
+		query = query.Where("q.type IN ?", f.Types)
+	}
+	if f.DiffMin > 0 {
+		query = query.Where("q.diff_value >= ?", f.DiffMin)
+	}
+	if f.DiffMax > 0 {
+		query = query.Where("q.diff_value <= ?", f.DiffMax)
+	}
+	if f.OnlyUndone {
+		query = query.Where("NOT EXISTS (SELECT 1 FROM answer_records ar WHERE ar.question_id = q.id AND ar.user_id = ? AND ar.deleted_at IS NULL)", userID)
+	}
+	if f.OnlyWrong || f.MinWrongCount > 0 {
+		minWrong := f.MinWrongCount
+		if minWrong < 1 {
+			minWrong = 1
+		}
+		query = query.Where("EXISTS (SELECT 1 FROM user_mistakes m WHERE m.question_id = q.id AND m.user_id = ? AND m.wrong_count >= ?)", userID, minWrong)
+	}
+	if f.OnlyFavorite {
+		// 组合题可能收藏在父题上
+		query = query.Where("EXISTS (SELECT 1 FROM user_favorites uf WHERE uf.user_id = ? AND (uf.question_id = q.id OR uf.question_id = q.parent_id))", userID)
+	}
+
+	grouped := query.Select(groupExpr + " AS id, MAX(q.type) AS type, MIN(q.category_path) AS path").Group(groupExpr)
+
+	var total int64
+	if err := db.DB.Table("(?) g", grouped).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	order := "g.path asc, g.id asc"
+	if f.Random {
+		order = "random()"
+	}
+	items := []SetItem{}
+	err := db.DB.Table("(?) g", grouped).Select("g.id, g.type").Order(order).Limit(f.Limit).Scan(&items).Error
+	return items, total, err
+}
+
+// runAndRespond 鉴权 + 出题 + 输出
+func runAndRespond(c *gin.Context, f SetFilters, extra gin.H) bool {
+	scopes, locked := allowedScopes(c, f.Scopes)
+	if len(scopes) == 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "FORBIDDEN", "message": "🔒 您尚未获得该练习所选科目的访问授权"})
+		return false
+	}
+	items, total, err := runSet(c.MustGet("userID").(uint), scopes, f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "出题失败"})
+		return false
+	}
+	resp := gin.H{"data": items, "total": len(items), "matched": total, "locked": locked}
+	for k, v := range extra {
+		resp[k] = v
+	}
+	if len(items) == 0 {
+		resp["message"] = "没有符合条件的题目，换个条件试试"
+	}
+	c.JSON(http.StatusOK, resp)
+	return true
+}
+
+// =========================================================
+// 🌐 接口
+// =========================================================
+
+type setReq struct {
+	Title   string     `json:"title"`
+	Filters SetFilters `json:"filters"`
+}
+
+// loadOwnSet 取自己的练习
+func loadOwnSet(c *gin.Context) (*PracticeSet, bool) {
+	var s PracticeSet
+	if err := db.DB.Where("id = ? AND user_id = ?", c.Param("id"), c.MustGet("userID").(uint)).First(&s).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "练习不存在"})
+		return nil, false
+	}
+	return &s, true
+}
+
+// loadSharedSet 凭分享码取练习 (须已打开分享)
+func loadSharedSet(c *gin.Context) (*PracticeSet, bool) {
+	var s PracticeSet
+	if err := db.DB.Where("share_code = ? AND shared = ?", c.Param("code"), true).First(&s).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "分享链接无效或已关闭"})
+		return nil, false
+	}
+	return &s, true
+}
+
+// PreviewSet 不保存，直接按条件出一次题
+// POST /practice/sets/preview {"filters":{...}}
+func (h *Handler) PreviewSet(c *gin.Context) {
+	var req setReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.Filters.normalize(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	runAndRespond(c, req.Filters, nil)
+}
+
+// CreateSet 保存练习
+// POST /practice/sets {"title":"","filters":{"scopes":[{"source":"","category":""}],"types":["A1"],"only_wrong":true,"min_wrong_count":2,"random":true,"limit":50}}
+func (h *Handler) CreateSet(c *gin.Context) {
+	var req setReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.Filters.normalize(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if scopes, _ := allowedScopes(c, req.Filters.Scopes); len(scopes) == 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "FORBIDDEN", "message": "🔒 您尚未获得所选科目的访问授权"})
+		return
+	}
+	if strings.TrimSpace(req.Title) == "" {
+		req.Title = "我的练习"
+	}
+	s := PracticeSet{
+		UserID:    c.MustGet("userID").(uint),
+		Title:     strings.TrimSpace(req.Title),
+		Filters:   req.Filters,
+		ShareCode: strings.ReplaceAll(uuid.New().String(), "-", "")[:12],
+	}
+	if err := db.DB.Create(&s).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "练习已保存", "data": s})
+}
+
+// ListSets 我的练习
+// GET /practice/sets
+func (h *Handler) ListSets(c *gin.Context) {
+	list := []PracticeSet{}
+	db.DB.Where("user_id = ?", c.MustGet("userID").(uint)).Order("updated_at desc").Find(&list)
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// UpdateSet 修改练习的名称 / 条件
+// PUT /practice/sets/:id
+func (h *Handler) UpdateSet(c *gin.Context) {
+	s, ok := loadOwnSet(c)
+	if !ok {
+		return
+	}
+	var req setReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.Filters.normalize(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if scopes, _ := allowedScopes(c, req.Filters.Scopes); len(scopes) == 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "FORBIDDEN", "message": "🔒 您尚未获得所选科目的访问授权"})
+		return
+	}
+	if t := strings.TrimSpace(req.Title); t != "" {
+		s.Title = t
+	}
+	s.Filters = req.Filters
+	if err := db.DB.Select("title", "filters").Updates(s).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "练习已更新", "data": s})
+}
+
+// DeleteSet 删除练习 (分享链接随之失效)
+// DELETE /practice/sets/:id
+func (h *Handler) DeleteSet(c *gin.Context) {
+	s, ok := loadOwnSet(c)
+	if !ok {
+		return
+	}
+	db.DB.Delete(s)
+	c.JSON(http.StatusOK, gin.H{"message": "练习已删除"})
+}
+
+// RunSet 按保存的条件重新出题 (随机模式每次结果不同)
+// GET /practice/sets/:id/run
+func (h *Handler) RunSet(c *gin.Context) {
+	s, ok := loadOwnSet(c)
+	if !ok {
+		return
+	}
+	if runAndRespond(c, s.Filters, gin.H{"set": s}) {
+		db.DB.Model(s).UpdateColumns(map[string]interface{}{"run_count": gorm.Expr("run_count + 1"), "last_run_at": time.Now()})
+	}
+}
+
+// ShareSet 打开 / 关闭分享
+// POST /practice/sets/:id/share {"shared":true}
+func (h *Handler) ShareSet(c *gin.Context) {
+	s, ok := loadOwnSet(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		Shared bool `json:"shared"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := db.DB.Model(s).Update("shared", req.Shared).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存失败"})
+		return
+	}
+	if !req.Shared {
+		c.JSON(http.StatusOK, gin.H{"message": "已关闭分享"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已开启分享，把链接发给同学即可", "share_code": s.ShareCode})
+}
+
+// GetSharedSet 查看分享的练习
+// GET /practice/sets/shared/:code
+func (h *Handler) GetSharedSet(c *gin.Context) {
+	s, ok := loadSharedSet(c)
+	if !ok {
+		return
+	}
+	var owner struct {
+		Username string
+		Nickname string
+	}
+	db.DB.Table("users").Select("username, nickname").Where("id = ?", s.UserID).Scan(&owner)
+	if owner.Nickname == "" {
+		owner.Nickname = owner.Username
+	}
+	_, locked := allowedScopes(c, s.Filters.Scopes)
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{
+		"id": s.ID, "title": s.Title, "filters": s.Filters, "owner": owner.Nickname,
+		"share_code": s.ShareCode, "locked": locked,
+	}})
+}
+
+// RunSharedSet 练习别人分享的题单 (按自己的授权与作答记录出题)
+// GET /practice/sets/shared/:code/run
+func (h *Handler) RunSharedSet(c *gin.Context) {
+	s, ok := loadSharedSet(c)
+	if !ok {
+		return
+	}
+	runAndRespond(c, s.Filters, gin.H{"set": gin.H{"id": s.ID, "title": s.Title, "share_code": s.ShareCode}})
+}
+
+// CopySharedSet 把分享的练习存到自己名下
+// POST /practice/sets/shared/:code/copy
+func (h *Handler) CopySharedSet(c *gin.Context) {
+	s, ok := loadSharedSet(c)
+	if !ok {
+		return
+	}
+	cp := PracticeSet{
+		UserID:    c.MustGet("userID").(uint),
+		Title:     s.Title,
+		Filters:   s.Filters,
+		ShareCode: strings.ReplaceAll(uuid.New().String(), "-", "")[:12],
+	}
+	if err := db.DB.Create(&cp).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已保存到我的练习", "data": cp})
+}
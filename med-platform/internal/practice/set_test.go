@@ -0,0 +1,42 @@
+package practice
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetFiltersNormalize(t *testing.T) {
+	scope := []SetScope{{Source: "临床执业", Category: "内科"}}
+	tests := []struct {
+		name    string
+		in      SetFilters
+		want    SetFilters
+		wantErr bool
+	}{
+		{"补默认题量", SetFilters{Scopes: scope}, SetFilters{Scopes: scope, Limit: DefaultSetLimit}, false},
+		{"题量不超过上限", SetFilters{Scopes: scope, Limit: 1000}, SetFilters{Scopes: scope, Limit: MaxSetLimit}, false},
+		{"负题量按默认", SetFilters{Scopes: scope, Limit: -1}, SetFilters{Scopes: scope, Limit: DefaultSetLimit}, false},
+		{"错题次数不小于 0", SetFilters{Scopes: scope, MinWrongCount: -3, Limit: 10}, SetFilters{Scopes: scope, Limit: 10}, false},
+		{"去掉范围首尾空白",
+			SetFilters{Scopes: []SetScope{{Source: " 临床执业 ", Category: " 内科\t"}}, Limit: 10},
+			SetFilters{Scopes: scope, Limit: 10}, false},
+		{"只设难度下限", SetFilters{Scopes: scope, DiffMin: 0.3, Limit: 10}, SetFilters{Scopes: scope, DiffMin: 0.3, Limit: 10}, false},
+		{"没选范围", SetFilters{}, SetFilters{}, true},
+		{"范围太多", SetFilters{Scopes: make([]SetScope, MaxSetScopes+1)}, SetFilters{}, true},
+		{"题库为空", SetFilters{Scopes: []SetScope{{Source: "  ", Category: "内科"}}}, SetFilters{}, true},
+		{"难度下限大于上限", SetFilters{Scopes: scope, DiffMin: 0.8, DiffMax: 0.5}, SetFilters{}, true},
+		{"难度为负", SetFilters{Scopes: scope, DiffMin: -0.1}, SetFilters{}, true},
+	}
+	for _, tt := range tests {
+		f := tt.in
+		f.Scopes = append([]SetScope(nil), tt.in.Scopes...)
+		err := f.normalize()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(f, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, f, tt.want)
+		}
+	}
+}
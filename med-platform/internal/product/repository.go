@@ -32,6 +32,27 @@ func (r *Repository) CheckPermission(userID uint, source string, category string
 	return err == nil && count > 0
 }
 
+// Entitlement 用户已授权的 (题库, 一级科目)
+type Entitlement struct {
+	Source   string
+	Category string
+}
+
+// ListEntitlements 一次查出用户全部有效授权 (口径与 CheckPermission 完全一致)
+// 💡 搜索等需要按授权批量过滤的场景用它，避免逐个科目调用 CheckPermission
+func (r *Repository) ListEntitlements(userID uint) ([]Entitlement, error) {
+	var list []Entitlement
+	err := db.DB.Table("user_products").
+		Select("DISTINCT product_contents.source, product_contents.category").
+		Joins("JOIN product_contents ON user_products.product_id = product_contents.product_id").
+		Where("user_products.user_id = ?", userID).
+		Where("user_products.expire_at > ?", time.Now()).
+		Where("user_products.deleted_at IS NULL").
+		Where("product_contents.deleted_at IS NULL").
+		Scan(&list).Error
+	return list, err
+}
+
 // ==========================================
 // 🧹 级联清理逻辑 (Source/Category 删除时)
 // ==========================================
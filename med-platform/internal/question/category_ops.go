@@ -0,0 +1,506 @@
+package question
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"med-platform/internal/common/db"
+	"med-platform/internal/common/service"
+	"med-platform/internal/product"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// =========================================================
+// 🌳 目录树编辑：移动 / 合并 / 拆分
+// 💡 目录节点与题目的 category_path 在同一个事务里一起改写，不再依赖 SyncCategories 事后按字符串重建
+// =========================================================
+
+// CategoryPathSep 目录路径分隔符
+const CategoryPathSep = " > "
+
+// TreeOpResult 目录操作结果
+type TreeOpResult struct {
+	Questions  int64    `json:"questions"`             // 改写了路径的题目数 (含子题)
+	Categories int64    `json:"categories"`            // 改写了路径的目录节点数
+	Bindings   []string `json:"bindings,omitempty"`    // 为保持授权不变而调整的商品权益说明
+	Category   *uint    `json:"category_id,omitempty"` // 新建 / 合并后的目录 ID
+}
+
+// rootOfPath 路径的一级科目 (商品权益按 题库 + 一级科目 授权)
+func rootOfPath(path string) string {
+	return strings.TrimSpace(strings.Split(path, CategoryPathSep)[0])
+}
+
+// childPath 拼接子路径
+func childPath(parent *Category, name string) string {
+	if parent == nil {
+		return name
+	}
+	return parent.FullPath + CategoryPathSep + name
+}
+
+// subtreeWhere 某路径及其下级 (精确到分隔符，避免"心血管"误伤"心血管外科")
+func subtreeWhere(column string) string {
+	return "(" + column + " = @old OR " + column + " LIKE @old || ' > %')"
+}
+
+// rewritePaths 把 oldPath 子树整体改写到 newPath 下：目录节点的路径与层级、题目的路径与一级科目、冗余的 category_id
+func rewritePaths(tx *gorm.DB, source, oldPath, newPath string, levelDelta int, res *TreeOpResult) error {
+	args := map[string]interface{}{
+		"source": source, "old": oldPath, "new": newPath, "cut": len([]rune(oldPath)) + 1,
+		"delta": levelDelta, "root": rootOfPath(newPath),
+	}
+	r := tx.Exec(`UPDATE categories SET full_path = @new || SUBSTRING(full_path FROM @cut), level = level + @delta, updated_at = NOW()
+		WHERE source = @source AND `+subtreeWhere("full_path"), args)
+	if r.Error != nil {
+		return r.Error
+	}
+	res.Categories += r.RowsAffected
+
+	// 刷新 updated_at：搜索索引按新路径重建
+	r = tx.Exec(`UPDATE questions SET category_path = @new || SUBSTRING(category_path FROM @cut), category = @root, updated_at = NOW()
+		WHERE source = @source AND `+subtreeWhere("category_path"), args)
+	if r.Error != nil {
+		return r.Error
+	}
+	res.Questions += r.RowsAffected
+	return relinkCategoryIDs(tx, source, newPath)
+}
+
+// relinkCategoryIDs 按新路径回填题目与作答记录上冗余的 category_id
+func relinkCategoryIDs(tx *gorm.DB, source, path string) error {
+	args := map[string]interface{}{"source": source, "old": path}
+	if err := tx.Exec(`UPDATE questions q SET category_id = c.id FROM categories c
+		WHERE c.source = q.source AND c.full_path = q.category_path AND q.source = @source AND `+subtreeWhere("q.category_path"), args).Error; err != nil {
+		return err
+	}
+	return tx.Exec(`UPDATE answer_records ar SET category_id = q.category_id FROM questions q
+		WHERE q.id = ar.question_id AND ar.category_id <> q.category_id AND q.source = @source AND `+subtreeWhere("q.category_path"), args).Error
+}
+
+// ensureCategoryPath 按路径逐级取 / 建目录节点，返回末级
+func ensureCategoryPath(tx *gorm.DB, source, path string) (*Category, error) {
+	var parent *Category
+	for i, name := range strings.Split(path, CategoryPathSep) {
+		name = strings.TrimSpace(name)
+		full := childPath(parent, name)
+		var cat Category
+		err := tx.Where("source = ? AND full_path = ?", source, full).First(&cat).Error
+		if err == gorm.ErrRecordNotFound {
+			cat = Category{Name: name, Level: i + 1, SortOrder: 999, Source: source, FullPath: full}
+			if parent != nil {
+				cat.ParentID = &parent.ID
+			}
+			err = tx.Create(&cat).Error
+		}
+		if err != nil {
+			return nil, err
+		}
+		parent = &cat
+	}
+	return parent, nil
+}
+
+// BindingConfirmError 跨一级科目移动 / 合并时两边的商品授权不一致，需要管理员确认后才调整授权
+type BindingConfirmError struct {
+	FromRoot, ToRoot string
+	FromOnly         int64 // 只授权原科目的商品数：确认后将获得目标科目的全部内容
+	ToOnly           int64 // 只授权目标科目的商品数：将获得移入的题目
+}
+
+func (e *BindingConfirmError) Error() string {
+	return fmt.Sprintf("「%s」与「%s」的商品授权不一致：%d 个只绑定「%s」的商品将获得「%s」的全部内容，%d 个只绑定「%s」的商品将获得移入的题目。确认无误后请勾选「同步授权」(grant_access) 重试",
+		e.FromRoot, e.ToRoot, e.FromOnly, e.FromRoot, e.ToRoot, e.ToOnly, e.ToRoot)
+}
+
+// checkBindings 在改写路径之前调用：目标一级科目已存在且两边授权的商品不一致时，未确认 (grant=false) 就拒绝操作
+// 目标科目是本次新建的 (还没有任何目录) 时，沿用原科目的授权正好等于原来的访问范围，无需确认
+// 返回值 widen 交给 keepBindings：只有目标科目是新建的或已确认时，才允许给原科目的商品追加目标科目的授权
+func checkBindings(tx *gorm.DB, source, fromRoot, toRoot string, grant bool) (widen bool, err error) {
+	if fromRoot == toRoot {
+		return false, nil
+	}
+	var existed int64
+	tx.Model(&Category{}).Where("source = ? AND full_path = ?", source, toRoot).Count(&existed)
+	if existed == 0 || grant {
+		return true, nil
+	}
+	onlyIn := func(a, b string) int64 {
+		var n int64
+		tx.Raw(`SELECT COUNT(DISTINCT p.product_id) FROM product_contents p
+			WHERE p.deleted_at IS NULL AND p.source = @source AND p.category = @a
+			AND NOT EXISTS (SELECT 1 FROM product_contents x WHERE x.deleted_at IS NULL
+				AND x.product_id = p.product_id AND x.source = p.source AND x.category = @b)`,
+			map[string]interface{}{"source": source, "a": a, "b": b}).Scan(&n)
+		return n
+	}
+	e := &BindingConfirmError{FromRoot: fromRoot, ToRoot: toRoot, FromOnly: onlyIn(fromRoot, toRoot), ToOnly: onlyIn(toRoot, fromRoot)}
+	if e.FromOnly > 0 || e.ToOnly > 0 {
+		return false, e
+	}
+	return false, nil
+}
+
+// keepBindings 题目换了一级科目时，让原科目的商品同样授权新科目；move=true 时原科目已不存在，旧绑定一并移除
+// 授权按一级科目生效，追加绑定会放开目标科目的全部内容：widen=false (目标科目原本就有、且未经确认) 时不追加
+func keepBindings(tx *gorm.DB, source, fromRoot, toRoot string, move, widen bool, res *TreeOpResult) error {
+	if fromRoot == toRoot {
+		return nil
+	}
+	if widen {
+		if err := widenBindings(tx, source, fromRoot, toRoot, res); err != nil {
+			return err
+		}
+	}
+	if move {
+		d := tx.Where("source = ? AND category = ?", source, fromRoot).Delete(&product.ProductContent{})
+		if d.Error != nil {
+			return d.Error
+		}
+		if d.RowsAffected > 0 {
+			res.Bindings = append(res.Bindings, fmt.Sprintf("「%s」已不再是一级科目，移除了 %d 条旧绑定", fromRoot, d.RowsAffected))
+		}
+	}
+	return nil
+}
+
+// widenBindings 让绑定 fromRoot 的商品同时授权 toRoot
+func widenBindings(tx *gorm.DB, source, fromRoot, toRoot string, res *TreeOpResult) error {
+	r := tx.Exec(`INSERT INTO product_contents (created_at, updated_at, product_id, source, category)
+		SELECT NOW(), NOW(), p.product_id, p.source, @to FROM product_contents p
+		WHERE p.deleted_at IS NULL AND p.source = @source AND p.category = @from
+		AND NOT EXISTS (SELECT 1 FROM product_contents x WHERE x.deleted_at IS NULL
+			AND x.product_id = p.product_id AND x.source = p.source AND x.category = @to)`,
+		map[string]interface{}{"source": source, "from": fromRoot, "to": toRoot})
+	if r.Error != nil {
+		return r.Error
+	}
+	if r.RowsAffected > 0 {
+		res.Bindings = append(res.Bindings, fmt.Sprintf("%d 个绑定「%s」的商品已同时授权「%s」", r.RowsAffected, fromRoot, toRoot))
+	}
+	return nil
+}
+
+// siblingExists 同一父目录下是否已有同名节点
+func siblingExists(tx *gorm.DB, source string, parentID *uint, name string, exceptID uint) bool {
+	query := tx.Model(&Category{}).Where("source = ? AND name = ? AND id <> ?", source, name, exceptID)
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+	var count int64
+	query.Count(&count)
+	return count > 0
+}
+
+// MoveCategory 把目录子树移到新的父目录下 (newParentID 为 nil 表示移到顶层)；newName 非空时顺便改名
+// 跨一级科目且两边商品授权不一致时，grant=true 才会执行 (见 checkBindings)
+func (r *Repository) MoveCategory(id uint, newParentID *uint, newName string, grant bool) (*TreeOpResult, error) {
+	res := &TreeOpResult{}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var cat Category
+		if err := tx.First(&cat, id).Error; err != nil {
+			return fmt.Errorf("目录不存在")
+		}
+		name := cat.Name
+		if newName = strings.TrimSpace(newName); newName != "" {
+			if strings.Contains(newName, ">") {
+				return fmt.Errorf("目录名不能包含「>」")
+			}
+			name = newName
+		}
+
+		var parent *Category
+		if newParentID != nil {
+			var p Category
+			if err := tx.First(&p, *newParentID).Error; err != nil {
+				return fmt.Errorf("目标父目录不存在")
+			}
+			if p.Source != cat.Source {
+				return fmt.Errorf("不能跨题库移动，请使用题库迁移")
+			}
+			if p.ID == cat.ID || strings.HasPrefix(p.FullPath+CategoryPathSep, cat.FullPath+CategoryPathSep) {
+				return fmt.Errorf("不能移动到自身或其下级目录中")
+			}
+			parent = &p
+		}
+		if siblingExists(tx, cat.Source, newParentID, name, cat.ID) {
+			return fmt.Errorf("目标位置已有同名目录「%s」，请改用合并", name)
+		}
+
+		oldPath, newPath := cat.FullPath, childPath(parent, name)
+		if oldPath == newPath {
+			return nil
+		}
+		level := 1
+		if parent != nil {
+			level = parent.Level + 1
+		}
+		wasRoot := cat.ParentID == nil
+		widen, err := checkBindings(tx, cat.Source, rootOfPath(oldPath), rootOfPath(newPath), grant)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&cat).Updates(map[string]interface{}{"parent_id": newParentID, "name": name}).Error; err != nil {
+			return err
+		}
+		if err := rewritePaths(tx, cat.Source, oldPath, newPath, level-cat.Level, res); err != nil {
+			return err
+		}
+		// 原来是一级科目：旧科目名整体消失，绑定迁过去；否则只是部分题目换了科目，新旧科目都保留授权
+		if err := keepBindings(tx, cat.Source, rootOfPath(oldPath), rootOfPath(newPath), wasRoot, widen, res); err != nil {
+			return err
+		}
+		return refreshCategoryStats(tx, cat.Source)
+	})
+	return res, err
+}
+
+// mergeInto 把 src 目录 (含子树) 并入 dst：同名子目录递归合并，其余子目录直接挂到 dst 下
+func mergeInto(tx *gorm.DB, src, dst Category, res *TreeOpResult) error {
+	var children []Category
+	tx.Where("parent_id = ?", src.ID).Find(&children)
+	for _, ch := range children {
+		var same Category
+		if err := tx.Where("parent_id = ? AND name = ?", dst.ID, ch.Name).First(&same).Error; err == nil {
+			if err := mergeInto(tx, ch, same, res); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := tx.Model(&ch).Update("parent_id", dst.ID).Error; err != nil {
+			return err
+		}
+		if err := rewritePaths(tx, src.Source, ch.FullPath, childPath(&dst, ch.Name), dst.Level-src.Level, res); err != nil {
+			return err
+		}
+	}
+
+	// 直接挂在 src 上的题目
+	r := tx.Exec(`UPDATE questions SET category_path = @new, category = @root, updated_at = NOW() WHERE source = @source AND category_path = @old`,
+		map[string]interface{}{"source": src.Source, "old": src.FullPath, "new": dst.FullPath, "root": rootOfPath(dst.FullPath)})
+	if r.Error != nil {
+		return r.Error
+	}
+	res.Questions += r.RowsAffected
+	if err := tx.Delete(&src).Error; err != nil {
+		return err
+	}
+	res.Categories++
+	return relinkCategoryIDs(tx, src.Source, dst.FullPath)
+}
+
+// MergeCategories 合并两个同级目录：sourceID 并入 targetID 后删除
+// 合并两个一级科目且商品授权不一致时，grant=true 才会执行 (见 checkBindings)
+func (r *Repository) MergeCategories(sourceID, targetID uint, grant bool) (*TreeOpResult, error) {
+	res := &TreeOpResult{}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var src, dst Category
+		if err := tx.First(&src, sourceID).Error; err != nil {
+			return fmt.Errorf("待合并的目录不存在")
+		}
+		if err := tx.First(&dst, targetID).Error; err != nil {
+			return fmt.Errorf("目标目录不存在")
+		}
+		if src.ID == dst.ID {
+			return fmt.Errorf("不能与自身合并")
+		}
+		sameParent := (src.ParentID == nil && dst.ParentID == nil) || (src.ParentID != nil && dst.ParentID != nil && *src.ParentID == *dst.ParentID)
+		if src.Source != dst.Source || !sameParent {
+			return fmt.Errorf("只能合并同一题库下的同级目录")
+		}
+		widen, err := checkBindings(tx, src.Source, rootOfPath(src.FullPath), rootOfPath(dst.FullPath), grant)
+		if err != nil {
+			return err
+		}
+		if err := mergeInto(tx, src, dst, res); err != nil {
+			return err
+		}
+		res.Category = &dst.ID
+		if err := keepBindings(tx, src.Source, rootOfPath(src.FullPath), rootOfPath(dst.FullPath), src.ParentID == nil, widen, res); err != nil {
+			return err
+		}
+		return refreshCategoryStats(tx, src.Source)
+	})
+	return res, err
+}
+
+// SplitCategory 按选中的题目把一个目录拆成两个：选中的题 (组合题整组) 移到新建的同级目录 newName 下，子章节结构随题目一起复制
+func (r *Repository) SplitCategory(id uint, newName string, questionIDs []uint) (*TreeOpResult, error) {
+	res := &TreeOpResult{}
+	newName = strings.TrimSpace(newName)
+	if newName == "" || strings.Contains(newName, ">") {
+		return nil, fmt.Errorf("新目录名不能为空，且不能包含「>」")
+	}
+	if len(questionIDs) == 0 {
+		return nil, fmt.Errorf("请选择要拆出的题目")
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var cat Category
+		if err := tx.First(&cat, id).Error; err != nil {
+			return fmt.Errorf("目录不存在")
+		}
+		if siblingExists(tx, cat.Source, cat.ParentID, newName, 0) {
+			return fmt.Errorf("同级已有名为「%s」的目录", newName)
+		}
+
+		// 组合题整组移动 (共用题干的小题不能分家)
+		type member struct {
+			ID           uint
+			CategoryPath string
+		}
+		var members []member
+		if err := tx.Raw(`SELECT id, category_path FROM questions
+			WHERE source = @source AND `+subtreeWhere("category_path")+`
+			AND COALESCE(parent_id, id) IN (SELECT COALESCE(parent_id, id) FROM questions WHERE id IN @ids)`,
+			map[string]interface{}{"source": cat.Source, "old": cat.FullPath, "ids": questionIDs}).Scan(&members).Error; err != nil {
+			return err
+		}
+		if len(members) == 0 {
+			return fmt.Errorf("选中的题目不在该目录下")
+		}
+
+		var parent *Category
+		if cat.ParentID != nil {
+			var p Category
+			if err := tx.First(&p, *cat.ParentID).Error; err != nil {
+				return err
+			}
+			parent = &p
+		}
+		newRoot := childPath(parent, newName)
+		created, err := ensureCategoryPath(tx, cat.Source, newRoot)
+		if err != nil {
+			return err
+		}
+		tx.Model(created).Update("sort_order", cat.SortOrder)
+		res.Category = &created.ID
+
+		// 按原有的相对路径分组改写，缺的子章节在新目录下补建
+		byPath := make(map[string][]uint)
+		for _, m := range members {
+			byPath[m.CategoryPath] = append(byPath[m.CategoryPath], m.ID)
+		}
+		for oldPath, ids := range byPath {
+			newPath := newRoot + strings.TrimPrefix(oldPath, cat.FullPath)
+			if _, err := ensureCategoryPath(tx, cat.Source, newPath); err != nil {
+				return err
+			}
+			r := tx.Model(&Question{}).Unscoped().Where("id IN ?", ids).
+				Updates(map[string]interface{}{"category_path": newPath, "category": rootOfPath(newPath)})
+			if r.Error != nil {
+				return r.Error
+			}
+			res.Questions += r.RowsAffected
+		}
+		if err := relinkCategoryIDs(tx, cat.Source, newRoot); err != nil {
+			return err
+		}
+		// 拆出的新目录是新建的：若它成了新的一级科目，授权范围正好是拆出的题目
+		if err := keepBindings(tx, cat.Source, rootOfPath(cat.FullPath), rootOfPath(newRoot), false, true, res); err != nil {
+			return err
+		}
+		return refreshCategoryStats(tx, cat.Source)
+	})
+	return res, err
+}
+
+// =========================================================
+// 🌐 接口
+// =========================================================
+
+// treeOpMessage 统一的结果提示
+func treeOpMessage(action string, res *TreeOpResult) string {
+	msg := fmt.Sprintf("%s成功：改写 %d 个目录、%d 道题目", action, res.Categories, res.Questions)
+	if len(res.Bindings) > 0 {
+		msg += "；" + strings.Join(res.Bindings, "；")
+	}
+	return msg
+}
+
+// bindingConflict 商品授权需要确认时返回 409，前端提示后带 grant_access=true 重试
+func bindingConflict(c *gin.Context, err error) bool {
+	var confirm *BindingConfirmError
+	if !errors.As(err, &confirm) {
+		return false
+	}
+	c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "need_confirm": true, "data": confirm})
+	return true
+}
+
+// MoveCategory 移动目录子树 (可顺便改名)
+// POST /admin/categories/:id/move  {"parent_id": 12 | null, "name": "新名称(可选)", "grant_access": false}
+func (h *Handler) MoveCategory(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var req struct {
+		ParentID    *uint  `json:"parent_id"`
+		Name        string `json:"name"`
+		GrantAccess bool   `json:"grant_access"` // 确认跨科目移动时同步调整商品授权
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数格式错误"})
+		return
+	}
+	res, err := h.repo.MoveCategory(uint(id), req.ParentID, req.Name, req.GrantAccess)
+	if bindingConflict(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	service.WriteAudit(c.MustGet("userID").(uint), "category.move", "category", uint(id), 0, treeOpMessage("移动", res))
+	c.JSON(http.StatusOK, gin.H{"message": treeOpMessage("移动", res), "data": res})
+}
+
+// MergeCategories 合并同级目录
+// POST /admin/categories/merge  {"source_id": 3, "target_id": 5, "grant_access": false}
+func (h *Handler) MergeCategories(c *gin.Context) {
+	var req struct {
+		SourceID    uint `json:"source_id" binding:"required"`
+		TargetID    uint `json:"target_id" binding:"required"`
+		GrantAccess bool `json:"grant_access"` // 确认合并一级科目时同步调整商品授权
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数格式错误"})
+		return
+	}
+	res, err := h.repo.MergeCategories(req.SourceID, req.TargetID, req.GrantAccess)
+	if bindingConflict(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	service.WriteAudit(c.MustGet("userID").(uint), "category.merge", "category", req.TargetID, 0,
+		fmt.Sprintf("目录 #%d 并入 #%d：%s", req.SourceID, req.TargetID, treeOpMessage("合并", res)))
+	c.JSON(http.StatusOK, gin.H{"message": treeOpMessage("合并", res), "data": res})
+}
+
+// SplitCategory 按题目拆分目录
+// POST /admin/categories/:id/split  {"name": "新目录名", "question_ids": [1, 2, 3]}
+func (h *Handler) SplitCategory(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var req struct {
+		Name        string `json:"name" binding:"required"`
+		QuestionIDs []uint `json:"question_ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数格式错误"})
+		return
+	}
+	res, err := h.repo.SplitCategory(uint(id), req.Name, req.QuestionIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	service.WriteAudit(c.MustGet("userID").(uint), "category.split", "category", uint(id), 0, treeOpMessage("拆分", res))
+	c.JSON(http.StatusOK, gin.H{"message": treeOpMessage("拆分", res), "data": res})
+}
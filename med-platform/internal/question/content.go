@@ -0,0 +1,411 @@
+package question
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"med-platform/internal/common/db"
+	"med-platform/internal/common/uploader"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// =========================================================
+// 🧱 内容块：把题干 / 选项里的 markdown、HTML 混排统一拆成结构化块
+// =========================================================
+
+// blockPattern 需要单独成块的片段 (按出现顺序切分，剩下的是普通文本)
+var blockPattern = regexp.MustCompile(`(?is)` + strings.Join([]string{
+	`(?P<table><table\b.*?</table>)`,
+	`(?P<math><math\b.*?</math>)`,
+	`(?P<audio><audio\b.*?(?:</audio>|/>))`,
+	`(?P<img><img\b[^>]*>)`,
+	`!\[(?P<mdalt>[^\]]*)\]\((?P<mdimg>[^)\s]+)\)`,
+	`\[图片:(?P<cimg>[^\]]+)\]`,
+	`\[音频:(?P<caudio>[^\]]+)\]`,
+	`\$\$(?P<dlatex>.+?)\$\$`,
+	`\\\[(?P<blatex>.+?)\\\]`,
+	`\\\((?P<ilatex>.+?)\\\)`,
+	`\$(?P<slatex>[^\s$](?:[^$\n]*?[^\s$])?)\$`,
+}, "|"))
+
+var (
+	attrSrcRe     = regexp.MustCompile(`(?i)\bsrc\s*=\s*["']([^"']+)["']`)
+	attrAltRe     = regexp.MustCompile(`(?i)\b(?:alt|title)\s*=\s*["']([^"']*)["']`)
+	tableRowRe    = regexp.MustCompile(`(?is)<tr\b.*?</tr>`)
+	tableCellRe   = regexp.MustCompile(`(?is)<t[dh]\b[^>]*>(.*?)</t[dh]>`)
+	tableCaptRe   = regexp.MustCompile(`(?is)<caption\b[^>]*>(.*?)</caption>`)
+	htmlBreakRe   = regexp.MustCompile(`(?i)<br\s*/?>|</(?:p|div|li|h[1-6])>`)
+	htmlTagRe     = regexp.MustCompile(`<[^>]*>`)
+	blankLinesRe  = regexp.MustCompile(`\n{3,}`)
+	mdTableSepRe  = regexp.MustCompile(`^\|?\s*:?-{3,}:?\s*(\|\s*:?-{3,}:?\s*)*\|?$`)
+	blockGroupIdx = map[string]int{}
+)
+
+func init() {
+	for i, name := range blockPattern.SubexpNames() {
+		if name != "" {
+			blockGroupIdx[name] = i
+		}
+	}
+}
+
+// ParseBlocks 把一段混排内容拆成内容块 (原文不变，只在读取时转换)
+func ParseBlocks(content string) []ContentBlock {
+	blocks := []ContentBlock{}
+	if strings.TrimSpace(content) == "" {
+		return blocks
+	}
+	last := 0
+	for _, m := range blockPattern.FindAllStringSubmatchIndex(content, -1) {
+		blocks = append(blocks, textBlocks(content[last:m[0]])...)
+		group := func(name string) (string, bool) {
+			i := blockGroupIdx[name]
+			if m[2*i] < 0 {
+				return "", false
+			}
+			return content[m[2*i]:m[2*i+1]], true
+		}
+
+		if s, ok := group("table"); ok {
+			blocks = append(blocks, htmlTableBlock(s))
+		} else if s, ok := group("math"); ok {
+			blocks = append(blocks, ContentBlock{Type: BlockFormula, Format: "mathml", Text: s})
+		} else if s, ok := group("audio"); ok {
+			if src := attrSrcRe.FindStringSubmatch(s); src != nil {
+				blocks = append(blocks, ContentBlock{Type: BlockAudio, URL: src[1], Caption: attrValue(attrAltRe, s)})
+			}
+		} else if s, ok := group("img"); ok {
+			if src := attrSrcRe.FindStringSubmatch(s); src != nil {
+				blocks = append(blocks, ContentBlock{Type: BlockImage, URL: src[1], Caption: attrValue(attrAltRe, s)})
+			}
+		} else if s, ok := group("mdimg"); ok {
+			alt, _ := group("mdalt")
+			blocks = append(blocks, ContentBlock{Type: BlockImage, URL: s, Caption: strings.TrimSpace(alt)})
+		} else if s, ok := group("cimg"); ok {
+			blocks = append(blocks, ContentBlock{Type: BlockImage, URL: strings.TrimSpace(s)})
+		} else if s, ok := group("caudio"); ok {
+			blocks = append(blocks, ContentBlock{Type: BlockAudio, URL: strings.TrimSpace(s)})
+		} else if s, ok := group("dlatex"); ok {
+			blocks = append(blocks, ContentBlock{Type: BlockFormula, Format: "latex", Text: strings.TrimSpace(s)})
+		} else if s, ok := group("blatex"); ok {
+			blocks = append(blocks, ContentBlock{Type: BlockFormula, Format: "latex", Text: strings.TrimSpace(s)})
+		} else if s, ok := group("ilatex"); ok {
+			blocks = append(blocks, ContentBlock{Type: BlockFormula, Format: "latex", Text: strings.TrimSpace(s), Inline: true})
+		} else if s, ok := group("slatex"); ok {
+			blocks = append(blocks, ContentBlock{Type: BlockFormula, Format: "latex", Text: s, Inline: true})
+		}
+		last = m[1]
+	}
+	return append(blocks, textBlocks(content[last:])...)
+}
+
+func attrValue(re *regexp.Regexp, s string) string {
+	if m := re.FindStringSubmatch(s); m != nil {
+		return strings.TrimSpace(html.UnescapeString(m[1]))
+	}
+	return ""
+}
+
+// plainText 去掉 HTML 标签，保留段落换行
+func plainText(s string) string {
+	s = htmlBreakRe.ReplaceAllString(s, "\n")
+	s = html.UnescapeString(htmlTagRe.ReplaceAllString(s, ""))
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.TrimSpace(blankLinesRe.ReplaceAllString(s, "\n\n"))
+}
+
+// textBlocks 普通文本段；其中的 markdown 表格 (| a | b |) 单独成块
+func textBlocks(s string) []ContentBlock {
+	var blocks []ContentBlock
+	var text, table []string
+	flushText := func() {
+		if t := plainText(strings.Join(text, "\n")); t != "" {
+			blocks = append(blocks, ContentBlock{Type: BlockText, Text: t})
+		}
+		text = nil
+	}
+	flushTable := func() {
+		if len(table) >= 2 && mdTableSepRe.MatchString(table[1]) {
+			rows := [][]string{splitMDRow(table[0])}
+			for _, line := range table[2:] {
+				rows = append(rows, splitMDRow(line))
+			}
+			blocks = append(blocks, ContentBlock{Type: BlockTable, Rows: rows})
+		} else {
+			text = append(text, table...)
+		}
+		table = nil
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "|") {
+			if len(table) == 0 {
+				flushText()
+			}
+			table = append(table, strings.TrimSpace(line))
+			continue
+		}
+		if len(table) > 0 {
+			flushTable()
+		}
+		text = append(text, line)
+	}
+	if len(table) > 0 {
+		flushTable()
+	}
+	flushText()
+	return blocks
+}
+
+func splitMDRow(line string) []string {
+	line = strings.Trim(strings.TrimSpace(line), "|")
+	cells := strings.Split(line, "|")
+	for i := range cells {
+		cells[i] = plainText(cells[i])
+	}
+	return cells
+}
+
+func htmlTableBlock(s string) ContentBlock {
+	block := ContentBlock{Type: BlockTable, Rows: [][]string{}}
+	if m := tableCaptRe.FindStringSubmatch(s); m != nil {
+		block.Caption = plainText(m[1])
+	}
+	for _, tr := range tableRowRe.FindAllString(s, -1) {
+		var row []string
+		for _, cell := range tableCellRe.FindAllStringSubmatch(tr, -1) {
+			row = append(row, plainText(cell[1]))
+		}
+		if len(row) > 0 {
+			block.Rows = append(block.Rows, row)
+		}
+	}
+	return block
+}
+
+// =========================================================
+// 📦 题目的标准化内容
+// =========================================================
+
+// QuestionContent 题目的结构化内容 (题干、材料、选项、解析都是内容块数组)
+type QuestionContent struct {
+	ID       uint                      `json:"id"`
+	Type     string                    `json:"type"`
+	Stem     []ContentBlock            `json:"stem"`
+	Material []ContentBlock            `json:"material,omitempty"`
+	Options  map[string][]ContentBlock `json:"options,omitempty"`
+	Analysis []ContentBlock            `json:"analysis"`
+	Children []QuestionContent         `json:"children,omitempty"`
+}
+
+// BuildContent 原文拆块 + 附加内容块，组合题连同子题一起转换
+func BuildContent(q *Question) QuestionContent {
+	out := QuestionContent{
+		ID:       q.ID,
+		Type:     q.Type,
+		Stem:     ParseBlocks(q.Stem),
+		Material: ParseBlocks(q.Material),
+		Analysis: ParseBlocks(q.Analysis),
+	}
+	var opts map[string]string
+	if len(q.Options) > 0 {
+		_ = json.Unmarshal(q.Options, &opts)
+	}
+	if len(opts) > 0 || (q.Media != nil && len(q.Media.Options) > 0) {
+		out.Options = make(map[string][]ContentBlock)
+		for k, v := range opts {
+			out.Options[k] = ParseBlocks(v)
+		}
+	}
+	if q.Media != nil {
+		out.Stem = append(out.Stem, q.Media.Stem...)
+		for k, blocks := range q.Media.Options {
+			out.Options[k] = append(out.Options[k], blocks...)
+		}
+	}
+	for i := range q.Children {
+		out.Children = append(out.Children, BuildContent(&q.Children[i]))
+	}
+	return out
+}
+
+// GetContent 题目的结构化内容 (前端按块渲染图片、表格、公式、音频)
+// GET /questions/:id/content
+func (h *Handler) GetContent(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	q, err := h.repo.GetDetail(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "题目未找到"})
+		return
+	}
+	if !checkAccess(c, q.Source, q.CategoryPath) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "FORBIDDEN", "message": "🔒 您无权查看该题目详情"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": BuildContent(q)})
+}
+
+// =========================================================
+// 🎞️ 附加内容块的上传与保存 (后台)
+// =========================================================
+
+// mediaDir 题目图片 / 音频的正式目录
+const mediaDir = "questions"
+
+// 单个题目附加内容的上限
+const (
+	maxMediaBlocks = 30
+	maxTableRows   = 50
+	maxTableCols   = 12
+)
+
+// UploadMedia 上传题目图片或音频 (先进临时目录，保存附加内容时再固化)
+// POST /admin/questions/media/upload  (form: file, kind=image|audio)
+func (h *Handler) UploadMedia(c *gin.Context) {
+	allowed, maxSize := uploader.AllowedExtensions, int64(uploader.MaxNoteImageSize)
+	if c.PostForm("kind") == BlockAudio {
+		allowed, maxSize = uploader.AllowedAudioExtensions, uploader.MaxAudioSize
+	}
+	url, err := uploader.SaveMediaWithHash(c, "file", allowed, maxSize)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "上传成功", "url": url})
+}
+
+// UpdateMedia 保存题目的附加内容块 (整体覆盖；传空对象即清空)
+// 与编辑题目一样走版本历史：有变化时生成新版本，可查看差异、回滚
+// PUT /admin/questions/:id/media
+func (h *Handler) UpdateMedia(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var req struct {
+		QuestionMedia
+		Note string `json:"note"` // 修改说明 (可选)
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数格式错误"})
+		return
+	}
+	media := req.QuestionMedia
+	var q Question
+	if err := db.DB.First(&q, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "题目不存在"})
+		return
+	}
+
+	// 先校验全部内容块，都通过后才把临时文件固化到正式目录
+	if err := validateMediaBlocks(media.Stem, "题干"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for k, blocks := range media.Options {
+		if !isOptionKey(k) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "选项只能是 A~F"})
+			return
+		}
+		if err := validateMediaBlocks(blocks, "选项"+k); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	confirmMediaBlocks(media.Stem)
+	for _, blocks := range media.Options {
+		confirmMediaBlocks(blocks)
+	}
+
+	q.Media = &media
+	next := snapshotOf(&q)
+	var updated *Question
+	var changed bool
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		updated, changed, err = reviseQuestion(tx, q.ID, next, c.MustGet("userID").(uint), RevisionEdit, req.Note)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存失败"})
+		return
+	}
+	if !changed {
+		c.JSON(http.StatusOK, gin.H{"message": "内容没有变化", "data": updated.Media})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("附加内容已保存 (v%d)", updated.Revision), "data": updated.Media})
+}
+
+func isOptionKey(k string) bool {
+	for _, key := range optionKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// validateMediaBlocks 校验附加内容块 (顺带规整空白、补默认公式格式)，不动文件
+func validateMediaBlocks(blocks []ContentBlock, where string) error {
+	if len(blocks) > maxMediaBlocks {
+		return fmt.Errorf("%s的附加内容最多 %d 块", where, maxMediaBlocks)
+	}
+	for i := range blocks {
+		b := &blocks[i]
+		b.Caption = strings.TrimSpace(b.Caption)
+		switch b.Type {
+		case BlockText:
+			if b.Text = strings.TrimSpace(b.Text); b.Text == "" {
+				return fmt.Errorf("%s第 %d 块：文本不能为空", where, i+1)
+			}
+		case BlockImage, BlockAudio:
+			if !strings.HasPrefix(b.URL, "/uploads/") {
+				return fmt.Errorf("%s第 %d 块：请先上传文件", where, i+1)
+			}
+			allowed := uploader.AllowedExtensions
+			if b.Type == BlockAudio {
+				allowed = uploader.AllowedAudioExtensions
+			}
+			if !allowed[strings.ToLower(filepath.Ext(b.URL))] {
+				return fmt.Errorf("%s第 %d 块：文件格式与类型不符", where, i+1)
+			}
+		case BlockTable:
+			if len(b.Rows) == 0 || len(b.Rows) > maxTableRows {
+				return fmt.Errorf("%s第 %d 块：表格需有 1~%d 行", where, i+1, maxTableRows)
+			}
+			for _, row := range b.Rows {
+				if len(row) == 0 || len(row) > maxTableCols {
+					return fmt.Errorf("%s第 %d 块：表格每行需有 1~%d 列", where, i+1, maxTableCols)
+				}
+			}
+		case BlockFormula:
+			if b.Format == "" {
+				b.Format = "latex"
+			}
+			if b.Format != "latex" && b.Format != "mathml" {
+				return fmt.Errorf("%s第 %d 块：公式格式只能是 latex 或 mathml", where, i+1)
+			}
+			if b.Text = strings.TrimSpace(b.Text); b.Text == "" {
+				return fmt.Errorf("%s第 %d 块：公式不能为空", where, i+1)
+			}
+		default:
+			return fmt.Errorf("%s第 %d 块：不支持的类型 %q", where, i+1, b.Type)
+		}
+	}
+	return nil
+}
+
+// confirmMediaBlocks 把校验通过的图片 / 音频从临时目录固化到正式目录
+func confirmMediaBlocks(blocks []ContentBlock) {
+	for i := range blocks {
+		if b := &blocks[i]; b.Type == BlockImage || b.Type == BlockAudio {
+			b.URL = uploader.ConfirmImages([]string{b.URL}, mediaDir)[0]
+		}
+	}
+}
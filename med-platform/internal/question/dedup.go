@@ -0,0 +1,694 @@
+package question
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"med-platform/internal/common/db"
+	"med-platform/internal/common/logger"
+	"med-platform/internal/common/service"
+	"med-platform/internal/itemstat"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"golang.org/x/text/width"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// =========================================================
+// 🧬 题目指纹 (SimHash)：跨题库发现重复 / 近似重复题
+// =========================================================
+
+// 指纹参数
+const (
+	MaxDupDistance   = 3  // 汉明距离不超过它视为近似重复 (64 位指纹分 4 段，任一段相同即为候选)
+	minFingerprintLn = 10 // 归一后少于这么多字的题目不参与查重 (太短的题干误报太多)
+	shingleSize      = 2  // 按连续 2 字切片 (中文题干短，3 字切片对改一个字太敏感)
+	dedupSyncBatch   = 500
+)
+
+// DedupInterval 指纹增量同步频率
+const DedupInterval = 10 * time.Minute
+
+// dedupRunning 防止两轮同步 (含重建重复题簇) 重叠执行
+var dedupRunning int32
+
+// QuestionFingerprint 大题级指纹 (组合题把子题一并计入)
+type QuestionFingerprint struct {
+	QuestionID uint   `gorm:"primaryKey;autoIncrement:false" json:"question_id"`
+	Source     string `gorm:"type:varchar(100);index" json:"source"`
+	SimHash    int64  `json:"sim_hash"`
+	Band0      int    `gorm:"index" json:"-"`
+	Band1      int    `gorm:"index" json:"-"`
+	Band2      int    `gorm:"index" json:"-"`
+	Band3      int    `gorm:"index" json:"-"`
+
+	SourceUpdatedAt time.Time `gorm:"index" json:"-"` // 题目 (含子题) 最后修改时间，增量同步的水位线
+}
+
+func (QuestionFingerprint) TableName() string { return "question_fingerprints" }
+
+// DupClusterRecord 预先算好的重复题簇 (随指纹同步整体重建，后台列表直接分页查询)
+type DupClusterRecord struct {
+	ID          uint           `gorm:"primaryKey"`
+	Size        int            `gorm:"index"` // 簇内题目数 (列表按它从大到小排)
+	FirstID     uint           // 簇内最小题号 (同样大小时的次序)
+	Sources     datatypes.JSON `gorm:"type:jsonb"` // 涉及的题库 ["内科", "外科"]
+	MemberIDs   datatypes.JSON `gorm:"type:jsonb"` // 簇内题号 [12, 34]
+	CrossBank   bool           `gorm:"index"`
+	KeyConflict bool
+	Members     []DupMember `gorm:"type:jsonb;serializer:json"`
+	CreatedAt   time.Time
+}
+
+func (DupClusterRecord) TableName() string { return "question_dup_clusters" }
+
+// normalizeForSimHash 在 cleanStemForFingerprint 基础上再去掉标签、标点与全半角差异
+func normalizeForSimHash(text string) string {
+	text = cleanStemForFingerprint(rubricTagRe.ReplaceAllString(text, ""))
+	text = strings.ToLower(width.Fold.String(text))
+	var sb strings.Builder
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// fingerprintText 参与指纹的文本：题干 + 选项 (按字母序)，组合题再加上各子题
+func fingerprintText(q *Question) string {
+	parts := []string{q.Stem}
+	var opts map[string]string
+	if len(q.Options) > 0 {
+		_ = json.Unmarshal(q.Options, &opts)
+	}
+	for _, k := range optionKeys {
+		if v := opts[k]; v != "" {
+			parts = append(parts, v)
+		}
+	}
+	for i := range q.Children {
+		parts = append(parts, fingerprintText(&q.Children[i]))
+	}
+	return normalizeForSimHash(strings.Join(parts, "|"))
+}
+
+// SimHash 64 位 SimHash (特征为连续 2 字切片，等权)；文本过短时返回 ok=false
+func SimHash(text string) (uint64, bool) {
+	runes := []rune(text)
+	if len(runes) < minFingerprintLn {
+		return 0, false
+	}
+	var weights [64]int
+	for i := 0; i+shingleSize <= len(runes); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(string(runes[i : i+shingleSize])))
+		v := h.Sum64()
+		for b := 0; b < 64; b++ {
+			if v&(1<<uint(b)) != 0 {
+				weights[b]++
+			} else {
+				weights[b]--
+			}
+		}
+	}
+	var hash uint64
+	for b := 0; b < 64; b++ {
+		if weights[b] > 0 {
+			hash |= 1 << uint(b)
+		}
+	}
+	return hash, true
+}
+
+// hamming 两个指纹的汉明距离
+func hamming(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// bandsOf 指纹拆成 4 段 16 位，用于在库里快速找候选
+func bandsOf(h uint64) [4]int {
+	return [4]int{int(h & 0xFFFF), int(h >> 16 & 0xFFFF), int(h >> 32 & 0xFFFF), int(h >> 48 & 0xFFFF)}
+}
+
+func newFingerprint(q *Question, updatedAt time.Time) (QuestionFingerprint, bool) {
+	h, ok := SimHash(fingerprintText(q))
+	if !ok {
+		return QuestionFingerprint{}, false
+	}
+	b := bandsOf(h)
+	return QuestionFingerprint{
+		QuestionID: q.ID, Source: q.Source, SimHash: int64(h),
+		Band0: b[0], Band1: b[1], Band2: b[2], Band3: b[3],
+		SourceUpdatedAt: updatedAt,
+	}, true
+}
+
+// =========================================================
+// 🔄 指纹同步
+// =========================================================
+
+// StartDedupTask 启动题目指纹增量同步 (首次运行时全量计算)，每轮同步后重建重复题簇
+// 请在 main.go 中调用: question.StartDedupTask()
+func StartDedupTask() {
+	go func() {
+		time.Sleep(time.Minute)
+		SyncFingerprints()
+
+		ticker := time.NewTicker(DedupInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			SyncFingerprints()
+		}
+	}()
+}
+
+// SyncFingerprints 增量同步一轮并重建重复题簇；已有同步在跑时直接返回 false
+func SyncFingerprints() bool {
+	if !atomic.CompareAndSwapInt32(&dedupRunning, 0, 1) {
+		return false
+	}
+	defer atomic.StoreInt32(&dedupRunning, 0)
+
+	if err := syncFingerprints(); err != nil {
+		logger.Log.Error("题目指纹同步失败", zap.Error(err))
+	}
+	// 已删除的题目移出指纹表 (软删除不更新 updated_at，增量游标发现不了)
+	db.DB.Exec(`DELETE FROM question_fingerprints f WHERE NOT EXISTS (
+		SELECT 1 FROM questions q WHERE q.id = f.question_id AND q.deleted_at IS NULL)`)
+	if err := rebuildDuplicateClusters(); err != nil {
+		logger.Log.Error("重复题簇重建失败", zap.Error(err))
+	}
+	return true
+}
+
+// syncFingerprints 按 (updated_at, id) 游标扫描有变化的题目，重算其所属大题的指纹
+func syncFingerprints() error {
+	var mark *time.Time
+	db.DB.Model(&QuestionFingerprint{}).Select("MAX(source_updated_at)").Scan(&mark)
+	lastTime, lastID := time.Time{}, uint(0)
+	if mark != nil {
+		lastTime = *mark
+	}
+
+	for {
+		type changedRow struct {
+			ID        uint
+			RootID    uint
+			UpdatedAt time.Time
+		}
+		var rows []changedRow
+		if err := db.DB.Raw(`
+			SELECT id, COALESCE(parent_id, id) AS root_id, updated_at
+			FROM questions
+			WHERE deleted_at IS NULL AND (updated_at > @t OR (updated_at = @t AND id > @id))
+			ORDER BY updated_at, id
+			LIMIT @limit
+		`, map[string]interface{}{"t": lastTime, "id": lastID, "limit": dedupSyncBatch}).Scan(&rows).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+
+		rootIDs := make([]uint, 0, len(rows))
+		seen := make(map[uint]bool)
+		for _, r := range rows {
+			if !seen[r.RootID] {
+				seen[r.RootID] = true
+				rootIDs = append(rootIDs, r.RootID)
+			}
+		}
+		if err := refreshFingerprints(rootIDs); err != nil {
+			return err
+		}
+		last := rows[len(rows)-1]
+		lastTime, lastID = last.UpdatedAt, last.ID
+	}
+}
+
+// refreshFingerprints 重算指定大题的指纹 (过短的题目删除指纹)
+func refreshFingerprints(rootIDs []uint) error {
+	var roots []Question
+	if err := db.DB.Preload("Children").Where("id IN ? AND parent_id IS NULL", rootIDs).Find(&roots).Error; err != nil {
+		return err
+	}
+	var prints []QuestionFingerprint
+	var skipped []uint
+	for i := range roots {
+		q := &roots[i]
+		updatedAt := q.UpdatedAt
+		for _, child := range q.Children {
+			if child.UpdatedAt.After(updatedAt) {
+				updatedAt = child.UpdatedAt
+			}
+		}
+		if fp, ok := newFingerprint(q, updatedAt); ok {
+			prints = append(prints, fp)
+		} else {
+			skipped = append(skipped, q.ID)
+		}
+	}
+	if len(prints) > 0 {
+		if err := db.DB.Save(&prints).Error; err != nil {
+			return err
+		}
+	}
+	if len(skipped) > 0 {
+		return db.DB.Where("question_id IN ?", skipped).Delete(&QuestionFingerprint{}).Error
+	}
+	return nil
+}
+
+// nearDuplicatesOf 一次查询为一批指纹找出库里的近似题 (按距离从近到远)，结果与 hashes 一一对应
+func nearDuplicatesOf(hashes []uint64, excludeSource string) ([][]QuestionFingerprint, error) {
+	result := make([][]QuestionFingerprint, len(hashes))
+	if len(hashes) == 0 {
+		return result, nil
+	}
+	var bands [4][]int
+	for _, h := range hashes {
+		b := bandsOf(h)
+		for i := range bands {
+			bands[i] = append(bands[i], b[i])
+		}
+	}
+	var candidates []QuestionFingerprint
+	query := db.DB.Where("band0 IN ? OR band1 IN ? OR band2 IN ? OR band3 IN ?", bands[0], bands[1], bands[2], bands[3])
+	if excludeSource != "" {
+		query = query.Where("source <> ?", excludeSource)
+	}
+	if err := query.Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	for i, h := range hashes {
+		var hits []QuestionFingerprint
+		for _, c := range candidates {
+			if hamming(uint64(c.SimHash), h) <= MaxDupDistance {
+				hits = append(hits, c)
+			}
+		}
+		sort.Slice(hits, func(a, b int) bool {
+			return hamming(uint64(hits[a].SimHash), h) < hamming(uint64(hits[b].SimHash), h)
+		})
+		result[i] = hits
+	}
+	return result, nil
+}
+
+// duplicateIssues 导入时的查重提示：与其他题库疑似重复、文件内部互相重复 (只提示，不阻止导入)
+// 💡 同一题库里的同题由题目编号 / 题干指纹原地更新，不在这里提示
+func duplicateIssues(roots []*Question, source string) []ImportIssue {
+	var issues []ImportIssue
+	type seen struct {
+		hash uint64
+		row  int
+	}
+	var inFile []seen // 文件内已出现的指纹 (按行序，取最早的一处)
+	for _, q := range roots {
+		h, ok := SimHash(fingerprintText(q))
+		if !ok {
+			continue
+		}
+		for _, prev := range inFile {
+			if hamming(prev.hash, h) <= MaxDupDistance {
+				issues = append(issues, ImportIssue{Row: q.ImportRow, Level: IssueWarning, Field: "题干",
+					Message: fmt.Sprintf("与本文件第 %d 行的题目疑似重复", prev.row)})
+				break
+			}
+		}
+		inFile = append(inFile, seen{h, q.ImportRow})
+	}
+
+	// 与库里其他题库比对：按批一次查询，避免每行一次往返
+	for start := 0; start < len(inFile); start += dedupSyncBatch {
+		batch := inFile[start:min(start+dedupSyncBatch, len(inFile))]
+		hashes := make([]uint64, len(batch))
+		for i, f := range batch {
+			hashes[i] = f.hash
+		}
+		found, err := nearDuplicatesOf(hashes, source)
+		if err != nil {
+			logger.Log.Warn("导入查重失败", zap.Error(err))
+			break
+		}
+		for i, hits := range found {
+			if len(hits) == 0 {
+				continue
+			}
+			issues = append(issues, ImportIssue{Row: batch[i].row, Level: IssueWarning, Field: "题干",
+				Message: fmt.Sprintf("与题库「%s」中的题目 #%d 疑似重复 (共 %d 处相似)", hits[0].Source, hits[0].QuestionID, len(hits))})
+		}
+	}
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Row < issues[j].Row })
+	return issues
+}
+
+// =========================================================
+// 🗂️ 重复题簇
+// =========================================================
+
+// DupMember 重复簇中的一道题
+type DupMember struct {
+	ID           uint   `json:"id"`
+	Source       string `json:"source"`
+	CategoryPath string `json:"category_path"`
+	Type         string `json:"type"`
+	Stem         string `json:"stem"`
+	Correct      string `json:"correct"`
+	Answers      int64  `json:"answers"`  // 当前作答记录数 (合并时通常保留作答多的那道)
+	Distance     int    `json:"distance"` // 与簇内第一道题的汉明距离
+}
+
+// DupCluster 一组互相近似的题目
+type DupCluster struct {
+	Members     []DupMember `json:"members"`
+	CrossBank   bool        `json:"cross_bank"`   // 是否跨题库
+	KeyConflict bool        `json:"key_conflict"` // 标准答案不一致 (合并前请人工确认)
+}
+
+// ListDuplicateClusters 分页查询预先算好的重复簇 (按簇大小从大到小)
+// source 非空时只返回包含该题库的簇；crossOnly 只看跨题库的簇
+func (r *Repository) ListDuplicateClusters(source string, crossOnly bool, page, pageSize int) ([]DupCluster, int64, error) {
+	query := db.DB.Model(&DupClusterRecord{})
+	if source != "" {
+		sources, _ := json.Marshal([]string{source})
+		query = query.Where("sources @> ?", string(sources))
+	}
+	if crossOnly {
+		query = query.Where("cross_bank = ?", true)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var records []DupClusterRecord
+	if err := query.Order("size DESC, first_id ASC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&records).Error; err != nil {
+		return nil, 0, err
+	}
+	clusters := make([]DupCluster, len(records))
+	for i, rec := range records {
+		clusters[i] = DupCluster{Members: rec.Members, CrossBank: rec.CrossBank, KeyConflict: rec.KeyConflict}
+	}
+	return clusters, total, nil
+}
+
+// rebuildDuplicateClusters 按当前指纹重新聚簇，整体替换 question_dup_clusters
+func rebuildDuplicateClusters() error {
+	clusters, err := computeDuplicateClusters()
+	if err != nil {
+		return err
+	}
+	records := make([]DupClusterRecord, 0, len(clusters))
+	for _, cl := range clusters {
+		ids := make([]uint, len(cl.Members))
+		seen := make(map[string]bool)
+		var sources []string
+		for i, m := range cl.Members {
+			ids[i] = m.ID
+			if !seen[m.Source] {
+				seen[m.Source] = true
+				sources = append(sources, m.Source)
+			}
+		}
+		idsJSON, _ := json.Marshal(ids)
+		sourcesJSON, _ := json.Marshal(sources)
+		records = append(records, DupClusterRecord{
+			Size: len(cl.Members), FirstID: ids[0], Sources: sourcesJSON, MemberIDs: idsJSON,
+			CrossBank: cl.CrossBank, KeyConflict: cl.KeyConflict, Members: cl.Members,
+		})
+	}
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&DupClusterRecord{}).Error; err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&records, 200).Error
+	})
+}
+
+// computeDuplicateClusters 指纹分段自连接找候选对，汉明距离过滤后用并查集聚簇
+func computeDuplicateClusters() ([]DupCluster, error) {
+	type pair struct {
+		A, B   uint
+		HA, HB int64
+	}
+	var pairs []pair
+	for band := 0; band < 4; band++ {
+		var part []pair
+		col := fmt.Sprintf("band%d", band)
+		if err := db.DB.Raw(`
+			SELECT a.question_id AS a, b.question_id AS b, a.sim_hash AS ha, b.sim_hash AS hb
+			FROM question_fingerprints a
+			JOIN question_fingerprints b ON b.` + col + ` = a.` + col + ` AND b.question_id > a.question_id
+		`).Scan(&part).Error; err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, part...)
+	}
+
+	// 并查集聚簇
+	parent := make(map[uint]uint)
+	var find func(uint) uint
+	find = func(x uint) uint {
+		if p, ok := parent[x]; ok && p != x {
+			parent[x] = find(p)
+			return parent[x]
+		}
+		parent[x] = x
+		return x
+	}
+	hashes := make(map[uint]uint64)
+	for _, p := range pairs {
+		if hamming(uint64(p.HA), uint64(p.HB)) > MaxDupDistance {
+			continue
+		}
+		hashes[p.A], hashes[p.B] = uint64(p.HA), uint64(p.HB)
+		ra, rb := find(p.A), find(p.B)
+		if ra != rb {
+			parent[ra] = rb
+		}
+	}
+	if len(hashes) == 0 {
+		return []DupCluster{}, nil
+	}
+
+	ids := make([]uint, 0, len(hashes))
+	for id := range hashes {
+		ids = append(ids, id)
+	}
+	var questions []Question
+	if err := db.DB.Select("id, source, category_path, type, stem, correct").Where("id IN ?", ids).Find(&questions).Error; err != nil {
+		return nil, err
+	}
+	type countRow struct {
+		QuestionID uint
+		N          int64
+	}
+	var counts []countRow
+	db.DB.Table("answer_records").Select("question_id, COUNT(*) AS n").Where("question_id IN ?", ids).Group("question_id").Scan(&counts)
+	answers := make(map[uint]int64, len(counts))
+	for _, c := range counts {
+		answers[c.QuestionID] = c.N
+	}
+
+	groups := make(map[uint][]Question)
+	for _, q := range questions {
+		root := find(q.ID)
+		groups[root] = append(groups[root], q)
+	}
+	clusters := []DupCluster{}
+	for _, qs := range groups {
+		if len(qs) < 2 {
+			continue
+		}
+		sort.Slice(qs, func(i, j int) bool { return qs[i].ID < qs[j].ID })
+		cl := DupCluster{}
+		sources := make(map[string]bool)
+		keys := make(map[string]bool)
+		for _, q := range qs {
+			sources[q.Source] = true
+			keys[normalizeKey(q.Correct)] = true
+			cl.Members = append(cl.Members, DupMember{
+				ID: q.ID, Source: q.Source, CategoryPath: q.CategoryPath, Type: q.Type,
+				Stem: stemPreview(q.Stem), Correct: q.Correct, Answers: answers[q.ID],
+				Distance: hamming(hashes[qs[0].ID], hashes[q.ID]),
+			})
+		}
+		cl.CrossBank = len(sources) > 1
+		cl.KeyConflict = len(keys) > 1
+		clusters = append(clusters, cl)
+	}
+	return clusters, nil
+}
+
+// =========================================================
+// 🔗 合并重复题
+// =========================================================
+
+// MergeResult 合并结果
+type MergeResult struct {
+	Merged int   `json:"merged"` // 被并入的题目数
+	Users  int64 `json:"users"`  // 涉及的用户数
+}
+
+// MergeDuplicates 把重复题的作答记录、历史、错题、收藏、复习状态、笔记、纠错反馈迁到保留题上，再软删除重复题
+// 💡 只支持单题合并 (组合题子题对应关系复杂，请人工处理)；历史模考卷里的旧题号仍能查到 (软删除)
+func (r *Repository) MergeDuplicates(canonicalID uint, dupIDs []uint, editorID uint) (*MergeResult, error) {
+	var canonical Question
+	if err := db.DB.Preload("Children").First(&canonical, canonicalID).Error; err != nil {
+		return nil, fmt.Errorf("保留题不存在")
+	}
+	var dups []Question
+	if err := db.DB.Preload("Children").Where("id IN ? AND id <> ?", dupIDs, canonicalID).Find(&dups).Error; err != nil {
+		return nil, err
+	}
+	if len(dups) == 0 {
+		return nil, fmt.Errorf("没有可合并的重复题")
+	}
+	for _, q := range append(dups, canonical) {
+		if len(q.Children) > 0 || q.ParentID != nil {
+			return nil, fmt.Errorf("题目 #%d 是组合题，暂不支持自动合并", q.ID)
+		}
+	}
+	ids := make([]uint, len(dups))
+	for i, q := range dups {
+		ids[i] = q.ID
+	}
+
+	res := &MergeResult{Merged: len(dups)}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		tx.Raw(`SELECT COUNT(DISTINCT user_id) FROM answer_histories WHERE question_id IN ?`, ids).Scan(&res.Users)
+		args := map[string]interface{}{"canon": canonicalID, "dups": ids, "cat": canonical.CategoryID, "rev": canonical.Revision}
+		stmts := []string{
+			// 作答记录：每人只留最新的一条
+			`DELETE FROM answer_records d USING answer_records k
+				WHERE d.question_id IN @dups AND k.user_id = d.user_id
+				AND (k.question_id = @canon OR k.question_id IN @dups) AND (k.updated_at, k.id) > (d.updated_at, d.id)`,
+			`DELETE FROM answer_records k USING answer_records d
+				WHERE k.question_id = @canon AND d.question_id IN @dups AND d.user_id = k.user_id AND d.updated_at > k.updated_at`,
+			`UPDATE answer_records SET question_id = @canon, category_id = @cat, question_revision = @rev WHERE question_id IN @dups`,
+			`UPDATE answer_histories SET question_id = @canon WHERE question_id IN @dups`,
+			// 错题本：错误次数累加
+			`INSERT INTO user_mistakes (user_id, question_id, choice, wrong_count, created_at, updated_at)
+				SELECT user_id, @canon, (ARRAY_AGG(choice ORDER BY updated_at DESC))[1], SUM(wrong_count), MIN(created_at), MAX(updated_at)
+				FROM user_mistakes WHERE question_id IN @dups GROUP BY user_id
+				ON CONFLICT (user_id, question_id) DO UPDATE SET
+					wrong_count = user_mistakes.wrong_count + EXCLUDED.wrong_count,
+					updated_at = GREATEST(user_mistakes.updated_at, EXCLUDED.updated_at)`,
+			`DELETE FROM user_mistakes WHERE question_id IN @dups`,
+			// 收藏
+			`INSERT INTO user_favorites (user_id, question_id, created_at)
+				SELECT user_id, @canon, MIN(created_at) FROM user_favorites WHERE question_id IN @dups GROUP BY user_id
+				ON CONFLICT (user_id, question_id) DO NOTHING`,
+			`DELETE FROM user_favorites WHERE question_id IN @dups`,
+			// 复习状态：保留题已有的优先，否则取最近复习过的一份
+			`INSERT INTO review_states (user_id, question_id, ease_factor, interval_days, repetitions, lapses, due_at, last_reviewed_at, from_mistake, graduated, created_at, updated_at)
+				SELECT DISTINCT ON (user_id) user_id, @canon, ease_factor, interval_days, repetitions, lapses, due_at, last_reviewed_at, from_mistake, graduated, created_at, updated_at
+				FROM review_states WHERE question_id IN @dups ORDER BY user_id, updated_at DESC
+				ON CONFLICT (user_id, question_id) DO NOTHING`,
+			`DELETE FROM review_states WHERE question_id IN @dups`,
+			// 笔记与纠错反馈直接迁移 (笔记刷新 updated_at，让全文索引跟着更新)
+			`UPDATE notes SET question_id = @canon, updated_at = NOW() WHERE question_id IN @dups`,
+			`UPDATE question_feedbacks SET question_id = @canon WHERE question_id IN @dups`,
+			// 知识点取并集
+			`INSERT INTO question_tags (question_id, tag_id, created_at)
+				SELECT DISTINCT @canon, tag_id, NOW() FROM question_tags WHERE question_id IN @dups
+				ON CONFLICT (question_id, tag_id) DO NOTHING`,
+			`DELETE FROM question_tags WHERE question_id IN @dups`,
+			`DELETE FROM question_item_stats WHERE question_id IN @dups`,
+			`DELETE FROM question_fingerprints WHERE question_id IN @dups`,
+		}
+		for _, sql := range stmts {
+			if err := tx.Exec(sql, args).Error; err != nil {
+				return err
+			}
+		}
+		// 重复题的答案与保留题不一致：迁过来的作答按保留题的答案重判
+		for _, q := range dups {
+			if normalizeKey(q.Correct) == normalizeKey(canonical.Correct) {
+				continue
+			}
+			job := RegradeJob{
+				QuestionID: canonicalID, Revision: canonical.Revision, OldCorrect: q.Correct, NewCorrect: canonical.Correct,
+				EditorID: editorID, Status: RegradePending,
+			}
+			if err := tx.Create(&job).Error; err != nil {
+				return err
+			}
+			break
+		}
+		if err := tx.Where("id IN ?", ids).Delete(&Question{}).Error; err != nil {
+			return err
+		}
+		return refreshStatsOfQuestions(tx, append(ids, canonical.ID))
+	})
+	if err != nil {
+		return nil, err
+	}
+	// 已合并的簇立即从列表里移除 (其余簇等下一轮同步重建)
+	for _, id := range ids {
+		db.DB.Where("member_ids @> ?", fmt.Sprintf("[%d]", id)).Delete(&DupClusterRecord{})
+	}
+	wakeRegrade()
+	itemstat.RefreshSource(canonical.Source)
+	return res, nil
+}
+
+// =========================================================
+// 🌐 接口
+// =========================================================
+
+// ListDuplicates 近似重复题簇 (后台任务每轮指纹同步后重建，这里只查表分页)
+// GET /admin/dedup/clusters?source=&cross_only=1&page=1
+func (h *Handler) ListDuplicates(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize := 20
+
+	clusters, total, err := h.repo.ListDuplicateClusters(c.Query("source"), c.Query("cross_only") == "1" || c.Query("cross_only") == "true", page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查重失败"})
+		return
+	}
+	var builtAt *time.Time
+	db.DB.Model(&DupClusterRecord{}).Select("MAX(created_at)").Scan(&builtAt)
+	c.JSON(http.StatusOK, gin.H{"data": clusters, "total": total, "page": page, "built_at": builtAt})
+}
+
+// MergeDuplicates 合并重复题
+// POST /admin/dedup/merge  {"canonical_id": 12, "duplicate_ids": [34, 56]}
+func (h *Handler) MergeDuplicates(c *gin.Context) {
+	var req struct {
+		CanonicalID  uint   `json:"canonical_id" binding:"required"`
+		DuplicateIDs []uint `json:"duplicate_ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数格式错误"})
+		return
+	}
+	res, err := h.repo.MergeDuplicates(req.CanonicalID, req.DuplicateIDs, c.MustGet("userID").(uint))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	service.WriteAudit(c.MustGet("userID").(uint), "question.merge", "question", req.CanonicalID, int(res.Users),
+		fmt.Sprintf("合并重复题 %v → #%d", req.DuplicateIDs, req.CanonicalID))
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("已合并 %d 道重复题，迁移了 %d 位用户的作答数据", res.Merged, res.Users), "data": res})
+}
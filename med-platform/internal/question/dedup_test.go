@@ -0,0 +1,101 @@
+package question
+
+import (
+	"testing"
+
+	"gorm.io/datatypes"
+)
+
+func TestNormalizeForSimHash(t *testing.T) {
+	tests := map[string]string{
+		"患者，男，45岁。":                 "患者男45岁",
+		"<p>心电图示 <b>ST</b> 段抬高</p>": "心电图示st段抬高",
+		"ＡＢＣ（全角）abc":                "abc全角abc",
+		"【共用题干】急性心梗":                "急性心梗",
+	}
+	for in, want := range tests {
+		if got := normalizeForSimHash(in); got != want {
+			t.Errorf("normalizeForSimHash(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFingerprintText(t *testing.T) {
+	q := &Question{
+		Stem:    "下列哪项是心肌梗死的特征性改变？",
+		Options: datatypes.JSON(`{"B":"T波倒置","A":"ST段抬高"}`),
+		Children: []Question{
+			{Stem: "首选检查"},
+		},
+	}
+	want := "下列哪项是心肌梗死的特征性改变st段抬高t波倒置首选检查"
+	if got := fingerprintText(q); got != want {
+		t.Errorf("fingerprintText = %q, want %q", got, want)
+	}
+}
+
+func TestSimHash(t *testing.T) {
+	const (
+		stem    = "患者，男，45岁。突发胸骨后压榨性疼痛2小时，伴大汗，心电图示V1-V4导联ST段抬高。最可能的诊断是"
+		edited  = "患者，男，55岁。突发胸骨后压榨性疼痛2小时，伴大汗，心电图示V1-V4导联ST段抬高。最可能的诊断是"
+		another = "下列哪种药物属于血管紧张素转换酶抑制剂，常用于治疗高血压和心力衰竭的首选药物"
+	)
+	hash := func(s string) uint64 {
+		h, ok := SimHash(normalizeForSimHash(s))
+		if !ok {
+			t.Fatalf("SimHash(%q) 不应判为过短", s)
+		}
+		return h
+	}
+
+	if _, ok := SimHash("太短的题干"); ok {
+		t.Error("少于 minFingerprintLn 个字的文本不应生成指纹")
+	}
+	if hash(stem) != hash("患者 男 45岁 突发胸骨后压榨性疼痛2小时 伴大汗 心电图示v1v4导联st段抬高 最可能的诊断是") {
+		t.Error("只差标点 / 大小写的题干指纹应相同")
+	}
+	if d := hamming(hash(stem), hash(edited)); d > MaxDupDistance {
+		t.Errorf("改了一个字的题干距离 = %d，应不超过 %d", d, MaxDupDistance)
+	}
+	if d := hamming(hash(stem), hash(another)); d <= MaxDupDistance {
+		t.Errorf("不相关题干距离 = %d，应大于 %d", d, MaxDupDistance)
+	}
+}
+
+func TestHamming(t *testing.T) {
+	tests := []struct {
+		a, b uint64
+		want int
+	}{
+		{0, 0, 0},
+		{0, 1, 1},
+		{0xFF, 0x0F, 4},
+		{0, ^uint64(0), 64},
+	}
+	for _, tt := range tests {
+		if got := hamming(tt.a, tt.b); got != tt.want {
+			t.Errorf("hamming(%x, %x) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestBandsOf(t *testing.T) {
+	h := uint64(0x1234_5678_9ABC_DEF0)
+	want := [4]int{0xDEF0, 0x9ABC, 0x5678, 0x1234}
+	if got := bandsOf(h); got != want {
+		t.Errorf("bandsOf = %x, want %x", got, want)
+	}
+
+	// 距离不超过 3 的两个指纹，4 段里至少有一段完全相同 (鸽巢原理)，按段找候选不会漏
+	near := h ^ (1 << 3) ^ (1 << 20) ^ (1 << 40)
+	a, b := bandsOf(h), bandsOf(near)
+	same := 0
+	for i := range a {
+		if a[i] == b[i] {
+			same++
+		}
+	}
+	if same == 0 {
+		t.Error("距离为 3 的指纹应至少有一段相同")
+	}
+}
@@ -0,0 +1,150 @@
+package question
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"med-platform/internal/common/db"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+// importHeader 导入/导出共用的表头 (第 0 列为序号，导入时忽略；末两列题目编号、知识点可选)
+var importHeader = []interface{}{
+	"序号", "分类路径", "题型", "题干",
+	"选项A", "选项B", "选项C", "选项D", "选项E", "选项F",
+	"答案", "解析", "难度", "难度系数", "考纲", "认知层次", "题目编号", "知识点",
+}
+
+var optionKeys = []string{"A", "B", "C", "D", "E", "F"}
+
+// buildExportRows 把一道大题 (含子题) 还原成导入格式的若干行
+// 💡 必须与 ImportQuestions 的分组规则严格对称，才能"导出 -> 修改 -> 再导入"不丢信息：
+//   - A3/A4：每个子题一行，题干列 = 共用题干 + 换行 + 子题题干 (导入时取最后一行作小题题干)
+//   - B1：每个子题一行，选项列重复父题的共用备选答案 (导入时按选项指纹归组)
+//   - 其余：独立单题一行
+func buildExportRows(root Question) [][]string {
+	if len(root.Children) == 0 {
+		return [][]string{exportRow(root, root.Stem, root.Options)}
+	}
+
+	rows := make([][]string, 0, len(root.Children))
+	isB1 := strings.Contains(root.Type, "B1")
+	for _, child := range root.Children {
+		if isB1 {
+			rows = append(rows, exportRow(child, child.Stem, root.Options))
+			continue
+		}
+
+		// 子题题干只能占一行，否则再导入时会被拆错
+		subStem := strings.TrimSpace(strings.ReplaceAll(child.Stem, "\n", " "))
+		stem := root.Stem
+		switch subStem {
+		case "":
+			// 子题没有独立题干：只写共用题干，再导入时小题题干仍为空
+		case root.Stem:
+			// 子题题干与共用题干相同 (原表用【共用题干】标记的写法)，原样还原标记
+			stem = "【共用题干】" + root.Stem
+		default:
+			stem = root.Stem + "\n" + subStem
+		}
+		rows = append(rows, exportRow(child, stem, child.Options))
+	}
+	return rows
+}
+
+// exportRow 按导入模板布局生成一行 (分类路径取题目自身；末列写出题目编号，再导入时原地更新)
+func exportRow(q Question, stem string, options []byte) []string {
+	var opts map[string]string
+	if len(options) > 0 {
+		_ = json.Unmarshal(options, &opts)
+	}
+
+	diff := ""
+	if q.DiffValue > 0 {
+		diff = strconv.FormatFloat(q.DiffValue, 'f', -1, 64)
+	}
+
+	row := []string{"", q.CategoryPath, q.Type, stem}
+	for _, k := range optionKeys {
+		row = append(row, opts[k])
+	}
+	return append(row, q.Correct, q.Analysis, q.Difficulty, diff, q.Syllabus, q.CognitiveLevel, q.ExternalKey, q.Knowledge)
+}
+
+// ExportQuestions 导出题库 (整库或某个章节子树) 为导入模板格式的 Excel
+// GET /admin/questions/export?source=xxx&category=生理学 > 血液
+func (h *Handler) ExportQuestions(c *gin.Context) {
+	source := c.Query("source")
+	category := c.Query("category")
+	if source == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "必须指定题库"})
+		return
+	}
+
+	query := db.DB.Where("source = ? AND parent_id IS NULL", source).
+		Preload("Children", func(tx *gorm.DB) *gorm.DB { return tx.Order("id asc") })
+	if category != "" {
+		query = query.Where("category_path LIKE ?", category+"%")
+	}
+
+	var roots []Question
+	if err := query.Order("id asc").Find(&roots).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询题目失败"})
+		return
+	}
+	if len(roots) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该范围内没有题目"})
+		return
+	}
+	fillKnowledge(roots)
+
+	f := excelize.NewFile()
+	defer f.Close()
+	sheet := f.GetSheetName(0)
+	sw, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成 Excel 失败"})
+		return
+	}
+
+	_ = sw.SetRow("A1", importHeader)
+	rowNum := 2
+	for _, root := range roots {
+		for _, r := range buildExportRows(root) {
+			r[0] = strconv.Itoa(rowNum - 1)
+			cells := make([]interface{}, len(r))
+			for i, v := range r {
+				cells[i] = v
+			}
+			cell, _ := excelize.CoordinatesToCellName(1, rowNum)
+			if err := sw.SetRow(cell, cells); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "生成 Excel 失败"})
+				return
+			}
+			rowNum++
+		}
+	}
+	if err := sw.Flush(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成 Excel 失败"})
+		return
+	}
+
+	name := source
+	if category != "" {
+		name = source + "-" + strings.ReplaceAll(category, " > ", "-")
+	}
+	filename := fmt.Sprintf("%s-%s.xlsx", name, time.Now().Format("20060102"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename*=UTF-8''%s", url.QueryEscape(filename)))
+	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	if err := f.Write(c.Writer); err != nil {
+		c.Status(http.StatusInternalServerError)
+	}
+}
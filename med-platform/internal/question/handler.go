@@ -8,18 +8,20 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"med-platform/internal/common/db"
+	"med-platform/internal/common/logger"
+	"med-platform/internal/common/model"
+	"med-platform/internal/common/service"
 	"med-platform/internal/product"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/xuri/excelize/v2"
-	"gorm.io/datatypes"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
@@ -44,17 +46,31 @@ var (
 )
 
 func processContentImages(content string) string {
+	result, _ := processContentImagesWithReport(content)
+	return result
+}
+
+// processContentImagesWithReport 同 processContentImages，额外返回转存失败的图片地址
+// 失败的图片保留原外链，由导入校验报告提示管理员
+func processContentImagesWithReport(content string) (string, []string) {
+	return processContentImagesWith(content, downloadAndSaveImage)
+}
+
+// processContentImagesWith 用指定的下载函数转存图片 (批量导入时传入带缓存的下载器，同一张图只下一次)
+func processContentImagesWith(content string, download func(string) (string, error)) (string, []string) {
 	if content == "" {
-		return ""
+		return "", nil
 	}
+	var failed []string
 
 	content = mdImgRegex.ReplaceAllStringFunc(content, func(s string) string {
 		matches := mdImgRegex.FindStringSubmatch(s)
 		if len(matches) < 3 {
 			return s
 		}
-		localURL, err := downloadAndSaveImage(matches[2])
+		localURL, err := download(matches[2])
 		if err != nil {
+			failed = append(failed, matches[2])
 			return s
 		}
 		return fmt.Sprintf("![%s](%s)", matches[1], localURL)
@@ -65,8 +81,9 @@ func processContentImages(content string) string {
 		if len(matches) < 2 {
 			return s
 		}
-		localURL, err := downloadAndSaveImage(matches[1])
+		localURL, err := download(matches[1])
 		if err != nil {
+			failed = append(failed, matches[1])
 			return s
 		}
 		return strings.Replace(s, matches[1], localURL, 1)
@@ -77,14 +94,15 @@ func processContentImages(content string) string {
 		if len(matches) < 2 {
 			return s
 		}
-		localURL, err := downloadAndSaveImage(matches[1])
+		localURL, err := download(matches[1])
 		if err != nil {
+			failed = append(failed, matches[1])
 			return s
 		}
 		return fmt.Sprintf("![图片](%s)", localURL)
 	})
 
-	return content
+	return content, failed
 }
 
 func downloadAndSaveImage(remoteURL string) (string, error) {
@@ -162,40 +180,26 @@ func checkAccess(c *gin.Context, source string, categoryPath string) bool {
 	return product.NewRepository().CheckPermission(userID, source, rootCategory)
 }
 
-func hardDeleteQuestions(tx *gorm.DB, questionIDs []uint) error {
-	if len(questionIDs) == 0 {
-		return nil
-	}
+// CheckAccess 对外暴露的鉴权入口 (模考等跨包模块复用同一套授权口径)
+func CheckAccess(c *gin.Context, source string, categoryPath string) bool {
+	return checkAccess(c, source, categoryPath)
+}
 
-	var childIDs []uint
-	tx.Model(&Question{}).Where("parent_id IN ?", questionIDs).Pluck("id", &childIDs)
-	allIDs := append(questionIDs, childIDs...)
+// AccessibleRoots 列出当前用户在某题库下已获授权的一级科目 (按目录排序)
+// 整库出题 / 智能练习等"不指定章节"的场景，只在这些科目里抽题
+func AccessibleRoots(c *gin.Context, source string) []string {
+	var roots []string
+	db.DB.Model(&Category{}).
+		Where("source = ? AND parent_id IS NULL", source).
+		Order("sort_order asc").Pluck("full_path", &roots)
 
-	if err := tx.Exec("DELETE FROM user_favorites WHERE question_id IN ?", allIDs).Error; err != nil {
-		return err
-	}
-	if err := tx.Exec("DELETE FROM user_mistakes WHERE question_id IN ?", allIDs).Error; err != nil {
-		return err
-	}
-	if err := tx.Exec("DELETE FROM answer_records WHERE question_id IN ?", allIDs).Error; err != nil {
-		return err
-	}
-	if err := tx.Exec("DELETE FROM question_feedbacks WHERE question_id IN ?", allIDs).Error; err != nil {
-		return err
-	}
-	if err := tx.Exec("DELETE FROM note_likes WHERE note_id IN (SELECT id FROM notes WHERE question_id IN ?)", allIDs).Error; err != nil {
-		return err
-	}
-	if err := tx.Exec("DELETE FROM note_collects WHERE note_id IN (SELECT id FROM notes WHERE question_id IN ?)", allIDs).Error; err != nil {
-		return err
-	}
-	if err := tx.Exec("DELETE FROM notes WHERE question_id IN ?", allIDs).Error; err != nil {
-		return err
-	}
-	if err := tx.Unscoped().Where("id IN ?", allIDs).Delete(&Question{}).Error; err != nil {
-		return err
+	var allowed []string
+	for _, root := range roots {
+		if checkAccess(c, source, root) {
+			allowed = append(allowed, root)
+		}
 	}
-	return nil
+	return allowed
 }
 
 func cleanStem(text string) string {
@@ -477,6 +481,7 @@ func (h *Handler) GetDetail(c *gin.Context) {
 	for _, cnt := range counts {
 		noteCountMap[cnt.QuestionID] = cnt.Total
 	}
+	tagMap := tagsOfQuestions(allQIDs)
 
 	// 4. 将基础数据和个人状态捏合在一起，返回完美的大 JSON
 	currentTotalNotes := noteCountMap[q.ID]
@@ -501,6 +506,7 @@ func (h *Handler) GetDetail(c *gin.Context) {
 				"stem":            child.Stem,
 				"options":         childOpts,
 				"correct":         child.Correct,
+				"subjective":      IsSubjective(child.Type),
 				"analysis":        child.Analysis,
 				"user_record":     recordMap[child.ID], // 小题的作答记录
 				"difficulty":      child.Difficulty,
@@ -509,6 +515,7 @@ func (h *Handler) GetDetail(c *gin.Context) {
 				"cognitive_level": child.CognitiveLevel,
 				"note_count":      childNoteCount,
 				"category_path":   child.CategoryPath,
+				"knowledge_tags":  tagMap[child.ID],
 			})
 		}
 	}
@@ -519,6 +526,7 @@ func (h *Handler) GetDetail(c *gin.Context) {
 		"stem":            q.Stem,
 		"options":         optionsMap,
 		"correct":         q.Correct,
+		"subjective":      IsSubjective(q.Type),
 		"analysis":        q.Analysis,
 		"difficulty":      q.Difficulty,
 		"diff_value":      q.DiffValue,
@@ -530,6 +538,7 @@ func (h *Handler) GetDetail(c *gin.Context) {
 		"note_count":      currentTotalNotes,     // 包含子题的总笔记数
 		"children":        childrenList,
 		"category_path":   q.CategoryPath,
+		"knowledge_tags":  tagMap[q.ID],
 	}
 
 	c.JSON(http.StatusOK, gin.H{"data": item})
@@ -568,6 +577,11 @@ func (h *Handler) List(c *gin.Context) {
 		return
 	}
 
+	c.JSON(http.StatusOK, gin.H{"data": renderQuestionList(c, rawQuestions), "total": total, "page": page, "page_size": pageSize})
+}
+
+// renderQuestionList 把一页小题组装成列表返回格式：子题归到父题下，附带当前用户的作答、收藏、笔记数与知识点
+func renderQuestionList(c *gin.Context, rawQuestions []Question) []map[string]interface{} {
 	var finalQuestions []*Question
 	var parentIDsToFetch []uint
 	processedParentMap := make(map[uint]bool)
@@ -663,6 +677,7 @@ func (h *Handler) List(c *gin.Context) {
 			noteCountMap[c.QuestionID] = c.Total
 		}
 	}
+	tagMap := tagsOfQuestions(allQIDs)
 
 	var responseList []map[string]interface{}
 	for _, q := range finalQuestions {
@@ -688,6 +703,7 @@ func (h *Handler) List(c *gin.Context) {
 					"stem":            child.Stem,
 					"options":         childOpts,
 					"correct":         child.Correct,
+					"subjective":      IsSubjective(child.Type),
 					"analysis":        child.Analysis,
 					"user_record":     recordMap[child.ID],
 					"difficulty":      child.Difficulty,
@@ -696,6 +712,7 @@ func (h *Handler) List(c *gin.Context) {
 					"cognitive_level": child.CognitiveLevel,
 					"note_count":      childNoteCount,
 					"category_path":   child.CategoryPath,
+					"knowledge_tags":  tagMap[child.ID],
 				})
 			}
 		}
@@ -706,6 +723,7 @@ func (h *Handler) List(c *gin.Context) {
 			"stem":            q.Stem,
 			"options":         optionsMap,
 			"correct":         q.Correct,
+			"subjective":      IsSubjective(q.Type),
 			"analysis":        q.Analysis,
 			"difficulty":      q.Difficulty,
 			"diff_value":      q.DiffValue,
@@ -717,6 +735,7 @@ func (h *Handler) List(c *gin.Context) {
 			"note_count":      currentTotalNotes,
 			"children":        childrenList,
 			"category_path":   q.CategoryPath,
+			"knowledge_tags":  tagMap[q.ID],
 		}
 		responseList = append(responseList, item)
 	}
@@ -724,7 +743,7 @@ func (h *Handler) List(c *gin.Context) {
 	if responseList == nil {
 		responseList = []map[string]interface{}{}
 	}
-	c.JSON(http.StatusOK, gin.H{"data": responseList, "total": total, "page": page, "page_size": pageSize})
+	return responseList
 }
 
 // ... SyncCategories ...
@@ -733,6 +752,7 @@ func (h *Handler) SyncCategories(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	h.repo.RefreshCategoryStats("")
 	c.JSON(http.StatusOK, gin.H{"message": "目录已同步"})
 }
 
@@ -788,159 +808,85 @@ func (h *Handler) UpdateCategory(c *gin.Context) {
 
 // =================================================================
 // 🔥 ImportQuestions 终极修复版
+// 💡 支持重复导入：按"题目编号 / 题干指纹"匹配已有题目并原地更新，作答记录、笔记、收藏全部保留
+// dry_run=true 时只做比对，返回逐行的 新增/修改/未变/移除 清单，不写库、不下载图片
+// 导入前逐行校验：有错误时整批拒绝并返回校验报告；skip_invalid=true 则跳过错误行，只导入通过的部分
+// 支持 Excel / CSV / JSON / Word 四种格式 (见 Importer)，解析后走同一套校验、去重、写库流程
+// 正式导入转为后台任务 (图片下载很慢，大题库在请求里跑会被网关超时)，返回任务 ID 供轮询，进度同时经 WebSocket 推送
 // =================================================================
 func (h *Handler) ImportQuestions(c *gin.Context) {
 	file, err := c.FormFile("file")
 	if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "请上传文件"}); return }
 	bankName := c.PostForm("bank_name")
 	if bankName == "" { c.JSON(http.StatusBadRequest, gin.H{"error": "必须指定题库分类名称"}); return }
+	dryRun := c.PostForm("dry_run") == "true" || c.PostForm("dry_run") == "1"
+	skipInvalid := c.PostForm("skip_invalid") == "true" || c.PostForm("skip_invalid") == "1"
 	
+	importer, err := importerFor(file.Filename)
+	if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return }
 	src, err := file.Open(); if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "文件打开失败"}); return }; defer src.Close()
-	f, err := excelize.OpenReader(src); if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "Excel 解析失败"}); return }
-	rows, err := f.GetRows(f.GetSheetName(0)); if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "读取行失败"}); return }
-
-	var roots []*Question
-	var lastFingerprint string = ""
-	var currentParent *Question = nil
-	var lastQType string = ""
-
-	for i, row := range rows {
-		if i == 0 || len(row) < 4 { continue }
-		getCol := func(idx int) string {
-			if idx < len(row) { return strings.TrimSpace(row[idx]) }
-			return ""
-		}
-
-		originCategory := getCol(1); qType := getCol(2)
-		
-		fullStem := processContentImages(getCol(3)) 
-		optA, optB, optC, optD, optE, optF := processContentImages(getCol(4)), processContentImages(getCol(5)), processContentImages(getCol(6)), processContentImages(getCol(7)), processContentImages(getCol(8)), processContentImages(getCol(9))
-		rawCorrect := getCol(10)
-		analysis := processContentImages(getCol(11))
-		
-		difficulty := getCol(12)
-		diffVal, _ := strconv.ParseFloat(getCol(13), 64); if diffVal == 0 { diffVal = 0.5 }
-		syllabus := getCol(14); cognitiveLevel := getCol(15)
-
-		var finalOpts datatypes.JSON = nil
-		optsMap := make(map[string]string)
-		hasOption := false
-		if optA != "" { optsMap["A"] = optA; hasOption = true }
-		if optB != "" { optsMap["B"] = optB; hasOption = true }
-		if optC != "" { optsMap["C"] = optC; hasOption = true }
-		if optD != "" { optsMap["D"] = optD; hasOption = true }
-		if optE != "" { optsMap["E"] = optE; hasOption = true }
-		if optF != "" { optsMap["F"] = optF; hasOption = true }
-		if hasOption { optsJson, _ := json.Marshal(optsMap); finalOpts = optsJson }
-
-		optsFingerprint := fmt.Sprintf("%s|%s|%s|%s|%s", optA, optB, optC, optD, optE)
-		topCategory := ""; if parts := strings.Split(originCategory, ">"); len(parts) > 0 { topCategory = strings.TrimSpace(parts[0]) } else { topCategory = originCategory }
-
-		finalCorrect := ""
-		isSubjective := strings.Contains(qType, "问答") || strings.Contains(qType, "论述") || strings.Contains(qType, "案例") || strings.Contains(qType, "名词解释")
-		if isSubjective {
-			finalCorrect = processContentImages(rawCorrect) 
-		} else {
-			finalCorrect = strings.TrimSpace(strings.ToUpper(rawCorrect))
-		}
-
-		var childQuestion *Question = nil
-
-		if strings.Contains(qType, "A3") || strings.Contains(qType, "A4") {
-			parts := strings.Split(fullStem, "\n")
-			currentMainStem := ""; currentSubStem := ""
-
-			if len(parts) > 1 {
-				currentMainStem = strings.Join(parts[:len(parts)-1], "\n")
-				currentSubStem = parts[len(parts)-1]
-			} else if strings.Contains(fullStem, "【共用主干】") || strings.Contains(fullStem, "【共用题干】") {
-				currentMainStem = fullStem
-				currentSubStem = fullStem 
-			} else {
-				currentMainStem = fullStem; currentSubStem = "" 
-			}
+	data, err := io.ReadAll(src); if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "文件读取失败"}); return }
+	rows, f, err := importer.Parse(data); if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return }
+	handedOff := false // 文件交给后台任务后由任务负责关闭
+	defer func() { if !handedOff { f.Close() } }()
+	if len(rows) == 0 { c.JSON(http.StatusBadRequest, gin.H{"error": "文件中没有题目数据"}); return }
+
+	// 1. 逐行校验 (有错误且未选择跳过时，不写库也不下载图片)
+	issues := validateRows(rows)
+	report := newImportReport(issues)
+	if report.Errors > 0 && !skipInvalid {
+		report.AnnotatedURL = annotateImportFile(f, report.Issues)
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("校验未通过：%d 处错误，%d 处警告", report.Errors, report.Warnings), "report": report})
+		return
+	}
+	rows = dropErrorRows(rows, issues)
+	if len(rows) == 0 { c.JSON(http.StatusBadRequest, gin.H{"error": "没有校验通过的行", "report": report}); return }
 
-			currentFingerprint := cleanStemForFingerprint(currentMainStem)
-			isSameGroup := false
-			if currentParent != nil && (strings.Contains(lastQType, "A3") || strings.Contains(lastQType, "A4")) {
-				if currentFingerprint != "" && lastFingerprint != "" {
-					if strings.Contains(currentFingerprint, lastFingerprint) || strings.Contains(lastFingerprint, currentFingerprint) {
-						isSameGroup = true
-					}
-				}
-			}
+	// 1.1 查重与知识点：近似重复的题、无法识别的知识点给出警告 (不拦截导入)
+	extra := append(duplicateIssues(buildQuestionTrees(rows, bankName), bankName), knowledgeIssues(rows)...)
+	if len(extra) > 0 {
+		issues = append(issues, extra...)
+		report = newImportReport(issues)
+	}
 
-			if isSameGroup {
-				if currentSubStem == "" && len(parts) <= 1 { currentSubStem = "" }
-				childQuestion = &Question{
-					Type: qType, Stem: cleanStem(currentSubStem), Options: finalOpts, Correct: finalCorrect,
-					Analysis: analysis, Category: topCategory, CategoryPath: originCategory, Source: bankName, 
-					Difficulty: difficulty, DiffValue: diffVal, Syllabus: syllabus, CognitiveLevel: cognitiveLevel,
-				}
-				currentParent.Children = append(currentParent.Children, *childQuestion)
-			} else {
-				newParent := &Question{
-					Type: qType, Stem: cleanStem(currentMainStem), Category: topCategory, CategoryPath: originCategory,
-					Source: bankName, ParentID: nil, Children: []Question{},
-				}
-				roots = append(roots, newParent)
-				currentParent = newParent; lastFingerprint = currentFingerprint; lastQType = qType
+	// 2. 预览：只比对不写库，同步返回
+	if dryRun {
+		if len(report.Issues) > 0 {
+			report.AnnotatedURL = annotateImportFile(f, report.Issues)
+		}
+		plan, err := h.repo.PlanImport(bankName, buildQuestionTrees(rows, bankName))
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "比对现有题目失败"}); return }
+		c.JSON(http.StatusOK, gin.H{"dry_run": true, "summary": plan.Summary, "rows": plan.Rows, "removed": plan.Removed, "report": report})
+		return
+	}
 
-				childQuestion = &Question{
-					Type: qType, Stem: cleanStem(currentSubStem), Options: finalOpts, Correct: finalCorrect,
-					Analysis: analysis, Category: topCategory, CategoryPath: originCategory, Source: bankName, 
-					Difficulty: difficulty, DiffValue: diffVal, Syllabus: syllabus, CognitiveLevel: cognitiveLevel,
-				}
-				currentParent.Children = append(currentParent.Children, *childQuestion)
-			}
+	// 3. 正式导入：建任务，后台转存图片并写库
+	job := &ImportJob{
+		UserID: c.MustGet("userID").(uint), Source: bankName, FileName: file.Filename,
+		Status: ImportJobPending, Total: len(rows), Errors: report.Errors, Warnings: report.Warnings,
+	}
+	created, err := h.repo.CreateImportJob(job)
+	if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "创建导入任务失败"}); return }
+	if !created {
+		c.JSON(http.StatusConflict, gin.H{"error": "该题库已有导入任务在进行中，请等待完成后再试"})
+		return
+	}
 
-		} else if strings.Contains(qType, "B1") {
-			isSameGroup := false
-			if currentParent != nil && strings.Contains(lastQType, "B1") {
-				if optsFingerprint == lastFingerprint { isSameGroup = true }
-			}
-			if isSameGroup {
-				childQuestion = &Question{
-					Type: qType, Stem: cleanStem(fullStem), Options: nil, Correct: finalCorrect,
-					Analysis: analysis, Category: topCategory, CategoryPath: originCategory, Source: bankName, 
-					Difficulty: difficulty, DiffValue: diffVal, Syllabus: syllabus, CognitiveLevel: cognitiveLevel,
-				}
-				currentParent.Children = append(currentParent.Children, *childQuestion)
-			} else {
-				newParent := &Question{
-					Type: qType, Stem: cleanStem(fullStem), Options: finalOpts, Category: topCategory, CategoryPath: originCategory,
-					Source: bankName, ParentID: nil, Children: []Question{},
-				}
-				roots = append(roots, newParent)
-				currentParent = newParent; lastFingerprint = optsFingerprint; lastQType = qType
-				childQuestion = &Question{
-					Type: qType, Stem: cleanStem(fullStem), Options: nil, Correct: finalCorrect,
-					Analysis: analysis, Category: topCategory, CategoryPath: originCategory, Source: bankName, 
-					Difficulty: difficulty, DiffValue: diffVal, Syllabus: syllabus, CognitiveLevel: cognitiveLevel,
-				}
-				currentParent.Children = append(currentParent.Children, *childQuestion)
-			}
+	handedOff = true
+	task := &importTask{repo: h.repo, job: job, file: f, rows: rows, issues: issues}
+	go task.run()
 
-		} else {
-			lastFingerprint = ""; currentParent = nil; lastQType = qType
-			q := &Question{
-				Type: qType, Stem: cleanStem(fullStem), Options: finalOpts, Correct: finalCorrect,
-				Analysis: analysis, Category: topCategory, CategoryPath: originCategory, Source: bankName,
-				Difficulty: difficulty, DiffValue: diffVal, Syllabus: syllabus, CognitiveLevel: cognitiveLevel, ParentID: nil,
-			}
-			roots = append(roots, q)
-		}
-	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "校验通过，已转入后台导入", "job_id": job.ID, "data": job})
+}
 
-	sort.SliceStable(roots, func(i, j int) bool { return getTypeWeight(roots[i].Type) < getTypeWeight(roots[j].Type) })
-	insertCount := 0
-	for _, root := range roots {
-		if err := db.DB.Create(root).Error; err == nil {
-			if len(root.Children) > 0 { insertCount += len(root.Children) } else { insertCount++ }
-		}
+// annotateImportFile 生成带批注的 Excel 副本，失败时只记日志 (不影响导入本身)
+func annotateImportFile(f *excelize.File, issues []ImportIssue) string {
+	u, err := saveAnnotatedCopy(f, issues)
+	if err != nil {
+		logger.Log.Warn("生成导入校验报告失败", zap.Error(err))
+		return ""
 	}
-	h.repo.SyncCategories()
-	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("成功导入 %d 道小题 (图片已转存)", insertCount)})
+	return u
 }
 
 // ... Admin Ops ...
@@ -1009,6 +955,8 @@ func (h *Handler) TransferCategory(c *gin.Context) {
 		return
 	}
 	tx.Commit()
+	h.repo.RefreshCategoryStats(req.FromSource)
+	h.repo.RefreshCategoryStats(req.ToSource)
 	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("迁移成功！共移动 %d 道题，%d 个目录节点", affectedQuestions, len(catIDs))})
 }
 
@@ -1048,8 +996,10 @@ type UpdateQuestionReq struct {
 	DiffValue      float64           `json:"diff_value"`
 	Syllabus       string            `json:"syllabus"`
 	CognitiveLevel string            `json:"cognitive_level"`
+	Note           string            `json:"note"` // 修改说明 (记入版本历史)
 }
 
+// UpdateQuestion 编辑题目：不再原地覆盖，每次有实际改动都会生成一个新版本 (见 revision.go)
 func (h *Handler) UpdateQuestion(c *gin.Context) {
 	id := c.Param("id")
 	var req UpdateQuestionReq
@@ -1062,6 +1012,7 @@ func (h *Handler) UpdateQuestion(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "题目不存在"})
 		return
 	}
+	oldType := q.Type
 	q.Stem = req.Stem
 	q.Type = req.Type
 	q.Correct = req.Correct
@@ -1076,11 +1027,27 @@ func (h *Handler) UpdateQuestion(c *gin.Context) {
 	} else {
 		q.Options = nil
 	}
-	if err := db.DB.Save(&q).Error; err != nil {
+
+	var updated *Question
+	var changed bool
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		updated, changed, err = reviseQuestion(tx, q.ID, snapshotOf(&q), c.MustGet("userID").(uint), RevisionEdit, req.Note)
+		return err
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新失败"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "题目已更新", "data": q})
+	if !changed {
+		c.JSON(http.StatusOK, gin.H{"message": "内容没有变化", "data": updated})
+		return
+	}
+	wakeRegrade()
+	if updated.Type != oldType {
+		h.repo.RefreshCategoryStats(updated.Source) // 题型变了 (如改成组合题) 会影响计数口径
+	}
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("题目已更新 (v%d)", updated.Revision), "data": updated})
 }
 
 func (h *Handler) DeleteSource(c *gin.Context) {
@@ -1092,45 +1059,44 @@ func (h *Handler) DeleteSource(c *gin.Context) {
 		return
 	}
 
-	tx := db.DB.Begin()
-
 	var qIDs []uint
-	tx.Model(&Question{}).Where("source = ?", req.SourceName).Pluck("id", &qIDs)
-
-	if err := hardDeleteQuestions(tx, qIDs); err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "题目数据清理失败"})
-		return
-	}
-
-	if err := tx.Unscoped().Where("source = ?", req.SourceName).Delete(&Category{}).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "目录清理失败"})
-		return
-	}
-
-	if err := tx.Where("source = ?", req.SourceName).Delete(&product.ProductContent{}).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "商品权益清理失败"})
+	db.DB.Model(&Question{}).Where("source = ? AND parent_id IS NULL", req.SourceName).Pluck("id", &qIDs)
+
+	entry := &model.TrashEntry{Kind: model.TrashBank, Source: req.SourceName, Title: req.SourceName, DeletedBy: c.MustGet("userID").(uint)}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		return h.repo.TrashQuestions(tx, entry, qIDs,
+			trashTable{"categories", "source = @source"},
+			trashTable{"product_contents", "source = @source"},
+		)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "题库删除失败"})
 		return
 	}
-
-	tx.Commit()
-	c.JSON(http.StatusOK, gin.H{"message": "题库已彻底删除，相关商品权益已同步移除"})
+	service.WriteAudit(entry.DeletedBy, "trash.bank", entry.Kind, entry.ID, int(entry.Impact.Users), "删除题库: "+req.SourceName)
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("题库已移入回收站 (%d 天内可恢复)，相关商品权益已同步移除", int(trashRetention().Hours()/24)), "data": entry})
 }
 
 func (h *Handler) DeleteQuestion(c *gin.Context) {
 	idStr := c.Param("id")
 	id, _ := strconv.Atoi(idStr)
 
-	tx := db.DB.Begin()
-	if err := hardDeleteQuestions(tx, []uint{uint(id)}); err != nil {
-		tx.Rollback()
+	var q Question
+	if err := db.DB.Select("id, source, category_path, stem").First(&q, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "题目不存在"})
+		return
+	}
+	entry := &model.TrashEntry{Kind: model.TrashQuestion, Source: q.Source, CategoryPath: q.CategoryPath,
+		Title: fmt.Sprintf("#%d %s", q.ID, stemPreview(q.Stem)), DeletedBy: c.MustGet("userID").(uint)}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		return h.repo.TrashQuestions(tx, entry, []uint{q.ID})
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除失败"})
 		return
 	}
-	tx.Commit()
-	c.JSON(http.StatusOK, gin.H{"message": "题目已彻底删除"})
+	service.WriteAudit(entry.DeletedBy, "trash.questions", entry.Kind, entry.ID, int(entry.Impact.Users), "删除题目: "+entry.Title)
+	c.JSON(http.StatusOK, gin.H{"message": "题目已移入回收站", "data": entry})
 }
 
 type BatchDeleteReq struct {
@@ -1149,15 +1115,20 @@ func (h *Handler) BatchDeleteQuestions(c *gin.Context) {
 		return
 	}
 
-	tx := db.DB.Begin()
-	if err := hardDeleteQuestions(tx, req.IDs); err != nil {
-		tx.Rollback()
+	var first Question
+	db.DB.Select("id, source").Where("id IN ?", req.IDs).First(&first)
+	entry := &model.TrashEntry{Kind: model.TrashQuestion, Source: first.Source,
+		Title: fmt.Sprintf("批量删除 %d 道题目", len(req.IDs)), DeletedBy: c.MustGet("userID").(uint)}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		return h.repo.TrashQuestions(tx, entry, req.IDs)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "批量删除失败: " + err.Error()})
 		return
 	}
-	tx.Commit()
+	service.WriteAudit(entry.DeletedBy, "trash.questions", entry.Kind, entry.ID, int(entry.Impact.Users), entry.Title)
 
-	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("已彻底删除 %d 道题目及关联数据", len(req.IDs))})
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("已将 %d 道题目及关联数据移入回收站", len(req.IDs)), "data": entry})
 }
 
 func (h *Handler) DeleteByCategory(c *gin.Context) {
@@ -1169,44 +1140,34 @@ func (h *Handler) DeleteByCategory(c *gin.Context) {
 		return
 	}
 
-	tx := db.DB.Begin()
-
 	var qIDs []uint
-	qQuery := tx.Model(&Question{}).Where("category_path LIKE ?", categoryPath+"%")
+	qQuery := db.DB.Model(&Question{}).Where("category_path LIKE ? AND parent_id IS NULL", categoryPath+"%")
 	if source != "" {
 		qQuery = qQuery.Where("source = ?", source)
 	}
 	qQuery.Pluck("id", &qIDs)
 
-	if len(qIDs) > 0 {
-		if err := hardDeleteQuestions(tx, qIDs); err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "题目清理失败"})
-			return
-		}
-	}
-
-	catQuery := tx.Unscoped().Where("full_path LIKE ?", categoryPath+"%")
+	catWhere := "full_path LIKE @path || '%'"
 	if source != "" {
-		catQuery = catQuery.Where("source = ?", source)
-	}
-	if err := catQuery.Delete(&Category{}).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "目录结构清理失败"})
-		return
+		catWhere += " AND source = @source"
 	}
-
+	extra := []trashTable{{"categories", catWhere}}
 	parts := strings.Split(categoryPath, "/")
-	if len(parts) == 1 { 
-		if err := tx.Where("source = ? AND category = ?", source, parts[0]).Delete(&product.ProductContent{}).Error; err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "商品权益清理失败"})
-			return
-		}
+	if len(parts) == 1 {
+		extra = append(extra, trashTable{"product_contents", "source = @source AND category = @path AND deleted_at IS NULL"})
 	}
 
-	tx.Commit()
-	c.JSON(http.StatusOK, gin.H{"message": "章节及题目已彻底粉碎，相关商品权益已更新"})
+	entry := &model.TrashEntry{Kind: model.TrashCategory, Source: source, CategoryPath: categoryPath,
+		Title: categoryPath, DeletedBy: c.MustGet("userID").(uint)}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		return h.repo.TrashQuestions(tx, entry, qIDs, extra...)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "章节删除失败"})
+		return
+	}
+	service.WriteAudit(entry.DeletedBy, "trash.category", entry.Kind, entry.ID, int(entry.Impact.Users), "删除章节: "+categoryPath)
+	c.JSON(http.StatusOK, gin.H{"message": "章节及题目已移入回收站，相关商品权益已更新", "data": entry})
 }
 
 // ==========================================
@@ -1282,26 +1243,57 @@ func (h *Handler) AdminListFeedbacks(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": list, "total": total, "page": page})
 }
 
+// AdminResolveFeedback 处理纠错反馈
+// 💡 "答案错误" 类反馈可直接带上 correct 修正标准答案：生成新版本并自动重判已有作答
 func (h *Handler) AdminResolveFeedback(c *gin.Context) {
 	id := c.Param("id")
 	var req struct {
 		Status     int    `json:"status"` 
 		AdminReply string `json:"admin_reply"`
+		Correct    string `json:"correct"` // 可选：修正后的标准答案
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	var fb QuestionFeedback
+	if err := db.DB.First(&fb, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "反馈不存在"})
+		return
+	}
+
 	updateData := map[string]interface{}{
 		"status":      req.Status,
 		"admin_reply": req.AdminReply,
 	}
 
-	if err := db.DB.Model(&QuestionFeedback{}).Where("id = ?", id).Updates(updateData).Error; err != nil {
+	var keyChanged bool
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if newKey := strings.TrimSpace(req.Correct); newKey != "" {
+			var q Question
+			if err := tx.First(&q, fb.QuestionID).Error; err != nil {
+				return err
+			}
+			next := snapshotOf(&q)
+			next.Correct = newKey
+			var err error
+			_, keyChanged, err = reviseQuestion(tx, q.ID, next, c.MustGet("userID").(uint), RevisionEdit, fmt.Sprintf("纠错反馈 #%d: 修正答案", fb.ID))
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Model(&QuestionFeedback{}).Where("id = ?", fb.ID).Updates(updateData).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "处理失败"})
 		return
 	}
 
+	if keyChanged {
+		wakeRegrade()
+		c.JSON(http.StatusOK, gin.H{"message": "处理完成，答案已修正，正在后台重新判分"})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "处理完成"})
 }
\ No newline at end of file
@@ -0,0 +1,44 @@
+package question
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// csvImporter CSV 文件：列布局与 Excel 模板完全相同，首行为表头
+// 💡 Excel "另存为 CSV" 在中文 Windows 上默认是 GBK 编码，不是合法 UTF-8 时按 GB18030 转码
+type csvImporter struct{}
+
+func (csvImporter) Parse(data []byte) ([]ImportRow, *excelize.File, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		decoded, err := simplifiedchinese.GB18030.NewDecoder().Bytes(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("CSV 编码无法识别，请另存为 UTF-8")
+		}
+		data = decoded
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1 // 允许列数不一致，交给校验报错
+	reader.LazyQuotes = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("CSV 解析失败: %v", err)
+	}
+
+	// 行号按记录计 (带换行的单元格会占多行文本，但报告里的行号与转换后的模板一致)
+	var rows []ImportRow
+	for i, record := range records {
+		if i == 0 || isBlankRow(record) {
+			continue
+		}
+		rows = append(rows, rowFromCells(record, i+1))
+	}
+	return rows, rowsToWorkbook(rows), nil
+}
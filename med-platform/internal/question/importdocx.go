@@ -0,0 +1,442 @@
+package question
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// docxImporter Word 文档 (.docx)：按常见的纸质题册排版逐段识别
+//
+//	分类：生理学 > 血液            (或【分类】，设置之后题目的分类路径)
+//	一、A1型题                     (或 题型：A1型题；单选题 / 多选题 分别视为 A1 / X 型题)
+//	1. 题干……                      (数字编号或 Word 自动编号开始一道新题)
+//	A. 选项  B. 选项               (一行可以写多个选项；字母格式的自动编号视为选项)
+//	答案：A    解析：……            (也支持【答案】【解析】，解析可跨多段)
+//	（1~3题共用题干）……            A3/A4：之后的小题自动拼上共用题干
+//	（1~3题共用备选答案）A. … B. … B1：之后没有选项的小题沿用共用选项
+//
+// 💡 文档内嵌的图片不会导入，需要图片请用图片链接
+type docxImporter struct{}
+
+var (
+	docxNumberedRe   = regexp.MustCompile(`^(\d+)\s*[.、．)）]\s*(.*)$`)
+	docxOptionRe     = regexp.MustCompile(`(?:^|\s)([A-F])\s*[.、．)）:：]\s*`)
+	docxSectionRe    = regexp.MustCompile(`^(?:[一二三四五六七八九十]+\s*[、.．]\s*)?((?:A1|A2|A3|A4|B1|X)\s*型题|单选题|多选题|判断题|填空题|名词解释|简答题|问答题|论述题|案例分析题)`)
+	docxSharedStemRe = regexp.MustCompile(`^[(（【\[][^)）】\]]*共用(?:题干|主干)[^)）】\]]*[)）】\]]\s*(.*)$`)
+	docxSharedOptRe  = regexp.MustCompile(`^[(（【\[][^)）】\]]*共用备选答案[^)）】\]]*[)）】\]]\s*(.*)$`)
+	docxFieldRe      = regexp.MustCompile(`(?:【(分类|题型|正确答案|答案|答案解析|解析|难度|考纲|认知层次|题目编号|编号)】|(分类|题型|正确答案|答案|答案解析|解析|难度|考纲|认知层次|题目编号|编号)\s*[:：])\s*`)
+)
+
+// 自动编号的段落类型
+const (
+	docxNumNone   = iota // 无自动编号
+	docxNumStem          // 数字等编号：新题
+	docxNumOption        // 字母编号，或没有格式信息的下级编号：选项
+)
+
+// docxParagraph 文档中的一段文字
+type docxParagraph struct {
+	Text  string
+	NumID string // Word 自动编号 (编号本身不在文字里)，"" 或 "0" 表示没有编号
+	Level int    // 自动编号的级别 (w:ilvl)
+}
+
+// docxNumbering word/numbering.xml 中各编号、各级别的编号格式 (decimal / upperLetter ...)
+type docxNumbering map[string]map[int]string
+
+// kind 判断段落的自动编号是题号还是选项号
+func (n docxNumbering) kind(para docxParagraph) int {
+	if para.NumID == "" || para.NumID == "0" {
+		return docxNumNone
+	}
+	switch format := n[para.NumID][para.Level]; {
+	case strings.HasSuffix(format, "Letter"):
+		return docxNumOption
+	case format == "" && para.Level > 0:
+		return docxNumOption
+	}
+	return docxNumStem
+}
+
+func (docxImporter) Parse(data []byte) ([]ImportRow, *excelize.File, error) {
+	paragraphs, numbering, err := readDocxParagraphs(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	p := &docxParser{}
+	for _, para := range paragraphs {
+		kind := numbering.kind(para)
+		for i, line := range strings.Split(para.Text, "\n") {
+			if i > 0 {
+				kind = docxNumNone
+			}
+			p.feed(strings.TrimSpace(line), kind)
+		}
+	}
+	p.flush()
+	return p.rows, rowsToWorkbook(p.rows), nil
+}
+
+// openDocxPart 打开 .docx 包内的一个部件，不存在时返回 nil
+func openDocxPart(zr *zip.Reader, name string) (io.ReadCloser, error) {
+	for _, zf := range zr.File {
+		if zf.Name == name {
+			return zf.Open()
+		}
+	}
+	return nil, nil
+}
+
+// readDocxNumbering 读取 word/numbering.xml：编号 (w:num) → 抽象编号 (w:abstractNum) → 各级别的 w:numFmt
+// 文档没有自动编号时该部件不存在，返回空表
+func readDocxNumbering(zr *zip.Reader) docxNumbering {
+	part, err := openDocxPart(zr, "word/numbering.xml")
+	if err != nil || part == nil {
+		return docxNumbering{}
+	}
+	defer part.Close()
+
+	abstract := make(map[string]map[int]string)
+	numToAbstract := make(map[string]string)
+	var absID, numID string
+	level := 0
+	decoder := xml.NewDecoder(part)
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		t, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch t.Name.Local {
+		case "abstractNum":
+			absID, numID = docxAttr(t, "abstractNumId"), ""
+			abstract[absID] = make(map[int]string)
+		case "lvl":
+			fmt.Sscan(docxAttr(t, "ilvl"), &level)
+		case "numFmt":
+			if absID != "" && numID == "" {
+				abstract[absID][level] = docxAttr(t, "val")
+			}
+		case "num":
+			numID = docxAttr(t, "numId")
+		case "abstractNumId":
+			if numID != "" {
+				numToAbstract[numID] = docxAttr(t, "val")
+			}
+		}
+	}
+
+	result := make(docxNumbering, len(numToAbstract))
+	for num, abs := range numToAbstract {
+		result[num] = abstract[abs]
+	}
+	return result
+}
+
+// docxAttr 取元素属性 (忽略 w: 命名空间)
+func docxAttr(el xml.StartElement, name string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// readDocxParagraphs 读取 word/document.xml 的段落文字 (只取正文 w:t，忽略域代码与修订删除的文字)
+func readDocxParagraphs(data []byte) ([]docxParagraph, docxNumbering, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("Word 文件解析失败，请确认是 .docx 格式")
+	}
+	doc, err := openDocxPart(zr, "word/document.xml")
+	if err != nil {
+		return nil, nil, fmt.Errorf("Word 文件解析失败")
+	}
+	if doc == nil {
+		return nil, nil, fmt.Errorf("Word 文件缺少正文")
+	}
+	defer doc.Close()
+
+	var result []docxParagraph
+	var buf strings.Builder
+	var current docxParagraph
+	inText, inNumPr := false, false
+	decoder := xml.NewDecoder(doc)
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("Word 正文解析失败")
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				buf.Reset()
+				current = docxParagraph{}
+			case "numPr":
+				inNumPr = true
+			case "ilvl":
+				if inNumPr {
+					fmt.Sscan(docxAttr(t, "val"), &current.Level)
+				}
+			case "numId":
+				if inNumPr {
+					current.NumID = docxAttr(t, "val")
+				}
+			case "t":
+				inText = true
+			case "tab":
+				buf.WriteString(" ")
+			case "br", "cr":
+				buf.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "numPr":
+				inNumPr = false
+			case "t":
+				inText = false
+			case "p":
+				current.Text = buf.String()
+				if strings.TrimSpace(current.Text) != "" {
+					result = append(result, current)
+				}
+			}
+		case xml.CharData:
+			if inText {
+				buf.Write(t)
+			}
+		}
+	}
+	return result, readDocxNumbering(zr), nil
+}
+
+// docxParser 逐行识别题目的状态机
+type docxParser struct {
+	rows []ImportRow
+
+	category string
+	qType    string
+
+	sharedStem    string    // A3/A4 共用题干
+	sharedOptions [6]string // B1 共用备选答案
+	inShared      string    // 正在读取的共用内容：stem / options
+
+	current *ImportRow
+	last    *string // 续行追加到的字段 (题干、选项、解析可以跨多段)
+}
+
+func (p *docxParser) feed(line string, numKind int) {
+	if line == "" {
+		return
+	}
+
+	// 1. 大题标题：一、A1型题
+	if m := docxSectionRe.FindStringSubmatch(line); m != nil && !docxNumberedRe.MatchString(line) {
+		p.flush()
+		p.setType(m[1])
+		return
+	}
+
+	// 2. 共用题干 / 共用备选答案
+	if m := docxSharedStemRe.FindStringSubmatch(line); m != nil {
+		p.flush()
+		p.sharedStem, p.sharedOptions, p.inShared = m[1], [6]string{}, "stem"
+		p.last = &p.sharedStem
+		return
+	}
+	if m := docxSharedOptRe.FindStringSubmatch(line); m != nil {
+		p.flush()
+		p.sharedStem, p.sharedOptions, p.inShared = "", [6]string{}, "options"
+		p.last = nil
+		if m[1] != "" {
+			p.readOptions(m[1], &p.sharedOptions)
+		}
+		return
+	}
+
+	// 3. 字段标记：分类 / 题型 / 答案 / 解析 ... (一行可以写多个，如 "答案：A  解析：……")
+	if locs := docxFieldRe.FindAllStringSubmatchIndex(line, -1); locs != nil && locs[0][0] == 0 {
+		for i, loc := range locs {
+			end := len(line)
+			if i+1 < len(locs) {
+				end = locs[i+1][0]
+			}
+			var name string
+			if loc[2] >= 0 {
+				name = line[loc[2]:loc[3]]
+			} else {
+				name = line[loc[4]:loc[5]]
+			}
+			p.setField(name, strings.TrimSpace(line[loc[1]:end]))
+		}
+		return
+	}
+
+	// 4. 新题
+	if m := docxNumberedRe.FindStringSubmatch(line); m != nil || numKind == docxNumStem {
+		stem := line
+		if m != nil {
+			stem = m[2]
+		}
+		p.startQuestion(stem)
+		return
+	}
+
+	// 5. 选项行 (字母自动编号的段落，编号不在文字里，按顺序填入下一个空选项)
+	target := p.optionTarget()
+	if loc := docxOptionRe.FindStringIndex(line); loc != nil && loc[0] == 0 {
+		if target != nil {
+			p.readOptions(line, target)
+		}
+		return
+	}
+	if numKind == docxNumOption {
+		if target != nil {
+			p.appendOption(line, target)
+		}
+		return
+	}
+
+	// 6. 续行
+	if p.last != nil {
+		if *p.last == "" {
+			*p.last = line
+		} else {
+			*p.last += "\n" + line
+		}
+	}
+}
+
+func (p *docxParser) setType(t string) {
+	t = strings.ReplaceAll(t, " ", "")
+	switch t {
+	case "单选题":
+		t = "A1型题"
+	case "多选题":
+		t = "X型题"
+	}
+	p.qType = t
+	p.sharedStem, p.sharedOptions, p.inShared = "", [6]string{}, ""
+	p.last = nil
+}
+
+func (p *docxParser) setField(name, value string) {
+	switch name {
+	case "分类":
+		p.flush()
+		p.category = value
+		p.sharedStem, p.sharedOptions, p.inShared = "", [6]string{}, ""
+		return
+	case "题型":
+		p.flush()
+		p.setType(value)
+		return
+	}
+
+	if p.current == nil {
+		return
+	}
+	r := p.current
+	switch name {
+	case "答案", "正确答案":
+		r.Correct = value
+		p.last = &r.Correct
+	case "解析", "答案解析":
+		r.Analysis = value
+		p.last = &r.Analysis
+	case "难度":
+		r.Difficulty = value
+		p.last = nil
+	case "考纲":
+		r.Syllabus = value
+		p.last = nil
+	case "认知层次":
+		r.CognitiveLevel = value
+		p.last = nil
+	case "题目编号", "编号":
+		r.ExternalKey = value
+		p.last = nil
+	}
+}
+
+func (p *docxParser) startQuestion(stem string) {
+	p.flush()
+	p.inShared = ""
+	p.current = &ImportRow{Category: p.category, Type: p.qType, Stem: stem, Columns: len(importHeader)}
+	p.last = &p.current.Stem
+}
+
+// optionTarget 选项行写入的位置：当前题目的选项，或正在读取的 B1 共用备选答案
+func (p *docxParser) optionTarget() *[6]string {
+	switch {
+	case p.inShared == "options" && p.current == nil:
+		return &p.sharedOptions
+	case p.current != nil:
+		return &p.current.Options
+	}
+	return nil
+}
+
+// appendOption 自动编号的选项：填入第一个空位
+func (p *docxParser) appendOption(text string, target *[6]string) {
+	for i := range target {
+		if target[i] == "" {
+			target[i] = text
+			p.last = &target[i]
+			return
+		}
+	}
+}
+
+// readOptions 把一行里的 A. xxx  B. xxx 拆成多个选项
+func (p *docxParser) readOptions(line string, target *[6]string) {
+	locs := docxOptionRe.FindAllStringSubmatchIndex(line, -1)
+	for i, loc := range locs {
+		idx := int(line[loc[2]] - 'A')
+		end := len(line)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		target[idx] = strings.TrimSpace(line[loc[1]:end])
+		p.last = &target[idx]
+	}
+}
+
+// flush 结束当前题目：拼上共用题干 / 共用选项，规整客观题答案后写入结果
+func (p *docxParser) flush() {
+	r := p.current
+	if r == nil {
+		return
+	}
+	p.current = nil
+	p.last = nil
+
+	code := TypeCode(r.Type)
+	if (code == "A3" || code == "A4") && p.sharedStem != "" {
+		// 与模板一致：共用题干 + 换行 + 小题题干 (小题题干只能占一行)
+		r.Stem = p.sharedStem + "\n" + strings.ReplaceAll(r.Stem, "\n", " ")
+	}
+	if code == "B1" && r.Options == [6]string{} {
+		r.Options = p.sharedOptions
+	}
+	if !isSubjectiveType(r.Type) {
+		r.Correct = strings.NewReplacer(" ", "", "、", "", ",", "", "，", "", ".", "", "。", "").Replace(r.Correct)
+	}
+	r.Row = len(p.rows) + 2
+	p.rows = append(p.rows, *r)
+}
@@ -0,0 +1,269 @@
+package question
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/datatypes"
+)
+
+// ImportRow 导入文件中的一行 (与 16 列模板一一对应，另有第 17 列"题目编号"、第 18 列"知识点"可选)
+type ImportRow struct {
+	Row            int // 文件中的行号 (从 1 开始，含表头)，用于报告定位
+	Category       string
+	Type           string
+	Stem           string
+	Options        [6]string // A ~ F
+	Correct        string
+	Analysis       string
+	Difficulty     string
+	DiffValue      string
+	Syllabus       string
+	CognitiveLevel string
+	ExternalKey    string // 题目编号 (可选)：填了就按它匹配已有题目，不填则按题干指纹匹配
+	Knowledge      string // 知识点 (可选)：多个用分号分隔，写完整路径 / 末级名称 / 大纲编号均可
+	Columns        int    // 该行实际的列数 (用于校验"列数不足")
+}
+
+// Importer 导入格式解析器：把上传文件解析成统一的导入行 (与 Excel 模板的列一一对应)
+// 💡 各格式只负责"读"，之后的 校验 -> 组装题目树 -> 比对去重 -> 写库 全部共用同一套流程
+type Importer interface {
+	// Parse 返回导入行，以及用于生成带批注校验报告的工作簿 (Excel 为原文件，其余格式转换成标准模板)
+	Parse(data []byte) ([]ImportRow, *excelize.File, error)
+}
+
+// importers 按文件扩展名注册的解析器
+var importers = map[string]Importer{
+	".xlsx": excelImporter{},
+	".csv":  csvImporter{},
+	".json": jsonImporter{},
+	".docx": docxImporter{},
+}
+
+// importerFor 按文件名选择解析器
+func importerFor(fileName string) (Importer, error) {
+	if imp, ok := importers[strings.ToLower(filepath.Ext(fileName))]; ok {
+		return imp, nil
+	}
+	return nil, fmt.Errorf("不支持的文件格式，仅支持 .xlsx / .csv / .json / .docx")
+}
+
+// excelImporter Excel 模板 (第一个工作表)
+type excelImporter struct{}
+
+func (excelImporter) Parse(data []byte) ([]ImportRow, *excelize.File, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("Excel 解析失败")
+	}
+	rows, err := parseExcelRows(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("读取行失败")
+	}
+	return rows, f, nil
+}
+
+// parseExcelRows 读取 Excel 第一个工作表，转换为导入行 (跳过表头与整行空白的行)
+// 列数不足的行也会保留下来，交给 validateRows 报错，而不是悄悄丢掉
+func parseExcelRows(f *excelize.File) ([]ImportRow, error) {
+	rows, err := f.GetRows(f.GetSheetName(0))
+	if err != nil {
+		return nil, err
+	}
+
+	var result []ImportRow
+	for i, row := range rows {
+		if i == 0 || isBlankRow(row) {
+			continue
+		}
+		result = append(result, rowFromCells(row, i+1))
+	}
+	return result, nil
+}
+
+// rowFromCells 按模板列位置把一行单元格转换为导入行 (Excel / CSV / JSON 共用)
+func rowFromCells(row []string, rowNum int) ImportRow {
+	getCol := func(idx int) string {
+		if idx < len(row) {
+			return strings.TrimSpace(row[idx])
+		}
+		return ""
+	}
+	r := ImportRow{
+		Row:            rowNum,
+		Category:       getCol(1),
+		Type:           getCol(2),
+		Stem:           getCol(3),
+		Correct:        getCol(10),
+		Analysis:       getCol(11),
+		Difficulty:     getCol(12),
+		DiffValue:      getCol(13),
+		Syllabus:       getCol(14),
+		CognitiveLevel: getCol(15),
+		ExternalKey:    getCol(16),
+		Knowledge:      getCol(17),
+		Columns:        len(row),
+	}
+	for k := 0; k < 6; k++ {
+		r.Options[k] = getCol(4 + k)
+	}
+	return r
+}
+
+// rowsToWorkbook 把导入行写成标准模板 (非 Excel 格式用它承载校验批注，行号与报告一致)
+func rowsToWorkbook(rows []ImportRow) *excelize.File {
+	f := excelize.NewFile()
+	sheet := f.GetSheetName(0)
+	_ = f.SetSheetRow(sheet, "A1", &importHeader)
+	for i, r := range rows {
+		cells := []interface{}{i + 1, r.Category, r.Type, r.Stem}
+		for _, o := range r.Options {
+			cells = append(cells, o)
+		}
+		cells = append(cells, r.Correct, r.Analysis, r.Difficulty, r.DiffValue, r.Syllabus, r.CognitiveLevel, r.ExternalKey, r.Knowledge)
+		cell, _ := excelize.CoordinatesToCellName(1, r.Row)
+		_ = f.SetSheetRow(sheet, cell, &cells)
+	}
+	return f
+}
+
+func isBlankRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// isSubjectiveType 主观题 (答案保留原文，不做大小写归一)
+func isSubjectiveType(qType string) bool {
+	return strings.Contains(qType, "问答") || strings.Contains(qType, "论述") || strings.Contains(qType, "案例") || strings.Contains(qType, "名词解释")
+}
+
+// buildQuestionTrees 把导入行组装成题目树 (A3/A4 按共用题干归组，B1 按共用选项归组)
+// 💡 传入前应已通过 validateRows 校验，图片也已由 localizeRowImages 转存
+func buildQuestionTrees(rows []ImportRow, bankName string) []*Question {
+	var roots []*Question
+	var lastFingerprint string = ""
+	var currentParent *Question = nil
+	var lastQType string = ""
+
+	for _, row := range rows {
+		originCategory := row.Category
+		qType := row.Type
+
+		fullStem := row.Stem
+		opts := row.Options
+		analysis := row.Analysis
+
+		diffVal, _ := strconv.ParseFloat(row.DiffValue, 64)
+		if diffVal == 0 {
+			diffVal = 0.5
+		}
+
+		var finalOpts datatypes.JSON = nil
+		optsMap := make(map[string]string)
+		for k, v := range opts {
+			if v != "" {
+				optsMap[optionKeys[k]] = v
+			}
+		}
+		if len(optsMap) > 0 {
+			optsJson, _ := json.Marshal(optsMap)
+			finalOpts = optsJson
+		}
+
+		optsFingerprint := fmt.Sprintf("%s|%s|%s|%s|%s", opts[0], opts[1], opts[2], opts[3], opts[4])
+		topCategory := strings.TrimSpace(strings.Split(originCategory, ">")[0])
+
+		finalCorrect := ""
+		if isSubjectiveType(qType) {
+			finalCorrect = row.Correct
+		} else {
+			finalCorrect = strings.TrimSpace(strings.ToUpper(row.Correct))
+		}
+
+		newLeaf := func(stem string, options datatypes.JSON) Question {
+			return Question{
+				Type: qType, Stem: cleanStem(stem), Options: options, Correct: finalCorrect,
+				Analysis: analysis, Category: topCategory, CategoryPath: originCategory, Source: bankName,
+				Difficulty: row.Difficulty, DiffValue: diffVal, Syllabus: row.Syllabus, CognitiveLevel: row.CognitiveLevel,
+				ExternalKey: row.ExternalKey, ImportRow: row.Row, Knowledge: row.Knowledge,
+			}
+		}
+
+		if strings.Contains(qType, "A3") || strings.Contains(qType, "A4") {
+			parts := strings.Split(fullStem, "\n")
+			currentMainStem := ""
+			currentSubStem := ""
+
+			if len(parts) > 1 {
+				currentMainStem = strings.Join(parts[:len(parts)-1], "\n")
+				currentSubStem = parts[len(parts)-1]
+			} else if strings.Contains(fullStem, "【共用主干】") || strings.Contains(fullStem, "【共用题干】") {
+				currentMainStem = fullStem
+				currentSubStem = fullStem
+			} else {
+				currentMainStem = fullStem
+				currentSubStem = ""
+			}
+
+			currentFingerprint := cleanStemForFingerprint(currentMainStem)
+			isSameGroup := false
+			if currentParent != nil && (strings.Contains(lastQType, "A3") || strings.Contains(lastQType, "A4")) {
+				if currentFingerprint != "" && lastFingerprint != "" {
+					if strings.Contains(currentFingerprint, lastFingerprint) || strings.Contains(lastFingerprint, currentFingerprint) {
+						isSameGroup = true
+					}
+				}
+			}
+
+			if !isSameGroup {
+				newParent := &Question{
+					Type: qType, Stem: cleanStem(currentMainStem), Category: topCategory, CategoryPath: originCategory,
+					Source: bankName, ParentID: nil, Children: []Question{}, ImportRow: row.Row,
+				}
+				roots = append(roots, newParent)
+				currentParent = newParent
+				lastFingerprint = currentFingerprint
+				lastQType = qType
+			}
+			currentParent.Children = append(currentParent.Children, newLeaf(currentSubStem, finalOpts))
+
+		} else if strings.Contains(qType, "B1") {
+			isSameGroup := false
+			if currentParent != nil && strings.Contains(lastQType, "B1") {
+				if optsFingerprint == lastFingerprint {
+					isSameGroup = true
+				}
+			}
+			if !isSameGroup {
+				newParent := &Question{
+					Type: qType, Stem: cleanStem(fullStem), Options: finalOpts, Category: topCategory, CategoryPath: originCategory,
+					Source: bankName, ParentID: nil, Children: []Question{}, ImportRow: row.Row,
+				}
+				roots = append(roots, newParent)
+				currentParent = newParent
+				lastFingerprint = optsFingerprint
+				lastQType = qType
+			}
+			currentParent.Children = append(currentParent.Children, newLeaf(fullStem, nil))
+
+		} else {
+			lastFingerprint = ""
+			currentParent = nil
+			lastQType = qType
+			q := newLeaf(fullStem, finalOpts)
+			roots = append(roots, &q)
+		}
+	}
+
+	return roots
+}
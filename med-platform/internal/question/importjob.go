@@ -0,0 +1,231 @@
+package question
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"med-platform/internal/common/db"
+	"med-platform/internal/common/logger"
+	"med-platform/internal/common/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm/clause"
+)
+
+// progressInterval 进度落库 / 推送的最小间隔 (几千行的导入不至于每行都写一次库)
+const progressInterval = time.Second
+
+// =========================================================
+// 🗄️ 任务记录
+// =========================================================
+
+// CreateImportJob 建立导入任务；同一题库同时只允许一个未结束的任务，避免两次导入互相覆盖
+// 🔥 由部分唯一索引 idx_import_job_active 保证，并发上传时只有一个能插入成功，其余返回 false
+func (r *Repository) CreateImportJob(job *ImportJob) (bool, error) {
+	res := db.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(job)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func (r *Repository) GetImportJob(id uint) (*ImportJob, error) {
+	var job ImportJob
+	err := db.DB.First(&job, id).Error
+	return &job, err
+}
+
+// ListImportJobs 最近的导入任务 (列表不带完整校验报告)
+func (r *Repository) ListImportJobs(source string, page, pageSize int) ([]ImportJob, int64, error) {
+	var list []ImportJob
+	var total int64
+	query := db.DB.Model(&ImportJob{})
+	if source != "" {
+		query = query.Where("source = ?", source)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Omit("report").Order("id desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&list).Error
+	return list, total, err
+}
+
+// FailInterruptedImportJobs 服务重启时，把上次没跑完的任务标记为失败
+// 💡 导入在单个事务里写库，中断时数据库里不会留下半批题目，重新上传即可
+func (r *Repository) FailInterruptedImportJobs() {
+	now := time.Now()
+	res := db.DB.Model(&ImportJob{}).
+		Where("status IN ?", []string{ImportJobPending, ImportJobRunning}).
+		Updates(map[string]interface{}{"status": ImportJobFailed, "message": "服务重启，任务已中断，请重新导入", "finished_at": now})
+	if res.RowsAffected > 0 {
+		logger.Log.Warn("已中断的导入任务标记为失败", zap.Int64("count", res.RowsAffected))
+	}
+}
+
+// =========================================================
+// ⚙️ 后台执行
+// =========================================================
+
+// importTask 一个正在执行的导入任务 (进度回调来自多个下载协程，需加锁)
+type importTask struct {
+	repo   *Repository
+	job    *ImportJob
+	file   *excelize.File
+	rows   []ImportRow
+	issues []ImportIssue
+
+	mu       sync.Mutex
+	lastSync time.Time
+}
+
+// run 转存图片 -> 比对 -> 单事务写库 -> 同步目录树，每一步都汇报进度
+func (t *importTask) run() {
+	defer t.file.Close()
+	defer func() {
+		if rec := recover(); rec != nil {
+			logger.Log.Error("导入任务异常退出", zap.Uint("job_id", t.job.ID), zap.Any("panic", rec))
+			t.finish(ImportJobFailed, fmt.Sprintf("导入任务异常退出: %v", rec))
+		}
+	}()
+
+	started := time.Now()
+	t.job.Status = ImportJobRunning
+	t.job.StartedAt = &started
+	t.update("images", 0, true)
+
+	// 1. 转存图片 (最耗时，按行汇报进度)
+	processed := 0
+	imageIssues := localizeRowImages(t.rows, func() {
+		t.mu.Lock()
+		processed++
+		n := processed
+		t.mu.Unlock()
+		t.update("images", n, false)
+	})
+	report := newImportReport(append(t.issues, imageIssues...))
+	if len(report.Issues) > 0 {
+		report.AnnotatedURL = annotateImportFile(t.file, report.Issues)
+	}
+	t.job.Report = report
+	t.job.Errors = report.Errors
+	t.job.Warnings = report.Warnings
+	t.update("saving", len(t.rows), true)
+
+	// 2. 比对并写库 (单事务，失败整体回滚)
+	plan, err := t.repo.PlanImport(t.job.Source, buildQuestionTrees(t.rows, t.job.Source))
+	if err != nil {
+		t.finish(ImportJobFailed, "比对现有题目失败: "+err.Error())
+		return
+	}
+	plan.EditorID = t.job.UserID
+	added, updated, err := t.repo.ApplyImport(plan)
+	if err != nil {
+		t.finish(ImportJobFailed, "导入失败，已全部回滚: "+err.Error())
+		return
+	}
+	t.repo.SyncCategories()
+	t.repo.RefreshCategoryStats(t.job.Source)
+	wakeRegrade()
+
+	msg := fmt.Sprintf("成功导入：新增 %d 道，更新 %d 道小题 (图片已转存)", added, updated)
+	if report.Errors > 0 {
+		msg += fmt.Sprintf("；跳过 %d 处错误所在的行", report.Errors)
+	}
+	if plan.Summary.Removed > 0 {
+		msg += fmt.Sprintf("；另有 %d 道旧题不在本次文件中，已保留未删除", plan.Summary.Removed)
+	}
+	t.job.Added = added
+	t.job.Updated = updated
+	t.job.Summary = &plan.Summary
+	t.finish(ImportJobSuccess, msg)
+}
+
+// update 更新进度；force=false 时按 progressInterval 节流 (最后一行总会写入)
+func (t *importTask) update(stage string, processed int, force bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !force && processed < t.job.Total && time.Since(t.lastSync) < progressInterval {
+		return
+	}
+	t.lastSync = time.Now()
+	t.job.Stage = stage
+	t.job.Processed = processed
+
+	db.DB.Model(&ImportJob{}).Where("id = ?", t.job.ID).Updates(map[string]interface{}{
+		"status": t.job.Status, "stage": stage, "processed": processed, "started_at": t.job.StartedAt,
+	})
+	t.push()
+}
+
+// finish 写入最终结果并推送
+func (t *importTask) finish(status, message string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	t.job.Status = status
+	t.job.Message = message
+	t.job.FinishedAt = &now
+	if err := db.DB.Save(t.job).Error; err != nil {
+		logger.Log.Error("保存导入任务结果失败", zap.Uint("job_id", t.job.ID), zap.Error(err))
+	}
+	t.push()
+}
+
+// push 通过 WebSocket 把进度推给发起人 (不带完整报告，需要时前端再拉详情)
+func (t *importTask) push() {
+	if service.Hub == nil {
+		return
+	}
+	service.Hub.SendToUser(t.job.UserID, gin.H{
+		"type": "import_progress",
+		"data": gin.H{
+			"id":        t.job.ID,
+			"source":    t.job.Source,
+			"status":    t.job.Status,
+			"stage":     t.job.Stage,
+			"total":     t.job.Total,
+			"processed": t.job.Processed,
+			"added":     t.job.Added,
+			"updated":   t.job.Updated,
+			"errors":    t.job.Errors,
+			"warnings":  t.job.Warnings,
+			"message":   t.job.Message,
+		},
+	})
+}
+
+// =========================================================
+// 🌐 接口
+// =========================================================
+
+// GetImportJob 查询导入任务进度与结果 (含校验报告)
+// GET /admin/import-jobs/:id
+func (h *Handler) GetImportJob(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	job, err := h.repo.GetImportJob(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "导入任务不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": job})
+}
+
+// ListImportJobs 导入任务列表
+// GET /admin/import-jobs?source=xxx&page=1
+func (h *Handler) ListImportJobs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	list, total, err := h.repo.ListImportJobs(c.Query("source"), page, 20)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询导入任务失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list, "total": total, "page": page})
+}
@@ -0,0 +1,181 @@
+package question
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// jsonImporter JSON 文件：题目数组，或 {"questions": [...]} / {"data": [...]}
+// 组合题用 children 表达 (A3/A4 父题为共用题干，B1 父题带共用选项)，字段名兼容本站接口返回的格式：
+//
+//	{"category": "生理学 > 血液", "type": "A1型题", "stem": "...",
+//	 "options": {"A": "...", "B": "..."} 或 ["...", "..."],
+//	 "answer": "AB" 或 ["A", "B"], "analysis": "...", "difficulty": "中", "diff_value": 0.6,
+//	 "syllabus": "...", "cognitive_level": "...", "key": "题目编号",
+//	 "knowledge": "内科学 > 心力衰竭" 或 ["...", "..."], "children": [...]}
+type jsonImporter struct{}
+
+type jsonQuestion struct {
+	Category       string          `json:"category"`
+	CategoryPath   string          `json:"category_path"`
+	Type           string          `json:"type"`
+	Stem           string          `json:"stem"`
+	Options        json.RawMessage `json:"options"`
+	Answer         json.RawMessage `json:"answer"`
+	Correct        json.RawMessage `json:"correct"`
+	Analysis       string          `json:"analysis"`
+	Difficulty     string          `json:"difficulty"`
+	DiffValue      json.RawMessage `json:"diff_value"`
+	Syllabus       string          `json:"syllabus"`
+	CognitiveLevel string          `json:"cognitive_level"`
+	Key            string          `json:"key"`
+	ExternalKey    string          `json:"external_key"`
+	Knowledge      json.RawMessage `json:"knowledge"`
+	Children       []jsonQuestion  `json:"children"`
+}
+
+func (jsonImporter) Parse(data []byte) ([]ImportRow, *excelize.File, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	var items []jsonQuestion
+	if err := json.Unmarshal(data, &items); err != nil {
+		var wrapper struct {
+			Questions []jsonQuestion `json:"questions"`
+			Data      []jsonQuestion `json:"data"`
+		}
+		if err2 := json.Unmarshal(data, &wrapper); err2 != nil {
+			return nil, nil, fmt.Errorf("JSON 解析失败: %v", err)
+		}
+		items = append(wrapper.Questions, wrapper.Data...)
+	}
+
+	// 先还原成题目，再按导出规则展开成模板行：组合题的展开方式与 Excel 导出严格一致
+	var rows []ImportRow
+	for _, item := range items {
+		for _, cells := range buildExportRows(item.toQuestion(nil)) {
+			rows = append(rows, rowFromCells(cells, len(rows)+2))
+		}
+	}
+	return rows, rowsToWorkbook(rows), nil
+}
+
+// toQuestion 转为题目 (子题未填的分类 / 题型沿用父题)
+func (j jsonQuestion) toQuestion(parent *Question) Question {
+	q := Question{
+		CategoryPath:   firstNonEmpty(j.CategoryPath, j.Category),
+		Type:           j.Type,
+		Stem:           j.Stem,
+		Correct:        jsonAnswer(firstNonEmptyRaw(j.Answer, j.Correct)),
+		Analysis:       j.Analysis,
+		Difficulty:     j.Difficulty,
+		DiffValue:      jsonNumber(j.DiffValue),
+		Syllabus:       j.Syllabus,
+		CognitiveLevel: j.CognitiveLevel,
+		ExternalKey:    firstNonEmpty(j.Key, j.ExternalKey),
+		Knowledge:      jsonTagList(j.Knowledge),
+	}
+	if opts := jsonOptions(j.Options); len(opts) > 0 {
+		q.Options, _ = json.Marshal(opts)
+	}
+	if parent != nil {
+		q.CategoryPath = firstNonEmpty(q.CategoryPath, parent.CategoryPath)
+		q.Type = firstNonEmpty(q.Type, parent.Type)
+	}
+	for _, child := range j.Children {
+		q.Children = append(q.Children, child.toQuestion(&q))
+	}
+	return q
+}
+
+// jsonOptions 选项支持对象 {"A": "..."} 与数组 ["...", "..."] 两种写法
+func jsonOptions(raw json.RawMessage) map[string]string {
+	if len(raw) == 0 {
+		return nil
+	}
+	opts := make(map[string]string)
+	var m map[string]string
+	if err := json.Unmarshal(raw, &m); err == nil {
+		for k, v := range m {
+			opts[strings.ToUpper(strings.TrimSpace(k))] = v
+		}
+		return opts
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		for i, v := range list {
+			if i < len(optionKeys) {
+				opts[optionKeys[i]] = v
+			}
+		}
+	}
+	return opts
+}
+
+// jsonAnswer 答案支持字符串与字母数组
+func jsonAnswer(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return strings.Join(list, "")
+	}
+	return strings.Trim(string(raw), `"`)
+}
+
+// jsonTagList 知识点支持字符串 (分号分隔) 与字符串数组
+func jsonTagList(raw json.RawMessage) string {
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return strings.Join(list, "；")
+	}
+	return jsonAnswer(raw)
+}
+
+// jsonNumber 难度系数支持数字与字符串，无法识别的按未填写处理 (导入时默认 0.5)
+func jsonNumber(raw json.RawMessage) float64 {
+	if len(raw) == 0 || string(raw) == "null" {
+		return 0
+	}
+	var f float64
+	if err := json.Unmarshal(raw, &f); err == nil {
+		return f
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		if s == "" {
+			return 0
+		}
+		if v, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+			return v
+		}
+	}
+	return 0
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func firstNonEmptyRaw(values ...json.RawMessage) json.RawMessage {
+	for _, v := range values {
+		if len(v) > 0 && string(v) != "null" {
+			return v
+		}
+	}
+	return nil
+}
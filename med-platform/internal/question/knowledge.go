@@ -0,0 +1,614 @@
+package question
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"med-platform/internal/common/db"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// =========================================================
+// 🏷️ 知识点：路径与解析
+// =========================================================
+
+// tagListSplitRe 一个单元格里的多个知识点 (分号 / 竖线 / 逗号 / 换行分隔)
+var tagListSplitRe = regexp.MustCompile(`[；;|｜,，\n]+`)
+
+// normalizeTagPath 统一路径写法：">" 两侧空白归一为 " > "
+func normalizeTagPath(path string) string {
+	var parts []string
+	for _, p := range strings.Split(strings.ReplaceAll(path, "＞", ">"), ">") {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, KnowledgeTagSep)
+}
+
+// splitTagList 拆分"知识点"列 (去空、去重，保持原顺序)
+func splitTagList(text string) []string {
+	var items []string
+	seen := make(map[string]bool)
+	for _, item := range tagListSplitRe.Split(text, -1) {
+		if item = normalizeTagPath(item); item != "" && !seen[item] {
+			seen[item] = true
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// tagResolver 把导入文件里写的知识点 (完整路径 / 末级名称 / 大纲编号) 解析成知识点 ID
+type tagResolver struct {
+	byPath map[string]uint
+	byName map[string][]uint
+	byCode map[string]uint
+}
+
+func loadTagResolver(tx *gorm.DB) (*tagResolver, error) {
+	var tags []KnowledgeTag
+	if err := tx.Select("id, name, code, full_path").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	r := &tagResolver{byPath: make(map[string]uint), byName: make(map[string][]uint), byCode: make(map[string]uint)}
+	for _, t := range tags {
+		r.byPath[t.FullPath] = t.ID
+		r.byName[t.Name] = append(r.byName[t.Name], t.ID)
+		if t.Code != "" {
+			r.byCode[t.Code] = t.ID
+		}
+	}
+	return r, nil
+}
+
+// resolve 依次按 完整路径 -> 大纲编号 -> 末级名称 匹配；名称重名时要求写完整路径
+func (r *tagResolver) resolve(item string) (uint, error) {
+	if id, ok := r.byPath[item]; ok {
+		return id, nil
+	}
+	if id, ok := r.byCode[item]; ok {
+		return id, nil
+	}
+	switch ids := r.byName[item]; len(ids) {
+	case 0:
+		return 0, fmt.Errorf("知识点「%s」不存在，将忽略 (请先在知识点体系中添加)", item)
+	case 1:
+		return ids[0], nil
+	default:
+		return 0, fmt.Errorf("知识点「%s」有 %d 个同名节点，请填写完整路径", item, len(ids))
+	}
+}
+
+// knowledgeIssues 导入校验：无法识别的知识点给出警告 (不阻止导入，该知识点被忽略)
+func knowledgeIssues(rows []ImportRow) []ImportIssue {
+	var resolver *tagResolver
+	var issues []ImportIssue
+	for _, row := range rows {
+		if row.Knowledge == "" {
+			continue
+		}
+		if resolver == nil {
+			var err error
+			if resolver, err = loadTagResolver(db.DB); err != nil {
+				return nil
+			}
+		}
+		for _, item := range splitTagList(row.Knowledge) {
+			if _, err := resolver.resolve(item); err != nil {
+				issues = append(issues, ImportIssue{Row: row.Row, Level: IssueWarning, Field: "知识点", Message: err.Error()})
+			}
+		}
+	}
+	return issues
+}
+
+// applyImportTags 导入写库后同步知识点：填了"知识点"列的小题以文件为准整体替换，没填的保留原有关联
+func applyImportTags(tx *gorm.DB, roots []*Question) error {
+	var leaves []*Question
+	for _, root := range roots {
+		if len(root.Children) == 0 {
+			leaves = append(leaves, root)
+			continue
+		}
+		for i := range root.Children {
+			leaves = append(leaves, &root.Children[i])
+		}
+	}
+
+	var resolver *tagResolver
+	for _, q := range leaves {
+		if q.Knowledge == "" || q.ID == 0 {
+			continue
+		}
+		if resolver == nil {
+			var err error
+			if resolver, err = loadTagResolver(tx); err != nil {
+				return err
+			}
+		}
+		var tagIDs []uint
+		for _, item := range splitTagList(q.Knowledge) {
+			if id, err := resolver.resolve(item); err == nil {
+				tagIDs = append(tagIDs, id)
+			}
+		}
+		if err := setQuestionTags(tx, []uint{q.ID}, tagIDs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setQuestionTags 把题目的知识点整体替换为 tagIDs
+func setQuestionTags(tx *gorm.DB, questionIDs, tagIDs []uint) error {
+	if err := tx.Where("question_id IN ?", questionIDs).Delete(&QuestionTag{}).Error; err != nil {
+		return err
+	}
+	return addQuestionTags(tx, questionIDs, tagIDs)
+}
+
+// addQuestionTags 追加知识点 (已有的关联忽略)
+func addQuestionTags(tx *gorm.DB, questionIDs, tagIDs []uint) error {
+	links := make([]QuestionTag, 0, len(questionIDs)*len(tagIDs))
+	for _, qid := range questionIDs {
+		for _, tid := range tagIDs {
+			links = append(links, QuestionTag{QuestionID: qid, TagID: tid})
+		}
+	}
+	if len(links) == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&links, 500).Error
+}
+
+// TagBrief 题目上展示的知识点
+type TagBrief struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	FullPath string `json:"full_path"`
+}
+
+// tagsOfQuestions 批量查询题目挂的知识点
+func tagsOfQuestions(questionIDs []uint) map[uint][]TagBrief {
+	result := make(map[uint][]TagBrief)
+	if len(questionIDs) == 0 {
+		return result
+	}
+	type row struct {
+		QuestionID uint
+		TagBrief
+	}
+	var rows []row
+	db.DB.Table("question_tags qt").
+		Select("qt.question_id, t.id, t.name, t.full_path").
+		Joins("JOIN knowledge_tags t ON t.id = qt.tag_id").
+		Where("qt.question_id IN ?", questionIDs).
+		Order("t.full_path").Scan(&rows)
+	for _, r := range rows {
+		result[r.QuestionID] = append(result[r.QuestionID], r.TagBrief)
+	}
+	return result
+}
+
+// knowledgeTextOf 导出用："知识点"列写完整路径，再导入时原样还原
+func knowledgeTextOf(questionIDs []uint) map[uint]string {
+	result := make(map[uint]string)
+	for qid, tags := range tagsOfQuestions(questionIDs) {
+		paths := make([]string, len(tags))
+		for i, t := range tags {
+			paths[i] = t.FullPath
+		}
+		result[qid] = strings.Join(paths, "；")
+	}
+	return result
+}
+
+// fillKnowledge 给导出的题目树填上"知识点"列
+func fillKnowledge(roots []Question) {
+	var ids []uint
+	for _, root := range roots {
+		ids = append(ids, root.ID)
+		for _, child := range root.Children {
+			ids = append(ids, child.ID)
+		}
+	}
+	texts := knowledgeTextOf(ids)
+	for i := range roots {
+		roots[i].Knowledge = texts[roots[i].ID]
+		for j := range roots[i].Children {
+			roots[i].Children[j].Knowledge = texts[roots[i].Children[j].ID]
+		}
+	}
+}
+
+// =========================================================
+// 🗄️ 知识点体系维护
+// =========================================================
+
+// EnsureTagPath 按路径逐级创建知识点 (已存在的节点复用)，返回末级节点
+func (r *Repository) EnsureTagPath(tx *gorm.DB, path, code string) (*KnowledgeTag, error) {
+	parts := strings.Split(normalizeTagPath(path), KnowledgeTagSep)
+	if len(parts) == 0 || parts[0] == "" {
+		return nil, fmt.Errorf("知识点路径不能为空")
+	}
+	var parent *KnowledgeTag
+	for i, name := range parts {
+		full := strings.Join(parts[:i+1], KnowledgeTagSep)
+		var tag KnowledgeTag
+		err := tx.Where("full_path = ?", full).First(&tag).Error
+		if err == gorm.ErrRecordNotFound {
+			tag = KnowledgeTag{Name: name, FullPath: full, Level: i + 1}
+			if parent != nil {
+				tag.ParentID = &parent.ID
+			}
+			err = tx.Create(&tag).Error
+		}
+		if err != nil {
+			return nil, err
+		}
+		parent = &tag
+	}
+	if code != "" && parent.Code != code {
+		parent.Code = code
+		if err := tx.Model(parent).Update("code", code).Error; err != nil {
+			return nil, err
+		}
+	}
+	return parent, nil
+}
+
+// GetKnowledgeTree 完整的知识点树 (按 sort_order、名称排序)
+func (r *Repository) GetKnowledgeTree() ([]*KnowledgeTag, error) {
+	var tags []*KnowledgeTag
+	if err := db.DB.Order("level asc, sort_order asc, full_path asc").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*KnowledgeTag, len(tags))
+	for _, t := range tags {
+		byID[t.ID] = t
+	}
+	roots := []*KnowledgeTag{}
+	for _, t := range tags {
+		if t.ParentID != nil {
+			if p, ok := byID[*t.ParentID]; ok {
+				p.Children = append(p.Children, t)
+				continue
+			}
+		}
+		roots = append(roots, t)
+	}
+	return roots, nil
+}
+
+// RenameKnowledgeTag 修改名称 / 编号 / 排序；改名时连带更新整棵子树的路径
+func (r *Repository) RenameKnowledgeTag(id uint, name, code string, sortOrder *int) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		var tag KnowledgeTag
+		if err := tx.First(&tag, id).Error; err != nil {
+			return fmt.Errorf("知识点不存在")
+		}
+		updates := map[string]interface{}{"code": code}
+		if sortOrder != nil {
+			updates["sort_order"] = *sortOrder
+		}
+		if name != "" && name != tag.Name {
+			oldPath := tag.FullPath
+			newPath := name
+			if i := strings.LastIndex(oldPath, KnowledgeTagSep); i >= 0 {
+				newPath = oldPath[:i+len(KnowledgeTagSep)] + name
+			}
+			var taken int64
+			tx.Model(&KnowledgeTag{}).Where("full_path = ?", newPath).Count(&taken)
+			if taken > 0 {
+				return fmt.Errorf("同级已有名为「%s」的知识点", name)
+			}
+			updates["name"] = name
+			updates["full_path"] = newPath
+			if err := tx.Exec(`UPDATE knowledge_tags SET full_path = CAST(? AS text) || SUBSTRING(full_path FROM ?) WHERE full_path LIKE ?`,
+				newPath, len([]rune(oldPath))+1, oldPath+KnowledgeTagSep+"%").Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&tag).Updates(updates).Error
+	})
+}
+
+// DeleteKnowledgeTag 删除知识点及其子树 (题目本身不受影响，只解除关联)
+func (r *Repository) DeleteKnowledgeTag(id uint) (int, error) {
+	var tag KnowledgeTag
+	if err := db.DB.First(&tag, id).Error; err != nil {
+		return 0, fmt.Errorf("知识点不存在")
+	}
+	var ids []uint
+	db.DB.Model(&KnowledgeTag{}).Where("id = ? OR full_path LIKE ?", id, tag.FullPath+KnowledgeTagSep+"%").Pluck("id", &ids)
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tag_id IN ?", ids).Delete(&QuestionTag{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", ids).Delete(&KnowledgeTag{}).Error
+	})
+	return len(ids), err
+}
+
+// leafQuestionIDs 把组合题父题展开成子题 (知识点只挂在可作答的小题上)
+func leafQuestionIDs(tx *gorm.DB, ids []uint) []uint {
+	var childIDs, parentIDs []uint
+	tx.Model(&Question{}).Where("parent_id IN ?", ids).Pluck("id", &childIDs)
+	tx.Model(&Question{}).Where("parent_id IN ?", ids).Distinct().Pluck("parent_id", &parentIDs)
+	isParent := make(map[uint]bool, len(parentIDs))
+	for _, id := range parentIDs {
+		isParent[id] = true
+	}
+	var leaves []uint
+	for _, id := range ids {
+		if !isParent[id] {
+			leaves = append(leaves, id)
+		}
+	}
+	return append(leaves, childIDs...)
+}
+
+// =========================================================
+// 📊 按知识点统计掌握度
+// =========================================================
+
+// TagMastery 某个知识点 (含下级) 的个人掌握情况
+type TagMastery struct {
+	TagID    uint    `json:"tag_id"`
+	Name     string  `json:"name"`
+	FullPath string  `json:"full_path"`
+	Level    int     `json:"level"`
+	Total    int64   `json:"total"`    // 挂在该知识点及下级上的小题数
+	Answered int64   `json:"answered"` // 做过的
+	Correct  int64   `json:"correct"`  // 当前答对的
+	Accuracy float64 `json:"accuracy"` // 正确率 (做过的题里)
+	Mastery  float64 `json:"mastery"`  // 掌握度：做过的题平均得分 (含 X 型题部分得分)
+}
+
+// KnowledgeMastery 用户在每个知识点上的掌握度 (上级知识点汇总下级，同一道题只计一次)
+func (r *Repository) KnowledgeMastery(userID uint, source string) ([]TagMastery, error) {
+	var list []TagMastery
+	err := db.DB.Raw(`
+		SELECT t.id AS tag_id, t.name, t.full_path, t.level,
+			COUNT(*) AS total,
+			COUNT(ar.id) AS answered,
+			COUNT(ar.id) FILTER (WHERE ar.is_correct) AS correct,
+			COALESCE(AVG(ar.score), 0) AS mastery
+		FROM (
+			SELECT DISTINCT t.id AS tag_id, qt.question_id
+			FROM knowledge_tags t
+			JOIN knowledge_tags d ON d.id = t.id OR d.full_path LIKE t.full_path || ' > %'
+			JOIN question_tags qt ON qt.tag_id = d.id
+			JOIN questions q ON q.id = qt.question_id AND q.deleted_at IS NULL
+			WHERE @source = '' OR q.source = @source
+		) x
+		JOIN knowledge_tags t ON t.id = x.tag_id
+		LEFT JOIN answer_records ar ON ar.question_id = x.question_id AND ar.user_id = @uid AND ar.deleted_at IS NULL
+		GROUP BY t.id, t.name, t.full_path, t.level
+		ORDER BY t.full_path
+	`, map[string]interface{}{"uid": userID, "source": source}).Scan(&list).Error
+	for i := range list {
+		if list[i].Answered > 0 {
+			list[i].Accuracy = float64(list[i].Correct) / float64(list[i].Answered)
+		}
+	}
+	if list == nil {
+		list = []TagMastery{}
+	}
+	return list, err
+}
+
+// =========================================================
+// 🌐 接口
+// =========================================================
+
+// GetKnowledgeTree 知识点树
+// GET /knowledge-tags
+func (h *Handler) GetKnowledgeTree(c *gin.Context) {
+	tree, err := h.repo.GetKnowledgeTree()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取知识点失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": tree})
+}
+
+// CreateKnowledgeTags 新增知识点 (可一次提交整份大纲，逐级自动建节点)
+// POST /admin/knowledge-tags  {"items": [{"path": "内科学 > 循环系统 > 心力衰竭", "code": "2.3.1"}]}
+func (h *Handler) CreateKnowledgeTags(c *gin.Context) {
+	var req struct {
+		Items []struct {
+			Path string `json:"path"`
+			Code string `json:"code"`
+		} `json:"items" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.Items) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数格式错误"})
+		return
+	}
+
+	var created []*KnowledgeTag
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		for _, item := range req.Items {
+			tag, err := h.repo.EnsureTagPath(tx, item.Path, strings.TrimSpace(item.Code))
+			if err != nil {
+				return fmt.Errorf("「%s」: %v", item.Path, err)
+			}
+			created = append(created, tag)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "保存知识点失败 " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("已保存 %d 个知识点", len(created)), "data": created})
+}
+
+// UpdateKnowledgeTag 修改知识点名称 / 大纲编号 / 排序
+// PUT /admin/knowledge-tags/:id
+func (h *Handler) UpdateKnowledgeTag(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var req struct {
+		Name      string `json:"name"`
+		Code      string `json:"code"`
+		SortOrder *int   `json:"sort_order"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数格式错误"})
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if strings.Contains(name, ">") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "名称不能包含「>」"})
+		return
+	}
+	if err := h.repo.RenameKnowledgeTag(uint(id), name, strings.TrimSpace(req.Code), req.SortOrder); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "修改成功"})
+}
+
+// DeleteKnowledgeTag 删除知识点 (连同下级)
+// DELETE /admin/knowledge-tags/:id
+func (h *Handler) DeleteKnowledgeTag(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	n, err := h.repo.DeleteKnowledgeTag(uint(id))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("已删除 %d 个知识点", n)})
+}
+
+// BatchTagQuestions 批量给题目打 / 去知识点 (组合题父题自动展开到各小题)
+// POST /admin/questions/tags  {"question_ids": [1,2], "tag_ids": [5], "mode": "add|remove|replace"}
+func (h *Handler) BatchTagQuestions(c *gin.Context) {
+	var req struct {
+		QuestionIDs []uint `json:"question_ids" binding:"required"`
+		TagIDs      []uint `json:"tag_ids"`
+		Mode        string `json:"mode"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.QuestionIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数格式错误"})
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = "add"
+	}
+	var found int64
+	db.DB.Model(&KnowledgeTag{}).Where("id IN ?", req.TagIDs).Count(&found)
+	if int(found) != len(req.TagIDs) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "部分知识点不存在"})
+		return
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		ids := leafQuestionIDs(tx, req.QuestionIDs)
+		if len(ids) == 0 {
+			return fmt.Errorf("题目不存在")
+		}
+		switch req.Mode {
+		case "add":
+			return addQuestionTags(tx, ids, req.TagIDs)
+		case "remove":
+			return tx.Where("question_id IN ? AND tag_id IN ?", ids, req.TagIDs).Delete(&QuestionTag{}).Error
+		case "replace":
+			return setQuestionTags(tx, ids, req.TagIDs)
+		}
+		return fmt.Errorf("不支持的操作「%s」", req.Mode)
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "知识点已更新"})
+}
+
+// GetKnowledgeMastery 我在各知识点上的掌握度
+// GET /knowledge-tags/mastery?source=xxx (不传 source 则跨所有题库)
+func (h *Handler) GetKnowledgeMastery(c *gin.Context) {
+	list, err := h.repo.KnowledgeMastery(c.MustGet("userID").(uint), c.Query("source"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "统计掌握度失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// PracticeByTag 按知识点练习：跨所有已授权题库取出挂在该知识点 (含下级) 上的题目
+// GET /knowledge-tags/:id/questions?mode=all|undone|wrong&page=1&page_size=20
+func (h *Handler) PracticeByTag(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	userID := c.MustGet("userID").(uint)
+
+	var tag KnowledgeTag
+	if err := db.DB.First(&tag, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "知识点不存在"})
+		return
+	}
+
+	base := db.DB.Model(&Question{}).
+		Where("id IN (?)", db.DB.Table("question_tags qt").Select("qt.question_id").
+			Joins("JOIN knowledge_tags d ON d.id = qt.tag_id").
+			Where("d.id = ? OR d.full_path LIKE ?", tag.ID, tag.FullPath+KnowledgeTagSep+"%"))
+
+	// 只保留已授权的 (题库, 一级科目)
+	type scope struct{ Source, Category string }
+	var scopes []scope
+	base.Session(&gorm.Session{}).Distinct("source", "category").Scan(&scopes)
+	var allowed [][]interface{}
+	for _, s := range scopes {
+		if checkAccess(c, s.Source, s.Category) {
+			allowed = append(allowed, []interface{}{s.Source, s.Category})
+		}
+	}
+	if len(allowed) == 0 {
+		c.JSON(http.StatusOK, gin.H{"data": []interface{}{}, "total": 0, "page": page, "page_size": pageSize, "locked": len(scopes)})
+		return
+	}
+	query := base.Where("(source, category) IN ?", allowed)
+
+	switch c.Query("mode") {
+	case "undone":
+		query = query.Where("NOT EXISTS (SELECT 1 FROM answer_records ar WHERE ar.question_id = questions.id AND ar.user_id = ? AND ar.deleted_at IS NULL)", userID)
+	case "wrong":
+		query = query.Where("EXISTS (SELECT 1 FROM answer_records ar WHERE ar.question_id = questions.id AND ar.user_id = ? AND ar.deleted_at IS NULL AND ar.is_correct = false)", userID)
+	}
+
+	var total int64
+	query.Count(&total)
+	var questions []Question
+	if err := query.Order("source asc, category_path asc, id asc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&questions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取题目失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": renderQuestionList(c, questions), "total": total, "page": page, "page_size": pageSize, "locked": len(scopes) - len(allowed)})
+}
+
+// GetQuestionTags 题目 (含子题) 挂的知识点
+// GET /questions/:id/tags
+func (h *Handler) GetQuestionTags(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var ids []uint
+	db.DB.Model(&Question{}).Where("parent_id = ?", id).Pluck("id", &ids)
+	ids = append(ids, uint(id))
+	c.JSON(http.StatusOK, gin.H{"data": tagsOfQuestions(ids)})
+}
@@ -18,6 +18,8 @@ type Category struct {
 	FullPath  string         `gorm:"type:text;index" json:"full_path"`
 	IsDirty   bool           `gorm:"default:false" json:"is_dirty"`
 	Source    string         `gorm:"index;size:100;not null;default:''"`
+	// 📊 子树内题量 (所有子题 + 独立单题)，导入 / 编辑 / 删除后由 RefreshCategoryStats 重算
+	QuestionCount int64     `gorm:"default:0;not null" json:"-"`
 	CreatedAt time.Time      `json:"-"`
 	UpdatedAt time.Time      `json:"-"`
 
@@ -65,6 +67,17 @@ type Question struct {
 	CognitiveLevel string  `gorm:"type:varchar(20)" json:"cognitive_level,omitempty"`
 	Syllabus       string  `gorm:"size:50" json:"syllabus"`
 
+	// 🔑 外部键：导入模板里的"题目编号"，不填则为题干指纹；重复导入时靠它原地更新而不是新建
+	ExternalKey string `gorm:"type:varchar(64);index" json:"external_key,omitempty"`
+	ImportRow   int    `gorm:"-" json:"-"` // 导入时所在的文件行号 (仅内存中使用)
+	Knowledge   string `gorm:"-" json:"-"` // 导入 / 导出时的"知识点"列 (仅内存中使用，实际关联见 QuestionTag)
+
+	// 📜 当前版本号：每次内容变更 +1，历史快照见 QuestionRevision；作答记录会记下答题时的版本
+	Revision int `gorm:"default:1;not null" json:"revision"`
+
+	// 🎞️ 附加的富媒体内容块 (带说明的图集、表格、公式、音频)，与题干 / 选项里原有的文字一并渲染
+	Media *QuestionMedia `gorm:"type:jsonb;serializer:json" json:"media,omitempty"`
+
 	UserRecord interface{} `gorm:"-" json:"user_record,omitempty"`
 	
 	// 🔥 确保这个字段也在
@@ -79,6 +92,32 @@ func (Question) TableName() string {
 	return "questions"
 }
 
+// 内容块类型
+const (
+	BlockText    = "text"    // 文本段落
+	BlockImage   = "image"   // 图片 (可带说明)
+	BlockTable   = "table"   // 表格 (首行为表头)
+	BlockFormula = "formula" // 公式 (LaTeX / MathML)
+	BlockAudio   = "audio"   // 音频 (听诊音等)
+)
+
+// ContentBlock 结构化内容块：前端按 Type 渲染，不再解析 markdown / HTML 混排字符串
+type ContentBlock struct {
+	Type    string     `json:"type"`
+	Text    string     `json:"text,omitempty"`    // text: 段落文字；formula: 公式源码
+	Format  string     `json:"format,omitempty"`  // formula: latex / mathml
+	Inline  bool       `json:"inline,omitempty"`  // formula: 是否为行内公式
+	URL     string     `json:"url,omitempty"`     // image / audio
+	Caption string     `json:"caption,omitempty"` // image / table / audio 的说明文字
+	Rows    [][]string `json:"rows,omitempty"`    // table
+}
+
+// QuestionMedia 题目附加的内容块：题干下方的图集 / 表格 / 公式 / 音频，以及各选项的附图
+type QuestionMedia struct {
+	Stem    []ContentBlock            `json:"stem,omitempty"`
+	Options map[string][]ContentBlock `json:"options,omitempty"`
+}
+
 // ---------------------------------------------------------
 // 🔥🔥🔥 新增统计表 (为了实现冷热分离 + 永久记录) 🔥🔥🔥
 // ---------------------------------------------------------
@@ -98,6 +137,16 @@ func (UserDailyStat) TableName() string {
 	return "user_daily_stats"
 }
 
+// StreakIslandsSQL 连续打卡区间：日期减去行号相同的为一组 (gaps and islands)
+// 返回 user_id, start_day, end_day (date), days 四列；filter 为附加在 user_daily_stats 上的条件
+// 💡 个人分析与连续打卡榜共用，"当前连续" 统一指截至今天 (end_day = 今天) 的那一段
+func StreakIslandsSQL(filter string) string {
+	return `SELECT user_id, MIN(day) AS start_day, MAX(day) AS end_day, COUNT(*) AS days FROM (
+			SELECT user_id, day, day - (ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY day))::int AS grp
+			FROM (SELECT user_id, date_str::date AS day FROM user_daily_stats WHERE count > 0 AND ` + filter + `) d
+		) g GROUP BY user_id, grp`
+}
+
 // UserArchivedStat 用户历史归档统计 (冷数据)
 // 作用：存储超过365天的老数据总和，保证"总刷题数"不丢失。
 // 特点：每个用户永远只有一行数据。
@@ -135,4 +184,157 @@ type QuestionFeedback struct {
 	Question    Question  `gorm:"foreignKey:QuestionID" json:"question"`
 }
 
-func (QuestionFeedback) TableName() string { return "question_feedbacks" }
\ No newline at end of file
+func (QuestionFeedback) TableName() string { return "question_feedbacks" }
+
+// ---------------------------------------------------------
+// 📦 后台导入任务
+// ---------------------------------------------------------
+
+// 导入任务状态
+const (
+	ImportJobPending = "pending" // 已提交，等待执行
+	ImportJobRunning = "running" // 执行中
+	ImportJobSuccess = "success" // 导入成功
+	ImportJobFailed  = "failed"  // 失败 (已整体回滚)
+)
+
+// ImportJob 一次正式导入 (校验通过后转入后台执行，前端轮询或通过 WebSocket 接收进度)
+type ImportJob struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	UserID    uint   `gorm:"index" json:"user_id"`                   // 发起人
+	Source    string `gorm:"type:varchar(100);index;uniqueIndex:idx_import_job_active,where:status = 'pending' OR status = 'running'" json:"source"` // 目标题库 (部分唯一索引：同一题库同时只能有一个未结束的任务)
+	FileName  string `gorm:"type:varchar(255)" json:"file_name"`
+
+	Status    string `gorm:"type:varchar(20);index;default:'pending'" json:"status"`
+	Stage     string `gorm:"type:varchar(20)" json:"stage"` // images: 转存图片 / saving: 写入题库
+	Total     int    `json:"total"`                         // 待导入行数
+	Processed int    `json:"processed"`                     // 已处理行数
+
+	Added    int    `json:"added"`
+	Updated  int    `json:"updated"`
+	Errors   int    `json:"errors"`   // 被跳过的错误数
+	Warnings int    `json:"warnings"` // 警告数 (含图片转存失败)
+	Message  string `gorm:"type:text" json:"message"`
+
+	Summary *ImportSummary `gorm:"type:jsonb;serializer:json" json:"summary,omitempty"`
+	Report  *ImportReport  `gorm:"type:jsonb;serializer:json" json:"report,omitempty"`
+
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+func (ImportJob) TableName() string { return "question_import_jobs" }
+
+
+// ---------------------------------------------------------
+// 📜 题目版本历史
+// ---------------------------------------------------------
+
+// 版本来源
+const (
+	RevisionBaseline = "baseline" // 首次修改前补记的原始版本
+	RevisionEdit     = "edit"     // 后台编辑
+	RevisionImport   = "import"   // 重复导入覆盖
+	RevisionRollback = "rollback" // 回滚到历史版本
+)
+
+// RevisionSnapshot 某个版本的完整内容 (只含影响作答的字段)
+type RevisionSnapshot struct {
+	Type           string            `json:"type"`
+	Stem           string            `json:"stem"`
+	Options        map[string]string `json:"options,omitempty"`
+	Correct        string            `json:"correct"`
+	Analysis       string            `json:"analysis"`
+	Difficulty     string            `json:"difficulty"`
+	DiffValue      float64           `json:"diff_value"`
+	Syllabus       string            `json:"syllabus"`
+	CognitiveLevel string            `json:"cognitive_level"`
+	Media          *QuestionMedia    `json:"media,omitempty"` // 附加内容块 (图集 / 表格 / 公式 / 音频)
+}
+
+// QuestionRevision 题目的一个历史版本 (只增不改)
+type QuestionRevision struct {
+	ID         uint             `gorm:"primaryKey" json:"id"`
+	QuestionID uint             `gorm:"uniqueIndex:idx_question_revision;not null" json:"question_id"`
+	Revision   int              `gorm:"uniqueIndex:idx_question_revision;not null" json:"revision"`
+	Snapshot   RevisionSnapshot `gorm:"type:jsonb;serializer:json" json:"snapshot"`
+	EditorID   uint             `gorm:"index" json:"editor_id"` // 0 = 系统 (原始版本)
+	Action     string           `gorm:"type:varchar(20)" json:"action"`
+	Note       string           `gorm:"type:varchar(255)" json:"note"` // 修改说明
+	CreatedAt  time.Time        `json:"created_at"`
+}
+
+func (QuestionRevision) TableName() string { return "question_revisions" }
+
+
+// ---------------------------------------------------------
+// 🔁 改答案后的重新判分任务
+// ---------------------------------------------------------
+
+// 重判任务状态
+const (
+	RegradePending = "pending"
+	RegradeRunning = "running"
+	RegradeSuccess = "success"
+	RegradeFailed  = "failed"
+)
+
+// RegradeJob 标准答案变更后排队的重判任务 (与新版本在同一事务里写入，由 answer 包的后台任务执行)
+type RegradeJob struct {
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	QuestionID uint   `gorm:"index" json:"question_id"`
+	Revision   int    `json:"revision"`                        // 触发重判的题目版本
+	OldCorrect string `gorm:"type:varchar(50)" json:"old_correct"`
+	NewCorrect string `gorm:"type:varchar(50)" json:"new_correct"`
+	EditorID   uint   `json:"editor_id"`
+
+	Status           string `gorm:"type:varchar(20);index;default:'pending'" json:"status"`
+	RecordsChanged   int    `json:"records_changed"`   // 改判的当前作答记录数
+	HistoriesChanged int    `json:"histories_changed"` // 改判的历史轨迹数
+	Affected         int    `json:"affected"`          // 受影响的用户数
+	Message          string `gorm:"type:varchar(255)" json:"message"`
+
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
+
+func (RegradeJob) TableName() string { return "question_regrade_jobs" }
+
+// RegradeQueued 有新的重判任务时发出信号，唤醒后台任务立即执行 (不阻塞，漏掉的由定时轮询兜底)
+var RegradeQueued = make(chan struct{}, 1)
+
+// ---------------------------------------------------------
+// 🏷️ 知识点体系 (跨题库)
+// ---------------------------------------------------------
+
+// KnowledgeTagSep 知识点路径分隔符 (与分类路径一致)
+const KnowledgeTagSep = " > "
+
+// KnowledgeTag 知识点：按国家考试大纲分层 (学科 > 系统 > 考点)，不属于任何题库，各题库的题目都可以挂上来
+type KnowledgeTag struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	Name      string `gorm:"type:varchar(100);not null" json:"name"`
+	Code      string `gorm:"type:varchar(50);index" json:"code,omitempty"` // 大纲编号，如 "2.3.1" (可选)
+	ParentID  *uint  `gorm:"index" json:"parent_id"`
+	Level     int    `gorm:"default:1" json:"level"`
+	FullPath  string `gorm:"type:varchar(500);uniqueIndex" json:"full_path"` // 内科学 > 循环系统 > 心力衰竭
+	SortOrder int    `gorm:"default:999" json:"sort_order"`
+
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"-"`
+
+	Children []*KnowledgeTag `gorm:"-" json:"children,omitempty"`
+}
+
+func (KnowledgeTag) TableName() string { return "knowledge_tags" }
+
+// QuestionTag 题目与知识点的多对多关联 (挂在可作答的小题上，组合题的父题不挂)
+type QuestionTag struct {
+	QuestionID uint      `gorm:"primaryKey;autoIncrement:false" json:"question_id"`
+	TagID      uint      `gorm:"primaryKey;autoIncrement:false;index" json:"tag_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (QuestionTag) TableName() string { return "question_tags" }
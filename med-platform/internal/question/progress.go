@@ -0,0 +1,241 @@
+package question
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"med-platform/internal/common/cache"
+	"med-platform/internal/common/db"
+
+	"gorm.io/gorm"
+)
+
+// =========================================================
+// 📊 目录树统计：总题数预先算好存在目录上，个人进度整层一次分组查询
+// =========================================================
+
+// 计入题量的口径：所有子题 + 独立单题 (A3/A4/B1 大题本身不算)
+const countableQuestion = `(q.parent_id > 0 OR (q.type NOT LIKE 'A3%' AND q.type NOT LIKE 'A4%' AND q.type NOT LIKE 'B1%'))`
+
+// treeProgressTTL 个人进度缓存有效期 (交卷时主动失效，这里只是兜底)
+const treeProgressTTL = 10 * time.Minute
+
+// RefreshCategoryStats 回填 category_id 并重算目录 (含子树) 的题量，source 为空时重算全部题库
+func (r *Repository) RefreshCategoryStats(source string) error {
+	return refreshCategoryStats(db.DB, source)
+}
+
+func refreshCategoryStats(tx *gorm.DB, source string) error {
+	scope := "TRUE"
+	if source != "" {
+		scope = "q.source = @source"
+	}
+	args := map[string]interface{}{"source": source}
+
+	// 1. 题目与作答记录上冗余的 category_id (导入时不写，这里按路径补齐)
+	if err := tx.Exec(`UPDATE questions q SET category_id = c.id FROM categories c
+		WHERE c.source = q.source AND c.full_path = q.category_path AND q.category_id <> c.id AND `+scope, args).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec(`UPDATE answer_records ar SET category_id = q.category_id FROM questions q
+		WHERE q.id = ar.question_id AND ar.category_id <> q.category_id AND `+scope, args).Error; err != nil {
+		return err
+	}
+
+	// 2. 按路径分组计数，再在内存里逐级累加到祖先目录
+	type pathCount struct {
+		Source       string
+		CategoryPath string
+		N            int64
+	}
+	var counts []pathCount
+	if err := tx.Raw(`SELECT q.source, q.category_path, COUNT(*) AS n FROM questions q
+		WHERE q.deleted_at IS NULL AND `+countableQuestion+` AND `+scope+`
+		GROUP BY q.source, q.category_path`, args).Scan(&counts).Error; err != nil {
+		return err
+	}
+
+	var cats []Category
+	query := tx.Select("id, source, full_path, question_count")
+	if source != "" {
+		query = query.Where("source = ?", source)
+	}
+	if err := query.Find(&cats).Error; err != nil {
+		return err
+	}
+	byPath := make(map[string]*Category, len(cats))
+	for i := range cats {
+		byPath[cats[i].Source+"\x00"+cats[i].FullPath] = &cats[i]
+	}
+
+	totals := make(map[uint]int64, len(cats))
+	for _, pc := range counts {
+		parts := strings.Split(pc.CategoryPath, CategoryPathSep)
+		for i := range parts {
+			if c, ok := byPath[pc.Source+"\x00"+strings.Join(parts[:i+1], CategoryPathSep)]; ok {
+				totals[c.ID] += pc.N
+			}
+		}
+	}
+
+	// 3. 只写有变化的目录
+	for _, c := range cats {
+		if totals[c.ID] == c.QuestionCount {
+			continue
+		}
+		if err := tx.Model(&Category{}).Where("id = ?", c.ID).UpdateColumn("question_count", totals[c.ID]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// categoryProgress 用户在某个目录 (不含子目录) 下的已做 / 答对题数
+type categoryProgress struct {
+	Done    int64 `json:"d"`
+	Correct int64 `json:"c"`
+}
+
+func treeProgressKey(userID uint) string {
+	return fmt.Sprintf("tree:progress:%d", userID)
+}
+
+// InvalidateTreeProgress 作答记录变化后清掉该用户的目录进度缓存
+func InvalidateTreeProgress(userID uint) {
+	if cache.RDB == nil || userID == 0 {
+		return
+	}
+	cache.RDB.Del(context.Background(), treeProgressKey(userID))
+}
+
+// userTreeProgress 用户在各目录下的进度，key 为 source + "\x00" + full_path
+// 一次按 answer_records.category_id 分组查询；Redis 可用时按 (用户, 题库) 缓存
+func (r *Repository) userTreeProgress(userID uint, source string) map[string]categoryProgress {
+	progress := map[string]categoryProgress{}
+	if userID == 0 {
+		return progress
+	}
+
+	ctx := context.Background()
+	key := treeProgressKey(userID)
+	if cache.RDB != nil {
+		if raw, err := cache.RDB.HGet(ctx, key, source).Result(); err == nil && json.Unmarshal([]byte(raw), &progress) == nil {
+			return progress
+		}
+	}
+
+	type row struct {
+		Source   string
+		FullPath string
+		Done     int64
+		Correct  int64
+	}
+	var rows []row
+	query := db.DB.Table("answer_records ar").
+		Select("c.source, c.full_path, COUNT(DISTINCT ar.question_id) AS done, COUNT(DISTINCT ar.question_id) FILTER (WHERE ar.is_correct) AS correct").
+		Joins("JOIN categories c ON c.id = ar.category_id").
+		Where("ar.user_id = ? AND ar.deleted_at IS NULL", userID)
+	if source != "" {
+		query = query.Where("c.source = ?", source)
+	}
+	if err := query.Group("c.source, c.full_path").Scan(&rows).Error; err != nil {
+		return progress
+	}
+	for _, rw := range rows {
+		progress[rw.Source+"\x00"+rw.FullPath] = categoryProgress{Done: rw.Done, Correct: rw.Correct}
+	}
+
+	if cache.RDB != nil {
+		if raw, err := json.Marshal(progress); err == nil {
+			cache.RDB.HSet(ctx, key, source, raw)
+			cache.RDB.Expire(ctx, key, treeProgressTTL)
+		}
+	}
+	return progress
+}
+
+// sumProgress 把子树内各目录的进度累加到 (source, fullPath) 这个节点上
+func sumProgress(progress map[string]categoryProgress, source, fullPath string) categoryProgress {
+	var sum categoryProgress
+	self := source + "\x00" + fullPath
+	prefix := self + CategoryPathSep
+	for k, p := range progress {
+		if k == self || strings.HasPrefix(k, prefix) {
+			sum.Done += p.Done
+			sum.Correct += p.Correct
+		}
+	}
+	return sum
+}
+
+// CategoryProgress 若干目录 (含子树) 合计的总题数与用户已做 / 答对题数，口径与目录树一致
+// 选中的目录互相包含时只算外层一次
+func (r *Repository) CategoryProgress(userID uint, categoryIDs []uint) (total, done, correct int64, err error) {
+	if len(categoryIDs) == 0 {
+		return 0, 0, 0, nil
+	}
+	var cats []Category
+	if err = db.DB.Where("id IN ?", categoryIDs).Order("level asc").Find(&cats).Error; err != nil {
+		return 0, 0, 0, err
+	}
+	var kept []Category
+	for _, c := range cats {
+		nested := false
+		for _, k := range kept {
+			if k.Source == c.Source && strings.HasPrefix(c.FullPath+CategoryPathSep, k.FullPath+CategoryPathSep) {
+				nested = true
+				break
+			}
+		}
+		if !nested {
+			kept = append(kept, c)
+		}
+	}
+
+	bySource := make(map[string]map[string]categoryProgress)
+	for _, c := range kept {
+		progress, ok := bySource[c.Source]
+		if !ok {
+			progress = r.userTreeProgress(userID, c.Source)
+			bySource[c.Source] = progress
+		}
+		p := sumProgress(progress, c.Source, c.FullPath)
+		total += c.QuestionCount
+		done += p.Done
+		correct += p.Correct
+	}
+	return total, done, correct, nil
+}
+
+// CategoryDoneSince 若干目录 (含子树) 内用户在 since 之后才第一次做的题数，口径与 CategoryProgress 的已做题数一致
+func (r *Repository) CategoryDoneSince(userID uint, categoryIDs []uint, since time.Time) (int64, error) {
+	if len(categoryIDs) == 0 {
+		return 0, nil
+	}
+	var n int64
+	err := db.DB.Table("answer_records ar").
+		Select("COUNT(DISTINCT ar.question_id)").
+		Joins("JOIN categories c ON c.id = ar.category_id").
+		Joins("JOIN categories sel ON sel.source = c.source AND (c.full_path = sel.full_path OR c.full_path LIKE sel.full_path || ? || '%')", CategoryPathSep).
+		Where("sel.id IN ? AND ar.user_id = ? AND ar.deleted_at IS NULL AND ar.created_at >= ?", categoryIDs, userID, since).
+		Scan(&n).Error
+	return n, err
+}
+
+// refreshStatsOfQuestions 重算这些题目 (含已软删除的) 所在题库的目录题量
+func refreshStatsOfQuestions(tx *gorm.DB, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	var sources []string
+	tx.Model(&Question{}).Unscoped().Where("id IN ?", ids).Distinct().Pluck("source", &sources)
+	for _, s := range sources {
+		if err := refreshCategoryStats(tx, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"med-platform/internal/common/db"
+	"med-platform/internal/search"
 
 	"gorm.io/gorm"
 )
@@ -41,10 +42,17 @@ func (r *Repository) List(page, pageSize int, category, keyword, source string)
 		query = query.Where("parent_id IS NULL OR parent_id = 0")
 	} else {
 		// 模式 B：按章节或关键词搜索
-		if keyword != "" {
-			// 搜索模式：保持宽泛匹配
+		if tsq := search.BuildTSQuery(keyword); tsq != "" {
+			// 搜索模式：走全文索引 (题干 / 选项 / 解析)；索引是定时同步的，
+			// 同时保留原来的 LIKE 匹配，刚导入 / 刚改过的题目不必等下一轮同步就能搜到
 			likeStr := "%" + keyword + "%"
-			query = query.Where("stem LIKE ? OR analysis LIKE ?", likeStr, likeStr)
+			matched := db.DB.Table("search_documents").Select("doc_id").
+				Where("doc_type = ? AND tsv @@ ?::tsquery", search.DocQuestion, tsq)
+			query = query.Where("(id IN (?) OR stem LIKE ? OR analysis LIKE ?)", matched, likeStr, likeStr)
+		} else if keyword != "" {
+			// 关键词里没有可检索的字 (纯符号)，退回宽泛匹配
+			likeStr := "%" + keyword + "%"
+			query = query.Where("(stem LIKE ? OR analysis LIKE ?)", likeStr, likeStr)
 		} else {
 			// 按章节浏览模式 (排除纯父题壳子，防止计数虚高)
 			query = query.Where("category_path LIKE ?", category+"%")
@@ -125,63 +133,44 @@ func (r *Repository) GetTree(parentID *int, source string, userID uint) ([]*Cate
 	}
 
 var nodes []*CategoryNode
+	if len(cats) == 0 {
+		return nodes, nil
+	}
+
+	// 1. 整层一次查出各节点的子目录数 (判断是否叶子)
+	ids := make([]uint, 0, len(cats))
 	for _, c := range cats {
-		pathPattern := c.FullPath + "%"
-
-		// 1. 统计总题数 (口径：所有子题 + 独立单题)
-		var total int64
-		db.DB.Table("questions").
-			Where("source = ? AND category_path LIKE ? AND deleted_at IS NULL", source, pathPattern).
-			Where("(parent_id > 0 OR (type NOT LIKE 'A3%' AND type NOT LIKE 'A4%' AND type NOT LIKE 'B1%'))").
-			Count(&total)
-
-		// 2. 统计已做题数
-		var done int64
-		if userID > 0 && total > 0 {
-			db.DB.Table("answer_records").
-				Joins("JOIN questions ON answer_records.question_id = questions.id").
-				Where("answer_records.user_id = ?", userID).
-				Where("questions.source = ?", source).
-				Where("questions.category_path LIKE ?", pathPattern).
-				Where("questions.deleted_at IS NULL").
-				Where("(questions.parent_id > 0 OR (questions.type NOT LIKE 'A3%' AND questions.type NOT LIKE 'A4%' AND questions.type NOT LIKE 'B1%'))").
-				Select("COUNT(DISTINCT answer_records.question_id)").
-				Scan(&done)
-		}
+		ids = append(ids, c.ID)
+	}
+	type childCount struct {
+		ParentID uint
+		N        int64
+	}
+	var childCounts []childCount
+	db.DB.Model(&Category{}).Select("parent_id, COUNT(*) AS n").
+		Where("parent_id IN ? AND level <= ?", ids, MaxLevel).
+		Group("parent_id").Scan(&childCounts)
+	subCount := make(map[uint]int64, len(childCounts))
+	for _, cc := range childCounts {
+		subCount[cc.ParentID] = cc.N
+	}
 
-		// 3. 🔥 新增：统计已答对题数
-		var correct int64
-		if userID > 0 && done > 0 {
-			db.DB.Table("answer_records").
-				Joins("JOIN questions ON answer_records.question_id = questions.id").
-				Where("answer_records.user_id = ? AND answer_records.is_correct = ?", userID, true).
-				Where("questions.source = ?", source).
-				Where("questions.category_path LIKE ?", pathPattern).
-				Where("questions.deleted_at IS NULL").
-				Where("(questions.parent_id > 0 OR (questions.type NOT LIKE 'A3%' AND questions.type NOT LIKE 'A4%' AND questions.type NOT LIKE 'B1%'))").
-				Select("COUNT(DISTINCT answer_records.question_id)").
-				Scan(&correct)
-		}
+	// 2. 当前用户的进度 (一次分组查询，带缓存)
+	progress := r.userTreeProgress(userID, source)
 
-		isLeaf := false
-		if c.Level >= MaxLevel {
-			isLeaf = true
-		} else {
-			var subCount int64
-			db.DB.Model(&Category{}).Where("parent_id = ? AND level <= ?", c.ID, MaxLevel).Count(&subCount)
-			isLeaf = (subCount == 0)
-		}
-		
+	for _, c := range cats {
+		// 总题数直接取预先算好的 question_count
+		p := sumProgress(progress, c.Source, c.FullPath)
 		nodes = append(nodes, &CategoryNode{
 			ID:           c.ID,
 			Name:         c.Name,
 			Full:         c.FullPath,
 			SortOrder:    c.SortOrder,
 			Level:        c.Level,
-			IsLeaf:       isLeaf,
-			TotalCount:   total,
-			DoneCount:    done,
-			CorrectCount: correct, // 🔥 填入正确数
+			IsLeaf:       c.Level >= MaxLevel || subCount[c.ID] == 0,
+			TotalCount:   c.QuestionCount,
+			DoneCount:    p.Done,
+			CorrectCount: p.Correct, // 🔥 填入正确数
 		})
 	}
 	return nodes, nil
@@ -290,8 +279,14 @@ func (r *Repository) UpdateCategory(id uint, req UpdateCategoryReq) error {
 	if err := db.DB.First(&cat, id).Error; err != nil {
 		return err
 	}
-	if req.Name != "" {
-		cat.Name = req.Name
+	// 改名要连带改写子树与题目的路径 (否则下次 SyncCategories 会按题目上的旧路径把旧目录建回来)
+	if req.Name != "" && req.Name != cat.Name {
+		if _, err := r.MoveCategory(id, cat.ParentID, req.Name, false); err != nil {
+			return err
+		}
+		if err := db.DB.First(&cat, id).Error; err != nil {
+			return err
+		}
 	}
 	if req.SortOrder != nil {
 		cat.SortOrder = *req.SortOrder
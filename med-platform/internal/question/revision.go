@@ -0,0 +1,371 @@
+package question
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"med-platform/internal/common/db"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// =========================================================
+// 📸 快照
+// =========================================================
+
+// snapshotOf 取题目当前内容的快照 (难度系数按库里 decimal(3,2) 的精度取整，避免"没改也算改了")
+// 💡 所属目录不算内容：目录移动 / 改名会直接改写路径且不留版本，回滚时不能把题目挪回可能已不存在的旧路径
+func snapshotOf(q *Question) RevisionSnapshot {
+	s := RevisionSnapshot{
+		Type: q.Type, Stem: q.Stem, Correct: q.Correct, Analysis: q.Analysis,
+		Difficulty: q.Difficulty, DiffValue: math.Round(q.DiffValue*100) / 100, Syllabus: q.Syllabus, CognitiveLevel: q.CognitiveLevel,
+	}
+	if len(q.Options) > 0 {
+		_ = json.Unmarshal(q.Options, &s.Options)
+	}
+	if len(s.Options) == 0 {
+		s.Options = nil
+	}
+	if q.Media != nil && (len(q.Media.Stem) > 0 || len(q.Media.Options) > 0) {
+		s.Media = q.Media
+	}
+	return s
+}
+
+// fields 快照对应的题目字段 (用 map 保证空值也能写入)
+func (s RevisionSnapshot) fields() map[string]interface{} {
+	var options datatypes.JSON
+	if len(s.Options) > 0 {
+		options, _ = json.Marshal(s.Options)
+	}
+	return map[string]interface{}{
+		"type":            s.Type,
+		"stem":            s.Stem,
+		"options":         options,
+		"correct":         s.Correct,
+		"analysis":        s.Analysis,
+		"difficulty":      s.Difficulty,
+		"diff_value":      s.DiffValue,
+		"syllabus":        s.Syllabus,
+		"cognitive_level": s.CognitiveLevel,
+		"media":           s.Media,
+	}
+}
+
+// FieldChange 字段级差异
+type FieldChange struct {
+	Field  string `json:"field"`
+	Label  string `json:"label"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// diffSnapshots 逐字段比较两个版本 (选项按 A~F 逐个比较)
+func diffSnapshots(before, after RevisionSnapshot) []FieldChange {
+	changes := []FieldChange{}
+	add := func(field, label, a, b string) {
+		if a != b {
+			changes = append(changes, FieldChange{Field: field, Label: label, Before: a, After: b})
+		}
+	}
+	add("type", "题型", before.Type, after.Type)
+	add("stem", "题干", before.Stem, after.Stem)
+	for _, k := range optionKeys {
+		add("options."+k, "选项"+k, before.Options[k], after.Options[k])
+	}
+	add("correct", "答案", before.Correct, after.Correct)
+	add("analysis", "解析", before.Analysis, after.Analysis)
+	add("difficulty", "难度", before.Difficulty, after.Difficulty)
+	add("diff_value", "难度系数", formatDiff(before.DiffValue), formatDiff(after.DiffValue))
+	add("syllabus", "考纲", before.Syllabus, after.Syllabus)
+	add("cognitive_level", "认知层次", before.CognitiveLevel, after.CognitiveLevel)
+	add("media", "附加内容", mediaText(before.Media), mediaText(after.Media))
+	return changes
+}
+
+// mediaText 附加内容块按 JSON 比较与展示
+func mediaText(m *QuestionMedia) string {
+	if m == nil {
+		return ""
+	}
+	b, _ := json.Marshal(m)
+	return string(b)
+}
+
+func formatDiff(v float64) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// =========================================================
+// ✍️ 写入新版本
+// =========================================================
+
+// reviseQuestion 在事务内把 next 的内容写入题目；内容有变化时版本号 +1 并保存快照
+// 💡 第一次修改前先补记一条"原始版本"，保证改之前学生看到的内容有据可查
+func reviseQuestion(tx *gorm.DB, id uint, next RevisionSnapshot, editorID uint, action, note string) (*Question, bool, error) {
+	var cur Question
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&cur, id).Error; err != nil {
+		return nil, false, err
+	}
+	before := snapshotOf(&cur)
+	if reflect.DeepEqual(before, next) {
+		return &cur, false, nil
+	}
+
+	if cur.Revision < 1 {
+		cur.Revision = 1
+	}
+	var count int64
+	tx.Model(&QuestionRevision{}).Where("question_id = ?", id).Count(&count)
+	if count == 0 {
+		baseline := QuestionRevision{
+			QuestionID: id, Revision: cur.Revision, Snapshot: before,
+			Action: RevisionBaseline, Note: "原始版本", CreatedAt: cur.UpdatedAt,
+		}
+		if err := tx.Create(&baseline).Error; err != nil {
+			return nil, false, err
+		}
+	}
+
+	fields := next.fields()
+	fields["revision"] = cur.Revision + 1
+	if err := tx.Model(&Question{}).Where("id = ?", id).Updates(fields).Error; err != nil {
+		return nil, false, err
+	}
+	rev := QuestionRevision{
+		QuestionID: id, Revision: cur.Revision + 1, Snapshot: next,
+		EditorID: editorID, Action: action, Note: note,
+	}
+	if err := tx.Create(&rev).Error; err != nil {
+		return nil, false, err
+	}
+
+	// 标准答案变了：排队重判已有作答 (事务提交后由 answer 包执行)
+	if normalizeKey(before.Correct) != normalizeKey(next.Correct) {
+		job := RegradeJob{
+			QuestionID: id, Revision: rev.Revision, OldCorrect: before.Correct, NewCorrect: next.Correct,
+			EditorID: editorID, Status: RegradePending,
+		}
+		if err := tx.Create(&job).Error; err != nil {
+			return nil, false, err
+		}
+	}
+
+	if err := tx.First(&cur, id).Error; err != nil {
+		return nil, false, err
+	}
+	return &cur, true, nil
+}
+
+// normalizeKey 与判题口径一致：忽略大小写和首尾空白
+func normalizeKey(s string) string {
+	return strings.ToUpper(strings.TrimSpace(s))
+}
+
+// wakeRegrade 事务提交后唤醒重判任务 (没有排队任务时也无害)
+func wakeRegrade() {
+	select {
+	case RegradeQueued <- struct{}{}:
+	default:
+	}
+}
+
+// =========================================================
+// 🗄️ 查询
+// =========================================================
+
+// RevisionItem 版本列表条目
+type RevisionItem struct {
+	QuestionRevision
+	EditorName string   `json:"editor_name"`
+	Changed    []string `json:"changed"` // 相对上一版本改动的字段名
+}
+
+// ListRevisions 题目的全部版本 (新的在前)
+func (r *Repository) ListRevisions(questionID uint) ([]RevisionItem, error) {
+	var revs []QuestionRevision
+	if err := db.DB.Where("question_id = ?", questionID).Order("revision asc").Find(&revs).Error; err != nil {
+		return nil, err
+	}
+
+	editorIDs := make([]uint, 0, len(revs))
+	for _, rev := range revs {
+		if rev.EditorID > 0 {
+			editorIDs = append(editorIDs, rev.EditorID)
+		}
+	}
+	type editor struct {
+		ID       uint
+		Username string
+		Nickname string
+	}
+	var editors []editor
+	if len(editorIDs) > 0 {
+		db.DB.Table("users").Select("id, username, nickname").Where("id IN ?", editorIDs).Scan(&editors)
+	}
+	names := make(map[uint]string, len(editors))
+	for _, e := range editors {
+		names[e.ID] = firstNonEmpty(e.Nickname, e.Username)
+	}
+
+	items := make([]RevisionItem, len(revs))
+	for i, rev := range revs {
+		item := RevisionItem{QuestionRevision: rev, EditorName: names[rev.EditorID], Changed: []string{}}
+		if rev.EditorID == 0 {
+			item.EditorName = "系统"
+		}
+		if i > 0 {
+			for _, ch := range diffSnapshots(revs[i-1].Snapshot, rev.Snapshot) {
+				item.Changed = append(item.Changed, ch.Label)
+			}
+		}
+		items[len(revs)-1-i] = item
+	}
+	return items, nil
+}
+
+func (r *Repository) GetRevision(questionID uint, revision int) (*QuestionRevision, error) {
+	var rev QuestionRevision
+	err := db.DB.Where("question_id = ? AND revision = ?", questionID, revision).First(&rev).Error
+	return &rev, err
+}
+
+// =========================================================
+// 🌐 接口
+// =========================================================
+
+// ListRevisions 版本历史
+// GET /admin/questions/:id/revisions
+func (h *Handler) ListRevisions(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var q Question
+	if err := db.DB.Unscoped().Select("id, revision").First(&q, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "题目不存在"})
+		return
+	}
+	list, err := h.repo.ListRevisions(q.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取版本历史失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list, "current": q.Revision})
+}
+
+// GetRevisionDiff 两个版本的字段级差异
+// GET /admin/questions/:id/revisions/:rev/diff?base=3  (base 默认为上一版本；rev=current 表示当前内容)
+func (h *Handler) GetRevisionDiff(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var q Question
+	if err := db.DB.Unscoped().First(&q, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "题目不存在"})
+		return
+	}
+
+	load := func(revision int) (RevisionSnapshot, bool) {
+		if revision == q.Revision {
+			return snapshotOf(&q), true
+		}
+		rev, err := h.repo.GetRevision(q.ID, revision)
+		if err != nil {
+			return RevisionSnapshot{}, false
+		}
+		return rev.Snapshot, true
+	}
+
+	target := q.Revision
+	if p := c.Param("rev"); p != "current" {
+		target, _ = strconv.Atoi(p)
+	}
+	base := target - 1
+	if v, err := strconv.Atoi(c.Query("base")); err == nil {
+		base = v
+	}
+
+	after, ok := load(target)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("版本 v%d 不存在", target)})
+		return
+	}
+	before := RevisionSnapshot{}
+	if base >= 1 {
+		if before, ok = load(base); !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("版本 v%d 不存在", base)})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"from": base, "to": target, "changes": diffSnapshots(before, after)})
+}
+
+// RollbackRevision 一键回滚：用历史版本的内容生成一个新版本 (历史本身不删)
+// POST /admin/questions/:id/revisions/:rev/rollback
+func (h *Handler) RollbackRevision(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	revision, _ := strconv.Atoi(c.Param("rev"))
+	target, err := h.repo.GetRevision(uint(id), revision)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "该版本不存在"})
+		return
+	}
+
+	var updated *Question
+	var changed bool
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var before Question
+		if err := tx.Select("type").First(&before, id).Error; err != nil {
+			return err
+		}
+		var err error
+		updated, changed, err = reviseQuestion(tx, uint(id), target.Snapshot, c.MustGet("userID").(uint),
+			RevisionRollback, fmt.Sprintf("回滚到 v%d", revision))
+		if err != nil || !changed {
+			return err
+		}
+		// 回滚可能改回了题型，目录题量要跟着重算
+		if updated.Type != before.Type {
+			return refreshStatsOfQuestions(tx, []uint{updated.ID})
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "回滚失败"})
+		return
+	}
+	if !changed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "当前内容与该版本一致，无需回滚"})
+		return
+	}
+	wakeRegrade()
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("已回滚到 v%d，当前为 v%d", revision, updated.Revision), "data": updated})
+}
+
+// ListRegradeJobs 改答案触发的重判任务 (可按题目过滤)
+// GET /admin/regrade-jobs?question_id=12&page=1
+func (h *Handler) ListRegradeJobs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	query := db.DB.Model(&RegradeJob{})
+	if qid, _ := strconv.Atoi(c.Query("question_id")); qid > 0 {
+		query = query.Where("question_id = ?", qid)
+	}
+	var total int64
+	query.Count(&total)
+	var list []RegradeJob
+	if err := query.Order("id desc").Offset((page - 1) * 20).Limit(20).Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取重判任务失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list, "total": total, "page": page})
+}
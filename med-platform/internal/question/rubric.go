@@ -0,0 +1,61 @@
+package question
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// =========================================================
+// 📝 主观题 (问答 / 论述 / 案例 / 名词解释)
+// =========================================================
+
+// subjectiveTypes 主观题题型关键字 (导入时题型名五花八门，按包含判断)
+var subjectiveTypes = []string{"问答", "论述", "案例", "名词解释", "简答"}
+
+// IsSubjective 是否为主观题：作答是自由文本，不做字符串精确比对
+func IsSubjective(t string) bool {
+	for _, k := range subjectiveTypes {
+		if strings.Contains(t, k) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	rubricTagRe    = regexp.MustCompile(`<[^>]*>`)
+	rubricBreakRe  = regexp.MustCompile(`(?:^|[\s；;。])(?:\d{1,2}[.、．)）]|[（(]\d{1,2}[)）]|[①②③④⑤⑥⑦⑧⑨⑩])`)
+	rubricCircleRe = regexp.MustCompile(`[①②③④⑤⑥⑦⑧⑨⑩]`)
+)
+
+// RubricPoints 从参考答案中拆出评分要点
+// 优先按编号 (1. / (1) / ①) 与换行拆分；拆不出来时再按分号、句号拆
+func RubricPoints(correct string) []string {
+	text := html.UnescapeString(rubricTagRe.ReplaceAllString(correct, "\n"))
+	text = rubricCircleRe.ReplaceAllStringFunc(text, func(s string) string { return "\n" + s })
+	text = rubricBreakRe.ReplaceAllString(text, "\n")
+
+	points := splitPoints(text, "\n")
+	if len(points) <= 1 {
+		points = splitPoints(text, "\n", "；", ";", "。")
+	}
+	return points
+}
+
+func splitPoints(text string, seps ...string) []string {
+	for _, sep := range seps[1:] {
+		text = strings.ReplaceAll(text, sep, seps[0])
+	}
+	seen := make(map[string]bool)
+	points := []string{}
+	for _, p := range strings.Split(text, seps[0]) {
+		p = strings.Trim(strings.TrimSpace(p), "，,：:")
+		if p == "" || seen[p] {
+			continue
+		}
+		seen[p] = true
+		points = append(points, p)
+	}
+	return points
+}
@@ -0,0 +1,349 @@
+package question
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"med-platform/internal/common/db"
+	"med-platform/internal/common/model"
+	"med-platform/internal/common/service"
+	"med-platform/internal/itemstat"
+	"med-platform/internal/sysconfig"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// =========================================================
+// 🗑️ 回收站：删除题库 / 章节 / 题目时先软删除，保留期内可整体恢复
+// =========================================================
+
+// DefaultTrashRetentionDays 回收站默认保留天数 (可在系统配置 TRASH_RETENTION_DAYS 中修改)
+const DefaultTrashRetentionDays = 30
+
+// trashTable 删除题目时需要转存的一张关联表
+type trashTable struct {
+	name  string
+	where string // 转存范围，@ids 为题目 ID (含子题)
+}
+
+// questionTrashTables 按顺序转存 (先转存依赖笔记的点赞 / 收藏 / 举报)，恢复时倒序写回
+var questionTrashTables = []trashTable{
+	{"note_likes", "note_id IN (SELECT id FROM notes WHERE question_id IN @ids)"},
+	{"note_collects", "note_id IN (SELECT id FROM notes WHERE question_id IN @ids)"},
+	{"note_reports", "note_id IN (SELECT id FROM notes WHERE question_id IN @ids)"},
+	{"notes", "question_id IN @ids"},
+	{"user_favorites", "question_id IN @ids"},
+	{"user_mistakes", "question_id IN @ids"},
+	{"answer_records", "question_id IN @ids"},
+	{"review_states", "question_id IN @ids"},
+	{"question_feedbacks", "question_id IN @ids"},
+	{"question_tags", "question_id IN @ids"},
+}
+
+// 整库 / 整章删除时额外转存的目录与商品权益；恢复时已有同名目录 / 同样权益的行跳过
+const (
+	restoreCategoryFilter = `NOT EXISTS (SELECT 1 FROM categories c WHERE c.source = r.source AND c.full_path = r.full_path)`
+	restoreContentFilter  = `NOT EXISTS (SELECT 1 FROM product_contents p WHERE p.deleted_at IS NULL
+		AND p.product_id = r.product_id AND p.source = r.source AND p.category = r.category)`
+)
+
+// trashRetention 当前配置的保留期
+func trashRetention() time.Duration {
+	days := int(sysconfig.GetFloat(sysconfig.KeyTrashRetentionDays, DefaultTrashRetentionDays))
+	if days < 1 {
+		days = DefaultTrashRetentionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// moveToTrash 把 table 中满足 where 的行整行转存到 trash_rows 后删除，返回转存行数
+func moveToTrash(tx *gorm.DB, entryID uint, table, where string, args map[string]interface{}) (int64, error) {
+	args["entry"] = entryID
+	if err := tx.Exec(`INSERT INTO trash_rows (entry_id, origin, data)
+		SELECT @entry, '`+table+`', to_jsonb(t) FROM `+table+` t WHERE `+where, args).Error; err != nil {
+		return 0, err
+	}
+	res := tx.Exec(`DELETE FROM `+table+` WHERE `+where, args)
+	return res.RowsAffected, res.Error
+}
+
+// restoreFromTrash 把转存的行原样写回 (主键不变)；filter 为额外的写回条件，r 代表待写回的行
+func restoreFromTrash(tx *gorm.DB, entryID uint, table, filter string) error {
+	if filter == "" {
+		filter = "TRUE"
+	}
+	return tx.Exec(`INSERT INTO `+table+`
+		SELECT r.* FROM (
+			SELECT (jsonb_populate_record(NULL::`+table+`, data)).* FROM trash_rows WHERE entry_id = ? AND origin = ?
+		) r WHERE `+filter+`
+		ON CONFLICT DO NOTHING`, entryID, table).Error
+}
+
+// trashImpact 统计删除影响面 (在转存之前调用)
+func trashImpact(tx *gorm.DB, ids []uint) model.TrashImpact {
+	impact := model.TrashImpact{Questions: int64(len(ids))}
+	if len(ids) == 0 {
+		return impact
+	}
+	count := func(table string, dst *int64) {
+		tx.Table(table).Where("question_id IN ?", ids).Count(dst)
+	}
+	count("answer_records", &impact.AnswerRecords)
+	count("notes", &impact.Notes)
+	count("user_favorites", &impact.Favorites)
+	count("user_mistakes", &impact.Mistakes)
+	count("question_feedbacks", &impact.Feedbacks)
+	tx.Raw(`SELECT COUNT(DISTINCT user_id) FROM (
+		SELECT user_id FROM answer_records WHERE question_id IN @ids
+		UNION SELECT user_id FROM notes WHERE question_id IN @ids
+		UNION SELECT user_id FROM user_favorites WHERE question_id IN @ids
+	) u`, map[string]interface{}{"ids": ids}).Scan(&impact.Users)
+	return impact
+}
+
+// TrashQuestions 新建回收站条目，把题目 (含子题) 软删除并转存其全部关联数据
+// extra 为同一操作里需要一并转存的其他表 (目录、商品权益)，where 中可使用 @source / @path
+func (r *Repository) TrashQuestions(tx *gorm.DB, entry *model.TrashEntry, questionIDs []uint, extra ...trashTable) error {
+	var childIDs []uint
+	if len(questionIDs) > 0 {
+		tx.Model(&Question{}).Where("parent_id IN ?", questionIDs).Pluck("id", &childIDs)
+	}
+	ids := append(append([]uint{}, questionIDs...), childIDs...)
+
+	entry.QuestionIDs = ids
+	entry.Impact = trashImpact(tx, ids)
+	entry.Status = model.TrashStatusTrashed
+	entry.ExpiresAt = time.Now().Add(trashRetention())
+	if err := tx.Create(entry).Error; err != nil {
+		return err
+	}
+
+	args := func() map[string]interface{} {
+		return map[string]interface{}{"ids": ids, "source": entry.Source, "path": entry.CategoryPath}
+	}
+	if len(ids) > 0 {
+		for _, t := range questionTrashTables {
+			if _, err := moveToTrash(tx, entry.ID, t.name, t.where, args()); err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("id IN ?", ids).Delete(&Question{}).Error; err != nil {
+			return err
+		}
+	}
+	for _, t := range extra {
+		n, err := moveToTrash(tx, entry.ID, t.name, t.where, args())
+		if err != nil {
+			return err
+		}
+		if t.name == "categories" {
+			entry.Impact.Categories = n
+		}
+	}
+	if err := refreshStatsOfQuestions(tx, ids); err != nil {
+		return err
+	}
+	return tx.Model(entry).Select("impact").Updates(entry).Error
+}
+
+// RestoreConflict 待恢复的题目在删除后已被重新导入 (同题库里有外部键相同的在用题目)
+type RestoreConflict struct {
+	TrashedID  uint   `json:"trashed_id"`
+	ExistingID uint   `json:"existing_id"`
+	Stem       string `json:"stem"`
+}
+
+// RestoreConflictError 存在冲突时拒绝恢复，避免同一道题出现两份
+type RestoreConflictError struct {
+	Conflicts []RestoreConflict
+}
+
+func (e *RestoreConflictError) Error() string {
+	return fmt.Sprintf("有 %d 道题目在删除后已被重新导入，恢复会产生重复题，请先处理冲突", len(e.Conflicts))
+}
+
+// restoreConflicts 按导入用的外部键 (题目编号 / 题干指纹) 比对回收站里的大题与同题库的在用题目
+func restoreConflicts(entry *model.TrashEntry) ([]RestoreConflict, error) {
+	conflicts := []RestoreConflict{}
+	if len(entry.QuestionIDs) == 0 {
+		return conflicts, nil
+	}
+	var trashed []*Question
+	if err := db.DB.Unscoped().Where("id IN ? AND parent_id IS NULL AND deleted_at IS NOT NULL", entry.QuestionIDs).
+		Preload("Children", func(tx *gorm.DB) *gorm.DB { return tx.Unscoped().Order("id asc") }).
+		Find(&trashed).Error; err != nil {
+		return nil, err
+	}
+
+	bySource := make(map[string]map[string]*Question)
+	for _, q := range trashed {
+		questionKeys(q) // 老数据没有外部键时现场补算，与导入口径一致
+		if bySource[q.Source] == nil {
+			bySource[q.Source] = make(map[string]*Question)
+		}
+		bySource[q.Source][q.ExternalKey] = q
+	}
+	for source, keyed := range bySource {
+		keys := make([]string, 0, len(keyed))
+		for k := range keyed {
+			keys = append(keys, k)
+		}
+		var existing []Question
+		if err := db.DB.Select("id, external_key").
+			Where("source = ? AND parent_id IS NULL AND external_key IN ?", source, keys).
+			Find(&existing).Error; err != nil {
+			return nil, err
+		}
+		for _, e := range existing {
+			q := keyed[e.ExternalKey]
+			conflicts = append(conflicts, RestoreConflict{TrashedID: q.ID, ExistingID: e.ID, Stem: stemPreview(q.Stem)})
+		}
+	}
+	return conflicts, nil
+}
+
+// RestoreTrash 恢复回收站条目：题目取消软删除，目录、权益与全部关联数据原样写回
+// 删除后又重新导入过同一批题时返回 *RestoreConflictError，不做恢复
+func (r *Repository) RestoreTrash(id, operatorID uint) (*model.TrashEntry, error) {
+	var entry model.TrashEntry
+	if err := db.DB.First(&entry, id).Error; err != nil {
+		return nil, fmt.Errorf("回收站条目不存在")
+	}
+	if entry.Status != model.TrashStatusTrashed {
+		return nil, fmt.Errorf("该条目已恢复或已彻底清除")
+	}
+	conflicts, err := restoreConflicts(&entry)
+	if err != nil {
+		return nil, err
+	}
+	if len(conflicts) > 0 {
+		return nil, &RestoreConflictError{Conflicts: conflicts}
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := restoreFromTrash(tx, entry.ID, "categories", restoreCategoryFilter); err != nil {
+			return err
+		}
+		if err := restoreFromTrash(tx, entry.ID, "product_contents", restoreContentFilter); err != nil {
+			return err
+		}
+		if len(entry.QuestionIDs) > 0 {
+			// 刷新 updated_at：搜索索引、查重指纹的增量同步会重新收录
+			if err := tx.Exec(`UPDATE questions SET deleted_at = NULL, updated_at = NOW() WHERE id IN ? AND deleted_at IS NOT NULL`, entry.QuestionIDs).Error; err != nil {
+				return err
+			}
+		}
+		for i := len(questionTrashTables) - 1; i >= 0; i-- {
+			if err := restoreFromTrash(tx, entry.ID, questionTrashTables[i].name, ""); err != nil {
+				return err
+			}
+		}
+		if len(entry.QuestionIDs) > 0 {
+			if err := tx.Exec(`UPDATE notes SET updated_at = NOW() WHERE question_id IN ?`, entry.QuestionIDs).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("entry_id = ?", entry.ID).Delete(&model.TrashRow{}).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		entry.Status = model.TrashStatusRestored
+		entry.RestoredBy = operatorID
+		entry.RestoredAt = &now
+		return tx.Model(&entry).Updates(map[string]interface{}{"status": entry.Status, "restored_by": operatorID, "restored_at": now}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.SyncCategories()
+	refreshStatsOfQuestions(db.DB, entry.QuestionIDs)
+	if entry.Source != "" {
+		itemstat.RefreshSource(entry.Source)
+	}
+	return &entry, nil
+}
+
+// ListTrash 回收站列表 (默认只看可恢复的)
+func (r *Repository) ListTrash(kind, status string, page, pageSize int) ([]model.TrashEntry, int64, error) {
+	var list []model.TrashEntry
+	var total int64
+	query := db.DB.Model(&model.TrashEntry{})
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if status == "" {
+		status = model.TrashStatusTrashed
+	}
+	if status != "all" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("id desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&list).Error
+	return list, total, err
+}
+
+// =========================================================
+// 🌐 接口
+// =========================================================
+
+// ListTrash 回收站
+// GET /admin/trash?kind=bank|category|question&status=trashed|restored|purged|all&page=1
+func (h *Handler) ListTrash(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	list, total, err := h.repo.ListTrash(c.Query("kind"), c.Query("status"), page, 20)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取回收站失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list, "total": total, "page": page})
+}
+
+// RestoreTrash 从回收站恢复
+// POST /admin/trash/:id/restore
+func (h *Handler) RestoreTrash(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	operatorID := c.MustGet("userID").(uint)
+	entry, err := h.repo.RestoreTrash(uint(id), operatorID)
+	var conflict *RestoreConflictError
+	if errors.As(err, &conflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "conflicts": conflict.Conflicts})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	service.WriteAudit(operatorID, "trash.restore", entry.Kind, entry.ID, int(entry.Impact.Users), "恢复: "+entry.Title)
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("已恢复「%s」：%d 道题目及其作答、笔记、收藏", entry.Title, entry.Impact.Questions), "data": entry})
+}
+
+// PurgeTrash 立即彻底清除 (不等保留期)
+// DELETE /admin/trash/:id
+func (h *Handler) PurgeTrash(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var entry model.TrashEntry
+	if err := db.DB.First(&entry, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "回收站条目不存在"})
+		return
+	}
+	if entry.Status != model.TrashStatusTrashed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该条目已恢复或已彻底清除"})
+		return
+	}
+	if err := service.PurgeTrashEntry(&entry); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "清除失败"})
+		return
+	}
+	service.WriteAudit(c.MustGet("userID").(uint), "trash.purge", entry.Kind, entry.ID, int(entry.Impact.Users), "彻底清除: "+entry.Title)
+	c.JSON(http.StatusOK, gin.H{"message": "已彻底清除，无法再恢复"})
+}
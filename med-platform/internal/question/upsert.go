@@ -0,0 +1,434 @@
+package question
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"med-platform/internal/common/db"
+
+	"gorm.io/gorm"
+)
+
+// 导入差异类型
+const (
+	DiffAdded     = "added"
+	DiffChanged   = "changed"
+	DiffUnchanged = "unchanged"
+	DiffRemoved   = "removed"
+)
+
+// ImportDiffRow 预检报告中的一行
+type ImportDiffRow struct {
+	Row        int      `json:"row"` // 文件行号 (removed 为 0)
+	Action     string   `json:"action"`
+	QuestionID uint     `json:"question_id,omitempty"`
+	Key        string   `json:"key"`
+	Type       string   `json:"type"`
+	Stem       string   `json:"stem"`            // 题干摘要
+	Group      bool     `json:"group,omitempty"` // 组合题父题 (共用题干/共用选项)
+	Changes    []string `json:"changes,omitempty"`
+}
+
+// ImportSummary 差异汇总
+type ImportSummary struct {
+	Added     int `json:"added"`
+	Changed   int `json:"changed"`
+	Unchanged int `json:"unchanged"`
+	Removed   int `json:"removed"`
+}
+
+// ImportPlan 导入计划：题目树 (已匹配的题目带上已有 ID) + 逐行差异
+type ImportPlan struct {
+	Source  string
+	Roots   []*Question
+	Rows    []ImportDiffRow
+	Removed []ImportDiffRow
+	Summary ImportSummary
+
+	EditorID uint // 发起导入的管理员 (记入版本历史)
+
+	backfill map[uint]string // 老题首次参与匹配时补写的 external_key
+}
+
+// =========================================================
+// 🔑 题目外部键
+// =========================================================
+
+// TypeCode 题型归一 (A1/A2/A3/A4/B1/X)，其余题型原样大写 (判分引擎也按它选择规则)
+func TypeCode(t string) string {
+	t = strings.ToUpper(strings.TrimSpace(t))
+	for _, code := range []string{"A1", "A2", "A3", "A4", "B1"} {
+		if strings.Contains(t, code) {
+			return code
+		}
+	}
+	if strings.Contains(t, "X") {
+		return "X"
+	}
+	return t
+}
+
+func fingerprintKey(parts ...string) string {
+	sum := sha1.Sum([]byte(strings.Join(parts, "|")))
+	return hex.EncodeToString(sum[:])[:24]
+}
+
+// optionsFingerprint B1 共用选项的指纹 (与导入时的归组口径一致，只看 A~E)
+func optionsFingerprint(options []byte) string {
+	var opts map[string]string
+	_ = json.Unmarshal(options, &opts)
+	parts := make([]string, 0, 5)
+	for _, k := range optionKeys[:5] {
+		parts = append(parts, cleanStemForFingerprint(opts[k]))
+	}
+	return strings.Join(parts, "|")
+}
+
+// questionKeys 计算一棵题目树上每个节点的指纹键 (已有显式编号的保留编号)
+// 💡 指纹只取题型 + 清洗后的题干，不含选项和答案：改了答案/解析的题依然能匹配上原题
+// B1 的共用选项本身就是"题干"，所以 B1 组的指纹以选项为准
+func questionKeys(root *Question) {
+	code := TypeCode(root.Type)
+	if len(root.Children) == 0 {
+		if root.ExternalKey == "" {
+			root.ExternalKey = fingerprintKey("Q", code, cleanStemForFingerprint(root.Stem))
+		}
+		return
+	}
+
+	groupFP := cleanStemForFingerprint(root.Stem)
+	if code == "B1" {
+		groupFP = optionsFingerprint(root.Options)
+	}
+	if root.ExternalKey == "" {
+		root.ExternalKey = fingerprintKey("G", code, groupFP)
+	}
+	for i := range root.Children {
+		child := &root.Children[i]
+		if child.ExternalKey == "" {
+			child.ExternalKey = fingerprintKey("C", code, groupFP, cleanStemForFingerprint(child.Stem))
+		}
+	}
+}
+
+// dedupeKeys 同一批题目里指纹撞车 (完全相同的题干) 时，按出现顺序追加 #2、#3 区分
+func dedupeKeys(nodes []*Question, taken map[string]bool) {
+	for _, q := range nodes {
+		base := q.ExternalKey
+		key := base
+		for n := 2; taken[key]; n++ {
+			key = fmt.Sprintf("%s#%d", base, n)
+		}
+		q.ExternalKey = key
+		taken[key] = true
+	}
+}
+
+// flatten 按"父题在前、子题随后"的顺序展开题目树
+func flatten(roots []*Question) []*Question {
+	var nodes []*Question
+	for _, r := range roots {
+		nodes = append(nodes, r)
+		for i := range r.Children {
+			nodes = append(nodes, &r.Children[i])
+		}
+	}
+	return nodes
+}
+
+// =========================================================
+// 📋 生成导入计划 (预检与正式导入共用)
+// =========================================================
+
+// PlanImport 把待导入的题目树与题库现有题目逐一比对，生成新增/修改/未变/移除清单
+func (r *Repository) PlanImport(source string, roots []*Question) (*ImportPlan, error) {
+	plan := &ImportPlan{Source: source, Roots: roots, backfill: make(map[uint]string)}
+
+	// 1. 待导入题目的键
+	for _, root := range roots {
+		questionKeys(root)
+	}
+	dedupeKeys(flatten(roots), make(map[string]bool))
+
+	// 2. 现有题目的键 (老数据没有键的现场补算，正式导入时顺手写回)
+	var existing []*Question
+	if err := db.DB.Where("source = ? AND parent_id IS NULL", source).
+		Preload("Children", func(tx *gorm.DB) *gorm.DB { return tx.Order("id asc") }).
+		Order("id asc").Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	taken := make(map[string]bool)
+	var missing []*Question
+	for _, q := range flatten(existing) {
+		if q.ExternalKey != "" {
+			taken[q.ExternalKey] = true
+		} else {
+			missing = append(missing, q)
+		}
+	}
+	for _, root := range existing {
+		questionKeys(root)
+	}
+	dedupeKeys(missing, taken)
+	for _, q := range missing {
+		plan.backfill[q.ID] = q.ExternalKey
+	}
+
+	index := make(map[string]*Question)
+	parentOf := make(map[uint]uint)
+	for _, root := range existing {
+		index[root.ExternalKey] = root
+		for i := range root.Children {
+			child := &root.Children[i]
+			index[child.ExternalKey] = child
+			parentOf[child.ID] = root.ID
+		}
+	}
+
+	// 3. 逐题匹配
+	used := make(map[uint]bool)
+	for _, root := range roots {
+		var match *Question
+		isGroup := len(root.Children) > 0
+		if e := index[root.ExternalKey]; e != nil && !used[e.ID] && (len(e.Children) > 0) == isGroup {
+			match = e
+		}
+		// 组合题的共用题干被改过时，按子题"投票"找回原来的父题
+		if match == nil && isGroup {
+			votes := make(map[uint]int)
+			for _, child := range root.Children {
+				if e := index[child.ExternalKey]; e != nil && parentOf[e.ID] > 0 {
+					votes[parentOf[e.ID]]++
+				}
+			}
+			best, bestVotes := uint(0), 0
+			for pid, v := range votes {
+				if v > bestVotes || (v == bestVotes && pid < best) {
+					best, bestVotes = pid, v
+				}
+			}
+			for _, e := range existing {
+				if e.ID == best && !used[e.ID] {
+					match = e
+					root.ExternalKey = e.ExternalKey // 沿用原父题的键，避免下次又匹配不上
+				}
+			}
+		}
+
+		if match == nil {
+			plan.addRow(root, nil)
+			for i := range root.Children {
+				plan.addRow(&root.Children[i], nil)
+			}
+			continue
+		}
+
+		used[match.ID] = true
+		root.ID = match.ID
+		plan.addRow(root, match)
+		for i := range root.Children {
+			child := &root.Children[i]
+			if e := index[child.ExternalKey]; e != nil && !used[e.ID] && parentOf[e.ID] == match.ID {
+				used[e.ID] = true
+				child.ID = e.ID
+				plan.addRow(child, e)
+			} else {
+				plan.addRow(child, nil)
+			}
+		}
+	}
+
+	// 4. 文件里涉及的章节中，现有但没被匹配到的题 -> 只报告，不删除 (保留用户作答与笔记)
+	paths := make(map[string]bool)
+	for _, q := range flatten(roots) {
+		paths[q.CategoryPath] = true
+	}
+	for _, q := range flatten(existing) {
+		if !used[q.ID] && paths[q.CategoryPath] {
+			isGroup := len(q.Children) > 0
+			plan.Removed = append(plan.Removed, ImportDiffRow{
+				Action: DiffRemoved, QuestionID: q.ID, Key: q.ExternalKey, Type: q.Type, Stem: stemPreview(q.Stem), Group: isGroup,
+			})
+			if !isGroup {
+				plan.Summary.Removed++
+			}
+		}
+	}
+
+	return plan, nil
+}
+
+// addRow 记录一道题的差异 (old 为 nil 表示新增)
+func (p *ImportPlan) addRow(q *Question, old *Question) {
+	row := ImportDiffRow{Row: q.ImportRow, Key: q.ExternalKey, Type: q.Type, Stem: stemPreview(q.Stem), Group: len(q.Children) > 0}
+	switch {
+	case old == nil:
+		row.Action = DiffAdded
+	default:
+		row.QuestionID = old.ID
+		row.Changes = diffQuestion(old, q)
+		if len(row.Changes) > 0 {
+			row.Action = DiffChanged
+		} else {
+			row.Action = DiffUnchanged
+		}
+	}
+	p.Rows = append(p.Rows, row)
+
+	// 汇总只数小题 (组合题父题不单独作答)，与正式导入返回的数量口径一致
+	if row.Group {
+		return
+	}
+	switch row.Action {
+	case DiffAdded:
+		p.Summary.Added++
+	case DiffChanged:
+		p.Summary.Changed++
+	default:
+		p.Summary.Unchanged++
+	}
+}
+
+// diffQuestion 列出字段变化 (长文本只提示"已修改"，短字段给出新旧值)
+func diffQuestion(old, q *Question) []string {
+	var changes []string
+	short := func(label, a, b string) {
+		if a != b {
+			changes = append(changes, fmt.Sprintf("%s: %s → %s", label, a, b))
+		}
+	}
+	long := func(label, a, b string) {
+		if a != b {
+			changes = append(changes, label+"已修改")
+		}
+	}
+
+	short("题型", old.Type, q.Type)
+	long("题干", old.Stem, q.Stem)
+	if !sameOptions(old.Options, q.Options) {
+		changes = append(changes, "选项已修改")
+	}
+	short("分类", old.CategoryPath, q.CategoryPath)
+
+	// 组合题父题只有题干/选项/分类
+	if len(q.Children) > 0 {
+		return changes
+	}
+	short("答案", old.Correct, q.Correct)
+	long("解析", old.Analysis, q.Analysis)
+	short("难度", old.Difficulty, q.Difficulty)
+	short("难度系数", fmt.Sprintf("%.2f", old.DiffValue), fmt.Sprintf("%.2f", q.DiffValue))
+	short("考纲", old.Syllabus, q.Syllabus)
+	short("认知层次", old.CognitiveLevel, q.CognitiveLevel)
+	return changes
+}
+
+func sameOptions(a, b []byte) bool {
+	var ma, mb map[string]string
+	_ = json.Unmarshal(a, &ma)
+	_ = json.Unmarshal(b, &mb)
+	if len(ma) != len(mb) {
+		return false
+	}
+	for k, v := range ma {
+		if mb[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func stemPreview(stem string) string {
+	r := []rune(strings.ReplaceAll(stem, "\n", " "))
+	if len(r) > 40 {
+		return string(r[:40]) + "..."
+	}
+	return string(r)
+}
+
+// =========================================================
+// 💾 执行导入计划
+// =========================================================
+
+// ApplyImport 在一个事务里执行导入计划：已有题目原地更新 (ID 不变，作答/笔记/收藏全部保留)，新题插入
+// 返回 新增、更新 的小题数
+func (r *Repository) ApplyImport(plan *ImportPlan) (added int, updated int, err error) {
+	roots := append([]*Question(nil), plan.Roots...)
+	sort.SliceStable(roots, func(i, j int) bool { return getTypeWeight(roots[i].Type) < getTypeWeight(roots[j].Type) })
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		for id, key := range plan.backfill {
+			if err := tx.Model(&Question{}).Where("id = ?", id).Update("external_key", key).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, root := range roots {
+			if root.ID == 0 {
+				if err := tx.Create(root).Error; err != nil {
+					return err
+				}
+				if len(root.Children) > 0 {
+					added += len(root.Children)
+				} else {
+					added++
+				}
+				continue
+			}
+
+			changed, err := reviseImported(tx, root, plan.EditorID)
+			if err != nil {
+				return err
+			}
+			if len(root.Children) == 0 {
+				if changed {
+					updated++
+				}
+				continue
+			}
+			for i := range root.Children {
+				child := &root.Children[i]
+				if child.ID == 0 {
+					child.ParentID = &root.ID
+					if err := tx.Create(child).Error; err != nil {
+						return err
+					}
+					added++
+					continue
+				}
+				changed, err := reviseImported(tx, child, plan.EditorID)
+				if err != nil {
+					return err
+				}
+				if changed {
+					updated++
+				}
+			}
+		}
+		return applyImportTags(tx, roots)
+	})
+	return added, updated, err
+}
+
+// reviseImported 用导入内容覆盖已有题目：内容有变化才生成新版本，题目编号与所属目录总是回写
+// 导入文件不含附加内容块，沿用题目现有的
+func reviseImported(tx *gorm.DB, q *Question, editorID uint) (bool, error) {
+	var cur Question
+	if err := tx.Select("id", "media", "category_path").First(&cur, q.ID).Error; err != nil {
+		return false, err
+	}
+	next := snapshotOf(q)
+	next.Media = snapshotOf(&cur).Media
+	_, changed, err := reviseQuestion(tx, q.ID, next, editorID, RevisionImport, "")
+	if err != nil {
+		return false, err
+	}
+	moved := cur.CategoryPath != q.CategoryPath
+	return changed || moved, tx.Model(&Question{}).Where("id = ?", q.ID).Updates(map[string]interface{}{
+		"external_key": q.ExternalKey, "category": q.Category, "category_path": q.CategoryPath,
+	}).Error
+}
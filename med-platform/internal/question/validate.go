@@ -0,0 +1,348 @@
+package question
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/xuri/excelize/v2"
+)
+
+// 校验问题级别
+const (
+	IssueError   = "error"   // 错误：该行无法导入
+	IssueWarning = "warning" // 警告：可以导入，但建议修正
+)
+
+// ImportReportDir 带批注的校验报告存放目录 (位于临时目录下，24 小时后由后台清理任务删除)
+const ImportReportDir = "./uploads/temp/import-reports"
+
+// ImportIssue 校验发现的一个问题
+type ImportIssue struct {
+	Row     int    `json:"row"`
+	Level   string `json:"level"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ImportReport 校验报告
+type ImportReport struct {
+	Errors       int           `json:"errors"`
+	Warnings     int           `json:"warnings"`
+	Issues       []ImportIssue `json:"issues"`
+	AnnotatedURL string        `json:"annotated_url,omitempty"` // 带"校验结果"列的 Excel 副本下载地址
+}
+
+func newImportReport(issues []ImportIssue) *ImportReport {
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Row < issues[j].Row })
+	report := &ImportReport{Issues: issues}
+	for _, is := range issues {
+		if is.Level == IssueError {
+			report.Errors++
+		} else {
+			report.Warnings++
+		}
+	}
+	if report.Issues == nil {
+		report.Issues = []ImportIssue{}
+	}
+	return report
+}
+
+// validateRows 逐行校验导入内容 (不访问数据库、不下载图片)
+func validateRows(rows []ImportRow) []ImportIssue {
+	var issues []ImportIssue
+	add := func(row int, level, field, format string, args ...interface{}) {
+		issues = append(issues, ImportIssue{Row: row, Level: level, Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+	keys := make(map[string]int)
+
+	for _, r := range rows {
+		// 1. 基本结构
+		if r.Columns > 0 && r.Columns < 4 {
+			add(r.Row, IssueError, "整行", "列数不足，至少需要 分类路径 / 题型 / 题干 三列")
+			continue
+		}
+		if r.Stem == "" {
+			add(r.Row, IssueError, "题干", "题干为空")
+		}
+
+		// 2. 分类路径：必须以 " > " 分隔，且不能有空层级
+		validateCategoryPath(r, add)
+
+		// 3. 题型
+		if r.Type == "" {
+			add(r.Row, IssueError, "题型", "题型为空")
+			continue
+		}
+		// 4. 答案与选项 (主观题、未识别的题型只要求有答案，不校验字母格式)
+		switch {
+		case isSubjectiveType(r.Type):
+			if r.Correct == "" {
+				add(r.Row, IssueWarning, "答案", "主观题缺少参考答案")
+			}
+		case getTypeWeight(r.Type) == 999:
+			add(r.Row, IssueWarning, "题型", "无法识别的题型「%s」，将按独立单题导入", r.Type)
+			if r.Correct == "" {
+				add(r.Row, IssueWarning, "答案", "缺少答案")
+			}
+		default:
+			validateObjectiveKey(r, add)
+		}
+
+		// 5. 难度系数
+		if r.DiffValue != "" {
+			if v, err := strconv.ParseFloat(r.DiffValue, 64); err != nil || v <= 0 || v > 1 {
+				add(r.Row, IssueWarning, "难度系数", "难度系数「%s」应为 0~1 之间的小数，将按 0.5 处理", r.DiffValue)
+			}
+		}
+
+		// 6. 题目编号
+		if r.ExternalKey != "" {
+			if len(r.ExternalKey) > 64 {
+				add(r.Row, IssueError, "题目编号", "题目编号过长 (最多 64 个字符)")
+			} else if first, ok := keys[r.ExternalKey]; ok {
+				add(r.Row, IssueWarning, "题目编号", "题目编号与第 %d 行重复，将自动追加序号区分", first)
+			} else {
+				keys[r.ExternalKey] = r.Row
+			}
+		}
+	}
+	return issues
+}
+
+func validateCategoryPath(r ImportRow, add func(int, string, string, string, ...interface{})) {
+	path := r.Category
+	switch {
+	case path == "":
+		add(r.Row, IssueError, "分类路径", "分类路径为空")
+		return
+	case len(path) > 255:
+		add(r.Row, IssueError, "分类路径", "分类路径过长 (最多 255 个字节)")
+		return
+	case strings.Contains(path, "【"):
+		add(r.Row, IssueError, "分类路径", "分类路径不能包含「【」，疑似把题干填到了分类列")
+		return
+	}
+	for _, part := range strings.Split(path, " > ") {
+		if strings.TrimSpace(part) == "" {
+			add(r.Row, IssueError, "分类路径", "分类路径「%s」存在空层级", path)
+			return
+		}
+		if strings.Contains(part, ">") {
+			add(r.Row, IssueWarning, "分类路径", "分类路径「%s」的分隔符应为「 > 」(两侧带空格)，否则会被当成同一层目录", path)
+			return
+		}
+	}
+}
+
+func validateObjectiveKey(r ImportRow, add func(int, string, string, string, ...interface{})) {
+	code := TypeCode(r.Type)
+
+	hasOption := make(map[string]bool)
+	for k, v := range r.Options {
+		if v != "" {
+			hasOption[optionKeys[k]] = true
+		}
+	}
+	switch {
+	case len(hasOption) == 0 && code == "B1":
+		add(r.Row, IssueError, "选项", "B1 题缺少共用备选答案 (每一行都需要重复填写父题的 A~E 选项)")
+	case len(hasOption) == 0:
+		add(r.Row, IssueError, "选项", "客观题缺少选项")
+	case len(hasOption) == 1:
+		add(r.Row, IssueWarning, "选项", "只有 1 个选项")
+	}
+
+	key := strings.ToUpper(strings.TrimSpace(r.Correct))
+	if key == "" {
+		add(r.Row, IssueError, "答案", "缺少正确答案")
+		return
+	}
+	for _, ch := range key {
+		if ch < 'A' || ch > 'F' {
+			add(r.Row, IssueError, "答案", "答案「%s」格式不正确，只能填写 A~F 的字母 (多选题连写，如 ABD)", r.Correct)
+			return
+		}
+	}
+	for _, ch := range key {
+		if len(hasOption) > 0 && !hasOption[string(ch)] {
+			add(r.Row, IssueError, "答案", "答案 %c 不在选项中", ch)
+		}
+	}
+
+	switch {
+	case code == "X" && len(key) < 2:
+		add(r.Row, IssueError, "答案", "X 型题 (多选题) 的答案应至少包含两个选项，当前为「%s」", key)
+	case code != "X" && len(key) > 1:
+		add(r.Row, IssueWarning, "答案", "%s 型题通常只有一个正确答案，当前为「%s」", code, key)
+	}
+}
+
+// imageWorkers 批量导入时同时下载图片的行数上限 (避免把图床或本机带宽打满)
+const imageWorkers = 8
+
+// imageCache 单次导入内的图片下载缓存：B1 每行都重复父题选项，同一张图只下载一次
+type imageCache struct {
+	mu    sync.Mutex
+	items map[string]*cachedImage
+}
+
+type cachedImage struct {
+	once     sync.Once
+	localURL string
+	err      error
+}
+
+func newImageCache() *imageCache {
+	return &imageCache{items: make(map[string]*cachedImage)}
+}
+
+func (c *imageCache) download(remoteURL string) (string, error) {
+	c.mu.Lock()
+	item, ok := c.items[remoteURL]
+	if !ok {
+		item = &cachedImage{}
+		c.items[remoteURL] = item
+	}
+	c.mu.Unlock()
+
+	item.once.Do(func() { item.localURL, item.err = downloadAndSaveImage(remoteURL) })
+	return item.localURL, item.err
+}
+
+// localizeRowImages 把导入行里的外链图片转存到本地，转存失败的记为警告 (保留原链接)
+// 按行并发 (最多 imageWorkers 行同时下载)，每处理完一行回调一次 onRowDone，用于汇报进度
+func localizeRowImages(rows []ImportRow, onRowDone func()) []ImportIssue {
+	cache := newImageCache()
+	perRow := make([][]ImportIssue, len(rows))
+	sem := make(chan struct{}, imageWorkers)
+	var wg sync.WaitGroup
+
+	for i := range rows {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+				if onRowDone != nil {
+					onRowDone()
+				}
+			}()
+			perRow[i] = localizeRow(&rows[i], cache)
+		}(i)
+	}
+	wg.Wait()
+
+	var issues []ImportIssue
+	for _, list := range perRow {
+		issues = append(issues, list...)
+	}
+	return issues
+}
+
+func localizeRow(r *ImportRow, cache *imageCache) []ImportIssue {
+	type field struct {
+		name string
+		val  *string
+	}
+	fields := []field{{"题干", &r.Stem}, {"解析", &r.Analysis}}
+	for k := range r.Options {
+		fields = append(fields, field{"选项" + optionKeys[k], &r.Options[k]})
+	}
+	if isSubjectiveType(r.Type) {
+		fields = append(fields, field{"答案", &r.Correct})
+	}
+
+	var issues []ImportIssue
+	for _, fd := range fields {
+		content, failed := processContentImagesWith(*fd.val, cache.download)
+		*fd.val = content
+		for _, u := range failed {
+			issues = append(issues, ImportIssue{Row: r.Row, Level: IssueWarning, Field: fd.name, Message: "图片转存失败，已保留原链接: " + u})
+		}
+	}
+	return issues
+}
+
+// dropErrorRows 去掉有错误的行 (只导入校验通过的部分)
+func dropErrorRows(rows []ImportRow, issues []ImportIssue) []ImportRow {
+	bad := make(map[int]bool)
+	for _, is := range issues {
+		if is.Level == IssueError {
+			bad[is.Row] = true
+		}
+	}
+	kept := make([]ImportRow, 0, len(rows))
+	for _, r := range rows {
+		if !bad[r.Row] {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// saveAnnotatedCopy 在原文件末尾追加"校验结果"列并保存副本，返回下载地址
+// 错误行标红、警告行标黄，管理员改完可以直接删掉这一列重新上传
+func saveAnnotatedCopy(f *excelize.File, issues []ImportIssue) (string, error) {
+	sheet := f.GetSheetName(0)
+	rows, err := f.GetRows(sheet)
+	if err != nil {
+		return "", err
+	}
+	width := len(importHeader)
+	if len(rows) > 0 && len(rows[0]) > width {
+		width = len(rows[0])
+	}
+	col, _ := excelize.ColumnNumberToName(width + 1)
+
+	errStyle, _ := f.NewStyle(&excelize.Style{
+		Fill:      excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FFC7CE"}},
+		Font:      &excelize.Font{Color: "9C0006"},
+		Alignment: &excelize.Alignment{WrapText: true, Vertical: "top"},
+	})
+	warnStyle, _ := f.NewStyle(&excelize.Style{
+		Fill:      excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FFEB9C"}},
+		Font:      &excelize.Font{Color: "9C5700"},
+		Alignment: &excelize.Alignment{WrapText: true, Vertical: "top"},
+	})
+
+	byRow := make(map[int][]ImportIssue)
+	for _, is := range issues {
+		byRow[is.Row] = append(byRow[is.Row], is)
+	}
+
+	_ = f.SetCellValue(sheet, col+"1", "校验结果")
+	_ = f.SetColWidth(sheet, col, col, 60)
+	for row, list := range byRow {
+		var lines []string
+		style := warnStyle
+		for _, is := range list {
+			tag := "⚠️"
+			if is.Level == IssueError {
+				tag = "❌"
+				style = errStyle
+			}
+			lines = append(lines, fmt.Sprintf("%s [%s] %s", tag, is.Field, is.Message))
+		}
+		cell := fmt.Sprintf("%s%d", col, row)
+		_ = f.SetCellValue(sheet, cell, strings.Join(lines, "\n"))
+		_ = f.SetCellStyle(sheet, cell, cell, style)
+	}
+
+	if err := os.MkdirAll(ImportReportDir, 0755); err != nil {
+		return "", err
+	}
+	fileName := fmt.Sprintf("%d_%s.xlsx", time.Now().Unix(), uuid.New().String())
+	if err := f.SaveAs(filepath.Join(ImportReportDir, fileName)); err != nil {
+		return "", err
+	}
+	return "/uploads/temp/import-reports/" + fileName, nil
+}
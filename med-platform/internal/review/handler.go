@@ -0,0 +1,80 @@
+package review
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaxForecastDays 到期预测最多看多少天
+const MaxForecastDays = 90
+
+type Handler struct {
+	repo *Repository
+}
+
+func NewHandler() *Handler {
+	return &Handler{repo: NewRepository()}
+}
+
+// GetQueue 今日复习队列
+// GET /review/queue?source=&category=&page=1&page_size=20
+// 错题本里的题会先自动补进队列，再按到期时间从早到晚返回
+func (h *Handler) GetQueue(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	source := c.Query("source")
+	category := c.Query("category")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	if err := h.repo.SyncMistakes(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "同步错题失败"})
+		return
+	}
+
+	list, total, err := h.repo.GetDueQueue(userID, source, category, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取复习队列失败"})
+		return
+	}
+
+	summary, err := h.repo.GetSummary(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取复习概况失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": list, "total": total, "page": page, "summary": summary})
+}
+
+// GetForecast 未来每日到期量预测
+// GET /review/forecast?days=30&source=&category=
+func (h *Handler) GetForecast(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if days <= 0 {
+		days = 30
+	}
+	if days > MaxForecastDays {
+		days = MaxForecastDays
+	}
+
+	if err := h.repo.SyncMistakes(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "同步错题失败"})
+		return
+	}
+
+	data, err := h.repo.GetForecast(userID, c.Query("source"), c.Query("category"), days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取复习预测失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
@@ -0,0 +1,41 @@
+package review
+
+import (
+	"time"
+)
+
+// ReviewState 用户-题目记忆状态 (间隔重复调度的核心表)
+// 每次作答都会按 SM-2 算法更新易度因子、复习间隔与下次到期时间，
+// 错题本中的题目会自动进入复习队列，连续答对若干次后"毕业"移出错题本。
+type ReviewState struct {
+	ID uint `gorm:"primarykey" json:"id"`
+
+	// 联合唯一索引：一个用户对一道题只有一份记忆状态
+	UserID     uint `gorm:"index:idx_review_user_question,unique;not null" json:"user_id"`
+	QuestionID uint `gorm:"index:idx_review_user_question,unique;not null" json:"question_id"`
+
+	EaseFactor   float64 `gorm:"type:decimal(4,2);default:2.5" json:"ease_factor"` // 易度因子 (SM-2 的 EF，最低 1.3)
+	IntervalDays int     `gorm:"default:0" json:"interval_days"`                   // 当前复习间隔 (天)
+	Repetitions  int     `gorm:"default:0" json:"repetitions"`                     // 连续答对次数，答错清零
+	Lapses       int     `gorm:"default:0" json:"lapses"`                          // 累计遗忘 (答错) 次数
+
+	DueAt          time.Time  `gorm:"index" json:"due_at"` // 下次到期复习时间
+	LastReviewedAt *time.Time `json:"last_reviewed_at"`
+
+	FromMistake bool `gorm:"default:false;index" json:"from_mistake"` // 是否来自错题本
+	Graduated   bool `gorm:"default:false" json:"graduated"`          // 是否已从错题本毕业
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (ReviewState) TableName() string {
+	return "review_states"
+}
+
+// Outcome 一次作答结果 (由 answer 包在写入作答流水时传入，避免反向依赖 answer 包)
+type Outcome struct {
+	UserID     uint
+	QuestionID uint
+	IsCorrect  bool
+}
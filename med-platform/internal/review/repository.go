@@ -0,0 +1,214 @@
+package review
+
+import (
+	"time"
+
+	"med-platform/internal/common/db"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct{}
+
+func NewRepository() *Repository {
+	return &Repository{}
+}
+
+// ApplyOutcomes 根据一批作答结果推进记忆状态 (必须在作答流水的同一个事务里调用)
+// 💡 answer 包的 BatchCreateOrUpdate 会调用它，因此普通刷题与模考交卷都会驱动复习调度
+// 答错的题会被标记为"来自错题本"；错题在到期复习时连续答对达到毕业条件后，自动从错题本移除
+func ApplyOutcomes(tx *gorm.DB, outcomes []Outcome) error {
+	if len(outcomes) == 0 {
+		return nil
+	}
+	now := time.Now()
+
+	for _, o := range outcomes {
+		var state ReviewState
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND question_id = ?", o.UserID, o.QuestionID).
+			First(&state).Error
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+		if err == gorm.ErrRecordNotFound {
+			state = ReviewState{UserID: o.UserID, QuestionID: o.QuestionID, EaseFactor: DefaultEase}
+		}
+
+		Schedule(&state, o.IsCorrect, now)
+		if !o.IsCorrect {
+			// 答错会写入错题本，重新进入"待毕业"流程
+			state.FromMistake = true
+			state.Graduated = false
+		}
+
+		if ShouldGraduate(&state) {
+			if err := tx.Table("user_mistakes").
+				Where("user_id = ? AND question_id = ?", o.UserID, o.QuestionID).
+				Delete(nil).Error; err != nil {
+				return err
+			}
+			state.FromMistake = false
+			state.Graduated = true
+		}
+
+		if err := tx.Save(&state).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SyncMistakes 把错题本中尚未建立记忆状态的题目补进复习队列 (立即到期)
+// 兼容调度上线前的历史错题，以及通过其他途径写入错题本的题目
+func (r *Repository) SyncMistakes(userID uint) error {
+	now := time.Now()
+	if err := db.DB.Exec(`
+		INSERT INTO review_states (user_id, question_id, ease_factor, interval_days, repetitions, lapses, due_at, from_mistake, graduated, created_at, updated_at)
+		SELECT m.user_id, m.question_id, ?, 0, 0, m.wrong_count, ?, true, false, ?, ?
+		FROM user_mistakes m
+		WHERE m.user_id = ?
+		ON CONFLICT (user_id, question_id) DO NOTHING
+	`, DefaultEase, now, now, now, userID).Error; err != nil {
+		return err
+	}
+
+	// 已有记忆状态但还未标记为错题的 (例如先答对后又在别处答错)，补上标记
+	return db.DB.Model(&ReviewState{}).
+		Where("user_id = ? AND from_mistake = ?", userID, false).
+		Where("question_id IN (?)", db.DB.Table("user_mistakes").Select("question_id").Where("user_id = ?", userID)).
+		Updates(map[string]interface{}{"from_mistake": true, "graduated": false}).Error
+}
+
+// QueueItem 复习队列条目
+type QueueItem struct {
+	QuestionID   uint       `json:"question_id"`
+	RootID       uint       `json:"root_id"` // 组合题的父题 ID (前端按大题渲染)
+	Type         string     `json:"type"`
+	Stem         string     `json:"stem"`
+	Source       string     `json:"source"`
+	CategoryPath string     `json:"category_path"`
+	DueAt        time.Time  `json:"due_at"`
+	IntervalDays int        `json:"interval_days"`
+	Repetitions  int        `json:"repetitions"`
+	Lapses       int        `json:"lapses"`
+	EaseFactor   float64    `json:"ease_factor"`
+	FromMistake  bool       `json:"from_mistake"`
+	LastReviewed *time.Time `json:"last_reviewed_at" gorm:"column:last_reviewed_at"`
+}
+
+// baseDueQuery 到期复习的公共查询 (过滤已删除题目 + 题库/章节范围)
+func (r *Repository) baseDueQuery(userID uint, source, categoryPath string) *gorm.DB {
+	query := db.DB.Table("review_states AS rs").
+		Joins("JOIN questions q ON q.id = rs.question_id AND q.deleted_at IS NULL").
+		Where("rs.user_id = ?", userID)
+
+	if source != "" {
+		query = query.Where("q.source = ?", source)
+	}
+	if categoryPath != "" {
+		query = query.Where("q.category_path LIKE ?", categoryPath+"%")
+	}
+	return query
+}
+
+// GetDueQueue 获取截至今日结束仍到期的复习题 (最早到期的排最前，分页)
+func (r *Repository) GetDueQueue(userID uint, source, categoryPath string, page, pageSize int) ([]QueueItem, int64, error) {
+	var items []QueueItem
+	var total int64
+
+	query := r.baseDueQuery(userID, source, categoryPath).
+		Where("rs.due_at <= ?", endOfDay(time.Now()))
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Select(`rs.question_id,
+			CASE WHEN q.parent_id IS NOT NULL AND q.parent_id > 0 THEN q.parent_id ELSE q.id END AS root_id,
+			q.type, q.stem, q.source, q.category_path,
+			rs.due_at, rs.interval_days, rs.repetitions, rs.lapses, rs.ease_factor, rs.from_mistake, rs.last_reviewed_at`).
+		Order("rs.due_at ASC, rs.question_id ASC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Scan(&items).Error
+
+	return items, total, err
+}
+
+// ForecastDay 每日到期量预测
+type ForecastDay struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+// GetForecast 未来 N 天每天到期的复习题数量 (已逾期的计入今天)
+func (r *Repository) GetForecast(userID uint, source, categoryPath string, days int) ([]ForecastDay, error) {
+	type row struct {
+		DateStr string
+		Count   int
+	}
+	var rows []row
+
+	now := time.Now()
+	today := now.Format("2006-01-02")
+	lastDay := endOfDay(now.AddDate(0, 0, days-1))
+
+	err := r.baseDueQuery(userID, source, categoryPath).
+		Where("rs.due_at <= ?", lastDay).
+		Select("GREATEST(TO_CHAR(rs.due_at, 'YYYY-MM-DD'), ?) AS date_str, COUNT(*) AS count", today).
+		Group("date_str").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	countMap := make(map[string]int, len(rows))
+	for _, rw := range rows {
+		countMap[rw.DateStr] = rw.Count
+	}
+
+	// 补齐没有到期题目的日子，前端可以直接画柱状图
+	result := make([]ForecastDay, 0, days)
+	for i := 0; i < days; i++ {
+		d := now.AddDate(0, 0, i).Format("2006-01-02")
+		result = append(result, ForecastDay{Date: d, Count: countMap[d]})
+	}
+	return result, nil
+}
+
+// GetSummary 复习概况：今日到期 / 已逾期 / 错题待毕业 / 累计毕业
+func (r *Repository) GetSummary(userID uint) (map[string]int64, error) {
+	now := time.Now()
+	y, m, d := now.Date()
+	startOfToday := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+
+	var dueToday, overdue, pendingMistakes, graduated int64
+	if err := r.baseDueQuery(userID, "", "").Where("rs.due_at <= ?", endOfDay(now)).Count(&dueToday).Error; err != nil {
+		return nil, err
+	}
+	if err := r.baseDueQuery(userID, "", "").Where("rs.due_at < ?", startOfToday).Count(&overdue).Error; err != nil {
+		return nil, err
+	}
+	if err := db.DB.Model(&ReviewState{}).Where("user_id = ? AND from_mistake = ?", userID, true).Count(&pendingMistakes).Error; err != nil {
+		return nil, err
+	}
+	if err := db.DB.Model(&ReviewState{}).Where("user_id = ? AND graduated = ?", userID, true).Count(&graduated).Error; err != nil {
+		return nil, err
+	}
+
+	return map[string]int64{
+		"due_today":        dueToday,
+		"overdue":          overdue,
+		"pending_mistakes": pendingMistakes,
+		"graduated":        graduated,
+	}, nil
+}
+
+// ReleaseMistakes 用户手动移出错题本时，同步取消对应题目的"错题"标记
+// 记忆状态本身保留，题目仍会按间隔正常到期复习
+func (r *Repository) ReleaseMistakes(userID, rootID uint) error {
+	return db.DB.Model(&ReviewState{}).
+		Where("user_id = ? AND question_id IN (SELECT id FROM questions WHERE id = ? OR parent_id = ?)", userID, rootID, rootID).
+		Update("from_mistake", false).Error
+}
@@ -0,0 +1,89 @@
+package review
+
+import (
+	"math"
+	"time"
+)
+
+// SM-2 调度参数
+const (
+	DefaultEase    = 2.5
+	MinEase        = 1.3
+	MaxIntervalDay = 365 // 间隔上限：一年至少见一次
+
+	// 错题毕业条件：连续答对 N 次，且间隔已拉长到 M 天以上
+	GraduateStreak      = 3
+	GraduateMinInterval = 6
+
+	qualityCorrect = 4 // 答对：对应 SM-2 的 "正确但有迟疑"
+	qualityWrong   = 1 // 答错：对应 SM-2 的 "错误，看答案后想起"
+)
+
+// Schedule 按 SM-2 算法根据一次作答结果推进记忆状态
+// 我们只有对/错两档信号，因此把答对映射为 q=4、答错映射为 q=1：
+//   - 答错：连续次数清零，间隔重置为 1 天，易度 -0.54 (不低于 1.3)
+//   - 答对：第 1 次间隔 1 天，第 2 次 3 天，之后按 间隔 × 易度 递增，易度不变
+//
+// 未到期 (到期日在今天之后) 的答对只记录作答时间，不推进连续次数与间隔，
+// 避免同一轮里连刷几遍就让错题"毕业"
+func Schedule(s *ReviewState, correct bool, now time.Time) {
+	if s.EaseFactor == 0 {
+		s.EaseFactor = DefaultEase
+	}
+	if correct && s.DueAt.After(endOfDay(now)) {
+		reviewedAt := now
+		s.LastReviewedAt = &reviewedAt
+		return
+	}
+
+	q := qualityWrong
+	if correct {
+		q = qualityCorrect
+	}
+
+	if !correct {
+		s.Repetitions = 0
+		s.Lapses++
+		s.IntervalDays = 1
+	} else {
+		s.Repetitions++
+		switch s.Repetitions {
+		case 1:
+			s.IntervalDays = 1
+		case 2:
+			s.IntervalDays = 3
+		default:
+			s.IntervalDays = int(math.Round(float64(s.IntervalDays) * s.EaseFactor))
+		}
+	}
+
+	// EF' = EF + (0.1 - (5-q) × (0.08 + (5-q) × 0.02))
+	diff := float64(5 - q)
+	s.EaseFactor += 0.1 - diff*(0.08+diff*0.02)
+	if s.EaseFactor < MinEase {
+		s.EaseFactor = MinEase
+	}
+	s.EaseFactor = math.Round(s.EaseFactor*100) / 100
+
+	if s.IntervalDays > MaxIntervalDay {
+		s.IntervalDays = MaxIntervalDay
+	}
+	if s.IntervalDays < 1 {
+		s.IntervalDays = 1
+	}
+
+	reviewedAt := now
+	s.LastReviewedAt = &reviewedAt
+	s.DueAt = now.AddDate(0, 0, s.IntervalDays)
+}
+
+// ShouldGraduate 错题是否达到毕业条件
+func ShouldGraduate(s *ReviewState) bool {
+	return s.FromMistake && s.Repetitions >= GraduateStreak && s.IntervalDays >= GraduateMinInterval
+}
+
+// endOfDay 当天 23:59:59 (用于"今日到期"口径)
+func endOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 23, 59, 59, 0, t.Location())
+}
@@ -0,0 +1,81 @@
+package review
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.Local)
+	tests := []struct {
+		name     string
+		state    ReviewState
+		correct  bool
+		interval int
+		reps     int
+		lapses   int
+		ease     float64
+		due      time.Time
+	}{
+		{"新题答对", ReviewState{}, true, 1, 1, 0, 2.5, now.AddDate(0, 0, 1)},
+		{"第二次答对", ReviewState{EaseFactor: 2.5, Repetitions: 1, IntervalDays: 1, DueAt: now}, true, 3, 2, 0, 2.5, now.AddDate(0, 0, 3)},
+		{"之后按易度递增", ReviewState{EaseFactor: 2.5, Repetitions: 2, IntervalDays: 3, DueAt: now}, true, 8, 3, 0, 2.5, now.AddDate(0, 0, 8)},
+		{"答错重置", ReviewState{EaseFactor: 2.5, Repetitions: 3, IntervalDays: 8, DueAt: now}, false, 1, 0, 1, 1.96, now.AddDate(0, 0, 1)},
+		{"易度不低于下限", ReviewState{EaseFactor: 1.5, Repetitions: 1, IntervalDays: 1, DueAt: now}, false, 1, 0, 1, MinEase, now.AddDate(0, 0, 1)},
+		{"间隔不超过上限", ReviewState{EaseFactor: 2.5, Repetitions: 5, IntervalDays: 300, DueAt: now}, true, MaxIntervalDay, 6, 0, 2.5, now.AddDate(0, 0, MaxIntervalDay)},
+		{"今天到期也算", ReviewState{EaseFactor: 2.5, Repetitions: 1, IntervalDays: 1, DueAt: endOfDay(now)}, true, 3, 2, 0, 2.5, now.AddDate(0, 0, 3)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tt.state
+			Schedule(&s, tt.correct, now)
+			if s.IntervalDays != tt.interval || s.Repetitions != tt.reps || s.Lapses != tt.lapses || s.EaseFactor != tt.ease {
+				t.Errorf("got interval=%d reps=%d lapses=%d ease=%v, want %d %d %d %v",
+					s.IntervalDays, s.Repetitions, s.Lapses, s.EaseFactor, tt.interval, tt.reps, tt.lapses, tt.ease)
+			}
+			if !s.DueAt.Equal(tt.due) {
+				t.Errorf("due = %v, want %v", s.DueAt, tt.due)
+			}
+			if s.LastReviewedAt == nil || !s.LastReviewedAt.Equal(now) {
+				t.Errorf("last reviewed = %v, want %v", s.LastReviewedAt, now)
+			}
+		})
+	}
+}
+
+func TestScheduleNotDue(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.Local)
+	due := now.AddDate(0, 0, 3)
+	s := ReviewState{EaseFactor: 2.5, Repetitions: 2, IntervalDays: 3, DueAt: due}
+	Schedule(&s, true, now)
+	if s.Repetitions != 2 || s.IntervalDays != 3 || !s.DueAt.Equal(due) {
+		t.Errorf("未到期的答对不应推进: reps=%d interval=%d due=%v", s.Repetitions, s.IntervalDays, s.DueAt)
+	}
+	if s.LastReviewedAt == nil || !s.LastReviewedAt.Equal(now) {
+		t.Errorf("last reviewed = %v, want %v", s.LastReviewedAt, now)
+	}
+
+	// 未到期答错照样重置
+	Schedule(&s, false, now)
+	if s.Repetitions != 0 || s.IntervalDays != 1 || s.Lapses != 1 {
+		t.Errorf("答错应重置: reps=%d interval=%d lapses=%d", s.Repetitions, s.IntervalDays, s.Lapses)
+	}
+}
+
+func TestShouldGraduate(t *testing.T) {
+	tests := []struct {
+		name  string
+		state ReviewState
+		want  bool
+	}{
+		{"达标", ReviewState{FromMistake: true, Repetitions: GraduateStreak, IntervalDays: GraduateMinInterval}, true},
+		{"不是错题", ReviewState{Repetitions: GraduateStreak, IntervalDays: GraduateMinInterval}, false},
+		{"连对次数不够", ReviewState{FromMistake: true, Repetitions: GraduateStreak - 1, IntervalDays: GraduateMinInterval}, false},
+		{"间隔不够", ReviewState{FromMistake: true, Repetitions: GraduateStreak, IntervalDays: GraduateMinInterval - 1}, false},
+	}
+	for _, tt := range tests {
+		if got := ShouldGraduate(&tt.state); got != tt.want {
+			t.Errorf("%s: ShouldGraduate = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
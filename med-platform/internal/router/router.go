@@ -5,12 +5,20 @@ import (
 	"med-platform/internal/common/captcha"
 	"med-platform/internal/common/middleware"
 	"med-platform/internal/common/service"
+	"med-platform/internal/exam"
 	"med-platform/internal/feedback"
 	"med-platform/internal/forum"
+	"med-platform/internal/itemstat"
+	"med-platform/internal/leaderboard"
 	"med-platform/internal/note"
 	"med-platform/internal/payment"
+	"med-platform/internal/practice"
 	"med-platform/internal/product"
 	"med-platform/internal/question"
+	"med-platform/internal/review"
+	"med-platform/internal/search"
+	"med-platform/internal/studygroup"
+	"med-platform/internal/studyplan"
 	"med-platform/internal/sysconfig"
 	"med-platform/internal/user"
 
@@ -29,6 +37,14 @@ type RouteManager struct {
 	feedback  *feedback.Handler
 	forum     *forum.Handler
 	sysconfig *sysconfig.Handler
+	exam      *exam.Handler
+	review    *review.Handler
+	practice  *practice.Handler
+	itemstat  *itemstat.Handler
+	search    *search.Handler
+	ranking   *leaderboard.Handler
+	studyplan *studyplan.Handler
+	group     *studygroup.Handler
 
 	// Limiters (限流器)
 	commentLimiter *middleware.IPRateLimiter
@@ -57,6 +73,14 @@ func SetupRouter() *gin.Engine {
 		feedback:  feedback.NewHandler(),
 		forum:     forum.NewHandler(),
 		sysconfig: sysconfig.NewHandler(),
+		exam:      exam.NewHandler(),
+		review:    review.NewHandler(),
+		practice:  practice.NewHandler(),
+		itemstat:  itemstat.NewHandler(),
+		search:    search.NewHandler(),
+		ranking:   leaderboard.NewHandler(),
+		studyplan: studyplan.NewHandler(),
+		group:     studygroup.NewHandler(),
 
 		// 针对不同场景的限流策略
 		commentLimiter: middleware.NewIPRateLimiter(1, 3), // 发言：1秒3次
@@ -102,6 +126,10 @@ func (m *RouteManager) registerAuthRoutes(parent *gin.RouterGroup) {
 	m.registerForumRoutes(userGroup)
 	m.registerUserCenterRoutes(userGroup)
 	m.registerQuestionRoutes(userGroup)
+	m.registerExamRoutes(userGroup)
+	m.registerReviewRoutes(userGroup)
+	m.registerPracticeRoutes(userGroup)
+	m.registerSearchRoutes(userGroup)
 	m.registerNoteRoutes(userGroup)
 	m.registerCommerceRoutes(userGroup)
 
@@ -158,8 +186,14 @@ func (m *RouteManager) registerQuestionRoutes(g *gin.RouterGroup) {
 	g.GET("/questions/skeleton", m.question.GetChapterSkeleton)
 	g.GET("/questions", m.question.List)
 	g.GET("/questions/:id", m.question.GetDetail)
+	g.GET("/questions/:id/content", m.question.GetContent)
+	g.GET("/questions/:id/tags", m.question.GetQuestionTags)
+	g.GET("/knowledge-tags", m.question.GetKnowledgeTree)
+	g.GET("/knowledge-tags/mastery", m.question.GetKnowledgeMastery)
+	g.GET("/knowledge-tags/:id/questions", m.question.PracticeByTag)
 	g.GET("/banks", m.question.GetSources)
 	g.POST("/questions/:id/submit", m.answer.Submit)
+	g.POST("/questions/:id/self-rate", m.answer.SelfRate)
 	g.POST("/feedback", m.question.SubmitFeedback)
 
 	// 错题/收藏/统计
@@ -169,7 +203,35 @@ func (m *RouteManager) registerQuestionRoutes(g *gin.RouterGroup) {
 	g.DELETE("/mistakes/:id", m.answer.RemoveMistake)
 	g.GET("/mistake-tree", m.answer.GetMistakeTree)
 	g.GET("/stats", m.answer.GetStats)
+	g.GET("/stats/analytics", m.answer.GetAnalytics)
 	g.GET("/rank/daily", m.answer.GetDailyRank)
+	g.GET("/leaderboard", m.ranking.Get)
+
+	// 备考计划
+	g.GET("/study-plan", m.studyplan.GetPlan)
+	g.POST("/study-plan", m.studyplan.CreatePlan)
+	g.PUT("/study-plan/:id", m.studyplan.UpdatePlan)
+	g.DELETE("/study-plan/:id", m.studyplan.DeletePlan)
+	g.GET("/study-plan/:id/days", m.studyplan.ListDays)
+
+	// 学习小组
+	g.POST("/groups", m.group.CreateGroup)
+	g.GET("/groups", m.group.ListMyGroups)
+	g.POST("/groups/join", m.group.JoinGroup)
+	g.GET("/groups/:id", m.group.GetGroup)
+	g.DELETE("/groups/:id", m.group.DissolveGroup)
+	g.POST("/groups/:id/leave", m.group.LeaveGroup)
+	g.POST("/groups/:id/invite-code", m.group.ResetInviteCode)
+	g.PUT("/groups/:id/members/:uid", m.group.UpdateMemberRole)
+	g.DELETE("/groups/:id/members/:uid", m.group.RemoveMember)
+	g.GET("/groups/:id/dashboard", m.group.GetDashboard)
+	g.GET("/groups/:id/challenges", m.group.ListChallenges)
+	g.POST("/groups/:id/challenges", m.group.CreateChallenge)
+	g.DELETE("/groups/:id/challenges/:cid", m.group.DeleteChallenge)
+	g.GET("/groups/:id/assignments", m.group.ListAssignments)
+	g.POST("/groups/:id/assignments", m.group.CreateAssignment)
+	g.GET("/groups/:id/assignments/:aid", m.group.GetAssignment)
+	g.DELETE("/groups/:id/assignments/:aid", m.group.DeleteAssignment)
 	g.POST("/favorites/:id", m.answer.ToggleFavorite)
 	g.GET("/favorites", m.answer.GetFavorites)
 	g.GET("/favorite-tree", m.answer.GetFavoriteTree)
@@ -179,6 +241,45 @@ func (m *RouteManager) registerQuestionRoutes(g *gin.RouterGroup) {
 	g.DELETE("/answers/reset-chapter", m.answer.ResetChapter)
 }
 
+// ⏱️ 模考模块 (限时组卷、交卷判分)
+func (m *RouteManager) registerExamRoutes(g *gin.RouterGroup) {
+	g.POST("/exams", m.exam.CreateExam)
+	g.GET("/exams", m.exam.ListMyExams)
+	g.GET("/exams/:id", m.exam.GetExam)
+	g.PUT("/exams/:id/answers", m.exam.SaveAnswers)
+	g.POST("/exams/:id/submit", m.exam.SubmitExam)
+	g.GET("/exams/:id/report", m.exam.GetReport)
+}
+
+// 🔁 间隔复习模块 (SM-2 到期队列、每日预测)
+func (m *RouteManager) registerReviewRoutes(g *gin.RouterGroup) {
+	g.GET("/review/queue", m.review.GetQueue)
+	g.GET("/review/forecast", m.review.GetForecast)
+}
+
+// 🎯 智能练习模块 (按薄弱点自适应出题)
+func (m *RouteManager) registerPracticeRoutes(g *gin.RouterGroup) {
+	g.GET("/practice/smart", m.practice.NextBatch)
+	g.GET("/practice/mastery", m.practice.GetMastery)
+
+	// 🧩 自组练习 (按条件组卷，可保存 / 重复练 / 分享)
+	g.POST("/practice/sets/preview", m.practice.PreviewSet)
+	g.GET("/practice/sets", m.practice.ListSets)
+	g.POST("/practice/sets", m.practice.CreateSet)
+	g.PUT("/practice/sets/:id", m.practice.UpdateSet)
+	g.DELETE("/practice/sets/:id", m.practice.DeleteSet)
+	g.GET("/practice/sets/:id/run", m.practice.RunSet)
+	g.POST("/practice/sets/:id/share", m.practice.ShareSet)
+	g.GET("/practice/sets/shared/:code", m.practice.GetSharedSet)
+	g.GET("/practice/sets/shared/:code/run", m.practice.RunSharedSet)
+	g.POST("/practice/sets/shared/:code/copy", m.practice.CopySharedSet)
+}
+
+// 🔎 全文搜索模块 (题目 + 公开笔记，按授权过滤)
+func (m *RouteManager) registerSearchRoutes(g *gin.RouterGroup) {
+	g.GET("/search", m.search.Search)
+}
+
 // 📝 笔记模块
 func (m *RouteManager) registerNoteRoutes(g *gin.RouterGroup) {
 	limit := middleware.RateLimitMiddleware(m.commentLimiter)
@@ -246,6 +347,8 @@ func (m *RouteManager) registerAdminRoutes(parent *gin.RouterGroup) {
 		staffGroup.POST("/notes/:id/ignore", m.note.AdminDismissReport)
 		staffGroup.GET("/feedbacks", m.question.AdminListFeedbacks)
 		staffGroup.PUT("/feedbacks/:id", m.question.AdminResolveFeedback)
+		staffGroup.GET("/item-stats", m.itemstat.AdminList)
+		staffGroup.GET("/item-stats/:id", m.itemstat.AdminGet)
 		staffGroup.GET("/platform-feedbacks", m.feedback.AdminList)
 		staffGroup.PUT("/platform-feedbacks/:id", m.feedback.AdminReply)
 
@@ -256,6 +359,7 @@ func (m *RouteManager) registerAdminRoutes(parent *gin.RouterGroup) {
 			superGroup.GET("/configs", m.sysconfig.ListConfigs)
 			superGroup.POST("/configs", m.sysconfig.SaveConfig)
 			superGroup.POST("/configs/test-email", m.sysconfig.SendTestEmail)
+			superGroup.GET("/audit-logs", m.sysconfig.ListAuditLogs)
 			// 🔥 新增：邮件营销/群发后台
 			superGroup.GET("/emails/users", m.sysconfig.ListEmailUsers)
 			superGroup.POST("/emails/send", m.sysconfig.SendCustomMail)
@@ -285,11 +389,34 @@ func (m *RouteManager) registerAdminRoutes(parent *gin.RouterGroup) {
 			superGroup.POST("/categories/sync", m.question.SyncCategories)
 			superGroup.PUT("/categories/:id", m.question.UpdateCategory)
 			superGroup.POST("/categories/reorder", m.question.ReorderCategories)
+			superGroup.POST("/categories/merge", m.question.MergeCategories)
+			superGroup.POST("/categories/:id/move", m.question.MoveCategory)
+			superGroup.POST("/categories/:id/split", m.question.SplitCategory)
 			superGroup.POST("/questions/import", m.question.ImportQuestions)
+			superGroup.GET("/questions/export", m.question.ExportQuestions)
+			superGroup.GET("/import-jobs", m.question.ListImportJobs)
+			superGroup.GET("/import-jobs/:id", m.question.GetImportJob)
 			superGroup.PUT("/questions/:id", m.question.UpdateQuestion)
+			superGroup.GET("/questions/:id/revisions", m.question.ListRevisions)
+			superGroup.GET("/questions/:id/revisions/:rev/diff", m.question.GetRevisionDiff)
+			superGroup.POST("/questions/:id/revisions/:rev/rollback", m.question.RollbackRevision)
+			superGroup.GET("/regrade-jobs", m.question.ListRegradeJobs)
+			superGroup.GET("/dedup/clusters", m.question.ListDuplicates)
+			superGroup.POST("/dedup/merge", m.question.MergeDuplicates)
+			superGroup.POST("/knowledge-tags", m.question.CreateKnowledgeTags)
+			superGroup.PUT("/knowledge-tags/:id", m.question.UpdateKnowledgeTag)
+			superGroup.DELETE("/knowledge-tags/:id", m.question.DeleteKnowledgeTag)
+			superGroup.POST("/questions/tags", m.question.BatchTagQuestions)
+			superGroup.GET("/trash", m.question.ListTrash)
+			superGroup.POST("/trash/:id/restore", m.question.RestoreTrash)
+			superGroup.DELETE("/trash/:id", m.question.PurgeTrash)
+			superGroup.POST("/questions/media/upload", middleware.RateLimitMiddleware(m.uploadLimiter), m.question.UploadMedia)
+			superGroup.PUT("/questions/:id/media", m.question.UpdateMedia)
 			superGroup.DELETE("/questions/:id", m.question.DeleteQuestion)
 			superGroup.POST("/questions/batch-delete", m.question.BatchDeleteQuestions)
 			superGroup.DELETE("/questions/by-category", m.question.DeleteByCategory)
+			superGroup.POST("/item-stats/recompute", m.itemstat.AdminRecompute)
+			superGroup.POST("/search/rebuild", m.search.AdminRebuild)
 
 			// 论坛板块
 			superGroup.POST("/forum/boards", m.forum.CreateBoard)
@@ -0,0 +1,83 @@
+package search
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"med-platform/internal/product"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	repo *Repository
+}
+
+func NewHandler() *Handler {
+	return &Handler{repo: NewRepository()}
+}
+
+// Search 全文搜索题目与公开笔记
+// GET /search?q=缺铁性贫血&source=&category=&type=&scope=question|note&page=1
+// 💡 普通用户只能搜到已授权科目下的内容，分面统计同样只统计可见部分
+func (h *Handler) Search(c *gin.Context) {
+	text := strings.TrimSpace(c.Query("q"))
+	tsq := BuildTSQuery(text)
+	if tsq == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请输入搜索关键词"})
+		return
+	}
+	if len([]rune(text)) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "关键词过长"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 50 {
+		pageSize = 20
+	}
+
+	q := Query{
+		Text: text, TSQuery: tsq,
+		Source: c.Query("source"), Root: c.Query("category"), Type: c.Query("type"),
+		Page: page, PageSize: pageSize,
+	}
+	switch c.Query("scope") {
+	case DocQuestion, DocNote:
+		q.DocType = c.Query("scope")
+	}
+
+	role, _ := c.Get("role")
+	if role == "admin" || role == "agent" {
+		q.Unrestricted = true
+	} else {
+		entitled, err := product.NewRepository().ListEntitlements(c.MustGet("userID").(uint))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "读取授权信息失败"})
+			return
+		}
+		q.Entitled = entitled
+	}
+
+	hits, total, facets, err := h.repo.Search(q)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "搜索失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": hits, "total": total, "page": page, "facets": facets})
+}
+
+// AdminRebuild 清空并重建全文索引 (后台执行)
+// POST /admin/search/rebuild
+func (h *Handler) AdminRebuild(c *gin.Context) {
+	if !Rebuild() {
+		c.JSON(http.StatusConflict, gin.H{"error": "索引同步正在进行中，请稍后再试"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已开始重建全文索引，完成前搜索结果可能不完整"})
+}
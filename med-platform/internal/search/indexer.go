@@ -0,0 +1,311 @@
+package search
+
+import (
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"med-platform/internal/common/db"
+	"med-platform/internal/common/logger"
+
+	"go.uber.org/zap"
+)
+
+// IndexInterval 增量同步频率 (新题、改题、新笔记最多延迟这么久可被搜到)
+const IndexInterval = 5 * time.Minute
+
+// indexBatchSize 每批同步的行数
+const indexBatchSize = 500
+
+// syncOverlap 每轮从水位线往前多读的时长：updated_at 取自事务开始时刻，
+// 比上一轮晚提交、时间却更早的改动只能靠这段重叠补上 (重复写入是幂等的)
+const syncOverlap = 10 * time.Minute
+
+// running 防止定时同步与后台手动重建同时跑
+var running int32
+
+// StartIndexTask 启动全文索引同步守护任务 (首次运行时全量建索引)
+// 请在 main.go 中调用: search.StartIndexTask()
+func StartIndexTask() {
+	go func() {
+		time.Sleep(30 * time.Second)
+		RunSync()
+
+		ticker := time.NewTicker(IndexInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			RunSync()
+		}
+	}()
+}
+
+// RunSync 增量同步一轮；已有任务在跑时直接返回 false
+func RunSync() bool {
+	if !atomic.CompareAndSwapInt32(&running, 0, 1) {
+		return false
+	}
+	defer atomic.StoreInt32(&running, 0)
+	syncAll()
+	return true
+}
+
+// Rebuild 清空索引后全量重建 (分词规则调整后使用)
+func Rebuild() bool {
+	if !atomic.CompareAndSwapInt32(&running, 0, 1) {
+		return false
+	}
+	go func() {
+		defer atomic.StoreInt32(&running, 0)
+		if err := db.DB.Exec("TRUNCATE TABLE search_documents").Error; err != nil {
+			logger.Log.Error("清空全文索引失败", zap.Error(err))
+			return
+		}
+		syncAll()
+	}()
+	return true
+}
+
+// IsRunning 当前是否有同步任务在跑
+func IsRunning() bool {
+	return atomic.LoadInt32(&running) == 1
+}
+
+func syncAll() {
+	start := time.Now()
+	questions, err := syncQuestions()
+	if err != nil {
+		logger.Log.Error("全文索引：同步题目失败", zap.Error(err))
+	}
+	notes, err := syncNotes()
+	if err != nil {
+		logger.Log.Error("全文索引：同步笔记失败", zap.Error(err))
+	}
+	removed, err := purgeStale()
+	if err != nil {
+		logger.Log.Error("全文索引：清理失效文档失败", zap.Error(err))
+	}
+	if questions+notes > 0 || removed > 0 {
+		logger.Log.Info("🔎 全文索引同步完成",
+			zap.Int("题目", questions), zap.Int("笔记", notes), zap.Int64("清理", removed),
+			zap.Duration("耗时", time.Since(start)))
+	}
+}
+
+// watermark 某类文档的增量同步起点：已同步到的业务表 updated_at 减去 syncOverlap
+func watermark(docType string) time.Time {
+	var t *time.Time
+	db.DB.Model(&SearchDocument{}).Where("doc_type = ?", docType).Select("MAX(source_updated_at)").Scan(&t)
+	if t == nil {
+		return time.Time{}
+	}
+	return t.Add(-syncOverlap)
+}
+
+// rootOf 一级科目 (与导入时 Question.Category 的取法一致)
+func rootOf(categoryPath string) string {
+	return strings.TrimSpace(strings.Split(categoryPath, ">")[0])
+}
+
+// =========================================================
+// 📚 题目
+// =========================================================
+
+type questionRow struct {
+	ID           uint
+	Source       string
+	CategoryPath string
+	Type         string
+	Stem         string
+	Options      []byte
+	Analysis     string
+	UpdatedAt    time.Time
+	Deleted      bool
+}
+
+// syncQuestions 按 (updated_at, id) 游标增量同步题目；软删除的题目顺带移出索引
+func syncQuestions() (int, error) {
+	lastTime, lastID := watermark(DocQuestion), uint(0)
+	synced := 0
+	for {
+		var rows []questionRow
+		err := db.DB.Raw(`
+			SELECT id, source, category_path, type, stem, options, analysis, updated_at, deleted_at IS NOT NULL AS deleted
+			FROM questions
+			WHERE updated_at > @t OR (updated_at = @t AND id > @id)
+			ORDER BY updated_at, id
+			LIMIT @limit
+		`, map[string]interface{}{"t": lastTime, "id": lastID, "limit": indexBatchSize}).Scan(&rows).Error
+		if err != nil || len(rows) == 0 {
+			return synced, err
+		}
+
+		var docs []SearchDocument
+		var deleted []uint
+		for _, q := range rows {
+			if q.Deleted {
+				deleted = append(deleted, q.ID)
+				continue
+			}
+			docs = append(docs, questionDocument(q))
+		}
+		if err := upsertDocuments(docs); err != nil {
+			return synced, err
+		}
+		if len(deleted) > 0 {
+			if err := db.DB.Where("doc_type = ? AND doc_id IN ?", DocQuestion, deleted).Delete(&SearchDocument{}).Error; err != nil {
+				return synced, err
+			}
+		}
+		synced += len(rows)
+		last := rows[len(rows)-1]
+		lastTime, lastID = last.UpdatedAt, last.ID
+	}
+}
+
+func questionDocument(q questionRow) SearchDocument {
+	var opts map[string]string
+	_ = json.Unmarshal(q.Options, &opts)
+	var optTexts []string
+	for _, k := range []string{"A", "B", "C", "D", "E", "F"} {
+		if v := opts[k]; v != "" {
+			optTexts = append(optTexts, v)
+		}
+	}
+	optionText := strings.Join(optTexts, " ")
+
+	return SearchDocument{
+		DocType: DocQuestion, DocID: q.ID, QuestionID: q.ID,
+		Source: q.Source, RootCategory: rootOf(q.CategoryPath), CategoryPath: q.CategoryPath, Type: q.Type,
+		Content: PlainText(q.Stem + " " + optionText + " " + q.Analysis),
+		TSV: buildTSVector(
+			weightedText{q.Stem, 'A'},
+			weightedText{optionText, 'B'},
+			weightedText{q.Analysis, 'C'},
+		),
+		SourceUpdatedAt: q.UpdatedAt,
+	}
+}
+
+// =========================================================
+// 📝 笔记 (只收录公开的主楼笔记，回复不进索引)
+// =========================================================
+
+type noteRow struct {
+	ID           uint
+	QuestionID   uint
+	Content      string
+	UpdatedAt    time.Time
+	Visible      bool
+	Source       string
+	CategoryPath string
+	Type         string
+}
+
+func syncNotes() (int, error) {
+	lastTime, lastID := watermark(DocNote), uint(0)
+	synced := 0
+	for {
+		var rows []noteRow
+		err := db.DB.Raw(`
+			SELECT n.id, n.question_id, n.content, n.updated_at,
+				(n.deleted_at IS NULL AND n.is_public AND n.parent_id IS NULL AND q.id IS NOT NULL) AS visible,
+				COALESCE(q.source, '') AS source, COALESCE(q.category_path, '') AS category_path, COALESCE(q.type, '') AS type
+			FROM notes n
+			LEFT JOIN questions q ON q.id = n.question_id AND q.deleted_at IS NULL
+			WHERE n.updated_at > @t OR (n.updated_at = @t AND n.id > @id)
+			ORDER BY n.updated_at, n.id
+			LIMIT @limit
+		`, map[string]interface{}{"t": lastTime, "id": lastID, "limit": indexBatchSize}).Scan(&rows).Error
+		if err != nil || len(rows) == 0 {
+			return synced, err
+		}
+
+		var docs []SearchDocument
+		var hidden []uint
+		for _, n := range rows {
+			if !n.Visible || strings.TrimSpace(n.Content) == "" {
+				hidden = append(hidden, n.ID)
+				continue
+			}
+			docs = append(docs, SearchDocument{
+				DocType: DocNote, DocID: n.ID, QuestionID: n.QuestionID,
+				Source: n.Source, RootCategory: rootOf(n.CategoryPath), CategoryPath: n.CategoryPath, Type: n.Type,
+				Content:         PlainText(n.Content),
+				TSV:             buildTSVector(weightedText{n.Content, 'D'}),
+				SourceUpdatedAt: n.UpdatedAt,
+			})
+		}
+		if err := upsertDocuments(docs); err != nil {
+			return synced, err
+		}
+		if len(hidden) > 0 {
+			if err := db.DB.Where("doc_type = ? AND doc_id IN ?", DocNote, hidden).Delete(&SearchDocument{}).Error; err != nil {
+				return synced, err
+			}
+		}
+		synced += len(rows)
+		last := rows[len(rows)-1]
+		lastTime, lastID = last.UpdatedAt, last.ID
+	}
+}
+
+// upsertDocuments 批量写入 (tsvector 字面量需显式转换，走原生 SQL)
+func upsertDocuments(docs []SearchDocument) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	var sb strings.Builder
+	sb.WriteString(`INSERT INTO search_documents
+		(doc_type, doc_id, question_id, source, root_category, category_path, type, content, tsv, source_updated_at, updated_at) VALUES `)
+	args := make([]interface{}, 0, len(docs)*10)
+	now := time.Now()
+	for i, d := range docs {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString("(?, ?, ?, ?, ?, ?, ?, ?, ?::tsvector, ?, ?)")
+		args = append(args, d.DocType, d.DocID, d.QuestionID, d.Source, d.RootCategory, d.CategoryPath, d.Type, d.Content, d.TSV, d.SourceUpdatedAt, now)
+	}
+	sb.WriteString(` ON CONFLICT (doc_type, doc_id) DO UPDATE SET
+		question_id = EXCLUDED.question_id, source = EXCLUDED.source, root_category = EXCLUDED.root_category,
+		category_path = EXCLUDED.category_path, type = EXCLUDED.type, content = EXCLUDED.content, tsv = EXCLUDED.tsv,
+		source_updated_at = EXCLUDED.source_updated_at, updated_at = EXCLUDED.updated_at`)
+	return db.DB.Exec(sb.String(), args...).Error
+}
+
+// purgeStale 清理失效文档，并让笔记跟随题目的归属变化
+// 💡 软删除不会更新 updated_at，增量游标发现不了，需要单独对账
+func purgeStale() (int64, error) {
+	var removed int64
+	res := db.DB.Exec(`
+		DELETE FROM search_documents d
+		WHERE d.doc_type = ? AND NOT EXISTS (SELECT 1 FROM questions q WHERE q.id = d.doc_id AND q.deleted_at IS NULL)
+	`, DocQuestion)
+	if res.Error != nil {
+		return removed, res.Error
+	}
+	removed += res.RowsAffected
+
+	res = db.DB.Exec(`
+		DELETE FROM search_documents d
+		WHERE d.doc_type = ? AND NOT EXISTS (
+			SELECT 1 FROM notes n JOIN questions q ON q.id = n.question_id AND q.deleted_at IS NULL
+			WHERE n.id = d.doc_id AND n.deleted_at IS NULL AND n.is_public AND n.parent_id IS NULL
+		)
+	`, DocNote)
+	if res.Error != nil {
+		return removed, res.Error
+	}
+	removed += res.RowsAffected
+
+	// 题目改了分类 / 题库后，挂在它下面的笔记文档同步归属 (授权过滤依赖它)
+	err := db.DB.Exec(`
+		UPDATE search_documents d
+		SET source = q.source, category_path = q.category_path, root_category = TRIM(split_part(q.category_path, '>', 1)), type = q.type
+		FROM questions q
+		WHERE d.doc_type = ? AND q.id = d.question_id
+			AND (d.source IS DISTINCT FROM q.source OR d.category_path IS DISTINCT FROM q.category_path OR d.type IS DISTINCT FROM q.type)
+	`, DocNote).Error
+	return removed, err
+}
@@ -0,0 +1,32 @@
+package search
+
+import "time"
+
+// 文档类型
+const (
+	DocQuestion = "question" // 题目 (题干 + 选项 + 解析)
+	DocNote     = "note"     // 公开笔记
+)
+
+// SearchDocument 全文检索索引表 (由 StartIndexTask 从题目、笔记增量同步，不直接写业务表)
+// 💡 中文分词在程序内完成 (见 tokenizer.go)，直接写入 tsvector，不依赖 zhparser 等数据库扩展
+type SearchDocument struct {
+	ID         uint   `gorm:"primaryKey" json:"-"`
+	DocType    string `gorm:"type:varchar(10);uniqueIndex:idx_search_doc" json:"doc_type"`
+	DocID      uint   `gorm:"uniqueIndex:idx_search_doc" json:"doc_id"`
+	QuestionID uint   `gorm:"index" json:"question_id"` // 笔记所属题目 (题目文档即自身)
+
+	// 冗余题目的归属信息：用于授权过滤与分面统计
+	Source       string `gorm:"type:varchar(100);index" json:"source"`
+	RootCategory string `gorm:"type:varchar(100);index" json:"root_category"`
+	CategoryPath string `gorm:"type:varchar(255)" json:"category_path"`
+	Type         string `gorm:"type:varchar(20)" json:"type"`
+
+	Content string `gorm:"type:text" json:"-"`                                   // 纯文本，用于生成摘要高亮
+	TSV     string `gorm:"type:tsvector;index:idx_search_tsv,type:gin" json:"-"` // 分词结果 (只经 SQL 写入)
+
+	SourceUpdatedAt time.Time `gorm:"index" json:"-"` // 业务表的 updated_at，增量同步的水位线
+	UpdatedAt       time.Time `json:"-"`
+}
+
+func (SearchDocument) TableName() string { return "search_documents" }
@@ -0,0 +1,202 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+
+	"med-platform/internal/common/db"
+	"med-platform/internal/product"
+
+	"gorm.io/gorm"
+)
+
+// snippetWidth 摘要长度 (字)
+const snippetWidth = 100
+
+type Repository struct{}
+
+func NewRepository() *Repository {
+	return &Repository{}
+}
+
+// Query 一次搜索的条件
+type Query struct {
+	Text    string
+	TSQuery string
+	Source  string
+	Root    string // 一级科目
+	Type    string
+	DocType string // question / note，空为全部
+
+	Unrestricted bool                  // 管理员 / 代理不受授权限制
+	Entitled     []product.Entitlement // 普通用户已授权的 (题库, 一级科目)
+
+	Page     int
+	PageSize int
+}
+
+// Hit 一条搜索结果
+type Hit struct {
+	DocType      string  `json:"doc_type"`
+	DocID        uint    `json:"doc_id"`
+	QuestionID   uint    `json:"question_id"`
+	Source       string  `json:"source"`
+	CategoryPath string  `json:"category_path"`
+	Type         string  `json:"type"`
+	Content      string  `json:"-"`
+	Snippet      string  `json:"snippet"` // 已转义的 HTML，命中词以 <em> 包裹
+	Score        float64 `json:"score"`
+}
+
+// FacetCount 分面统计的一项
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// Facets 分面统计：每个维度都按"除自身以外的其他筛选条件"统计，方便前端切换
+type Facets struct {
+	Sources    []FacetCount `json:"sources"`
+	Categories []FacetCount `json:"categories"`
+	Types      []FacetCount `json:"types"`
+	DocTypes   []FacetCount `json:"doc_types"`
+}
+
+// scoped 命中 + 授权过滤，再叠加除 except 以外的筛选条件
+func (q Query) scoped(except string) *gorm.DB {
+	tx := db.DB.Table("search_documents AS d").Where("d.tsv @@ ?::tsquery", q.TSQuery)
+	if !q.Unrestricted {
+		pairs := make([][]interface{}, 0, len(q.Entitled))
+		for _, e := range q.Entitled {
+			pairs = append(pairs, []interface{}{e.Source, e.Category})
+		}
+		tx = tx.Where("(d.source, d.root_category) IN ?", pairs)
+	}
+	if q.Source != "" && except != "source" {
+		tx = tx.Where("d.source = ?", q.Source)
+	}
+	if q.Root != "" && except != "category" {
+		tx = tx.Where("d.root_category = ?", q.Root)
+	}
+	if q.Type != "" && except != "type" {
+		tx = tx.Where("d.type = ?", q.Type)
+	}
+	if q.DocType != "" && except != "doc_type" {
+		tx = tx.Where("d.doc_type = ?", q.DocType)
+	}
+	return tx
+}
+
+// Search 按相关度排序的分页结果 + 分面统计
+func (r *Repository) Search(q Query) ([]Hit, int64, *Facets, error) {
+	facets := &Facets{Sources: []FacetCount{}, Categories: []FacetCount{}, Types: []FacetCount{}, DocTypes: []FacetCount{}}
+	hits := []Hit{}
+	if !q.Unrestricted && len(q.Entitled) == 0 {
+		return hits, 0, facets, nil
+	}
+
+	var total int64
+	if err := q.scoped("").Count(&total).Error; err != nil {
+		return nil, 0, nil, err
+	}
+
+	// 题干权重最高，其次选项、解析、笔记
+	err := q.scoped("").
+		Select("d.doc_type, d.doc_id, d.question_id, d.source, d.category_path, d.type, d.content, "+
+			"ts_rank_cd('{0.1, 0.2, 0.4, 1.0}', d.tsv, ?::tsquery) AS score", q.TSQuery).
+		Order("score DESC").Order("d.doc_id ASC").
+		Offset((q.Page - 1) * q.PageSize).Limit(q.PageSize).
+		Scan(&hits).Error
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	terms := queryTerms(q.Text)
+	for i := range hits {
+		hits[i].Snippet = makeSnippet(hits[i].Content, terms)
+	}
+
+	for _, f := range []struct {
+		dim    string
+		column string
+		target *[]FacetCount
+	}{
+		{"source", "d.source", &facets.Sources},
+		{"category", "d.root_category", &facets.Categories},
+		{"type", "d.type", &facets.Types},
+		{"doc_type", "d.doc_type", &facets.DocTypes},
+	} {
+		err := q.scoped(f.dim).
+			Select(f.column + " AS value, COUNT(*) AS count").
+			Group(f.column).Order("count DESC").Limit(50).
+			Scan(f.target).Error
+		if err != nil {
+			return nil, 0, nil, err
+		}
+	}
+	return hits, total, facets, nil
+}
+
+// makeSnippet 截取第一个命中词附近的一段文字，命中词用 <em> 标出 (其余内容做 HTML 转义)
+func makeSnippet(content string, terms []string) string {
+	runes := []rune(content)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	// 标记所有命中位置
+	marked := make([]bool, len(runes))
+	first := -1
+	for _, term := range terms {
+		t := []rune(term)
+		if len(t) == 0 {
+			continue
+		}
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if string(lower[i:i+len(t)]) == term {
+				for k := i; k < i+len(t); k++ {
+					marked[k] = true
+				}
+				if first == -1 || i < first {
+					first = i
+				}
+			}
+		}
+	}
+
+	start := 0
+	if first > snippetWidth/4 {
+		start = first - snippetWidth/4
+	}
+	end := start + snippetWidth
+	if end > len(runes) {
+		end = len(runes)
+		if end-snippetWidth > 0 && end-snippetWidth < start {
+			start = end - snippetWidth
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	inMark := false
+	for i := start; i < end; i++ {
+		if marked[i] && !inMark {
+			b.WriteString("<em>")
+			inMark = true
+		} else if !marked[i] && inMark {
+			b.WriteString("</em>")
+			inMark = false
+		}
+		b.WriteString(html.EscapeString(string(runes[i])))
+	}
+	if inMark {
+		b.WriteString("</em>")
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
@@ -0,0 +1,191 @@
+package search
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 中文分词采用"二元切分"：连续汉字按相邻两字切成词元 (红细胞 -> 红细 / 细胞)
+// 建索引与查询用同一套规则，查询时同一串汉字的词元要求位置相邻 (<->)，效果接近短语匹配，
+// 不需要维护医学词典，也不会因为分词器漏词而搜不到
+const (
+	maxTokenLen = 32    // 过长的英文 / 数字串截断
+	maxPosition = 16383 // tsvector 位置上限
+	maxPerToken = 256   // 单个词元最多记录的位置数
+)
+
+var (
+	htmlTagRe  = regexp.MustCompile(`<[^>]*>`)
+	mdImageRe  = regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)`)
+	customImRe = regexp.MustCompile(`\[图片[:：]?[^\]]*\]`)
+	urlRe      = regexp.MustCompile(`https?://\S+`)
+	spaceRe    = regexp.MustCompile(`\s+`)
+)
+
+// PlainText 去掉 HTML 标签、图片与链接，只留可检索的文字
+func PlainText(s string) string {
+	s = mdImageRe.ReplaceAllString(s, " ")
+	s = customImRe.ReplaceAllString(s, " ")
+	s = htmlTagRe.ReplaceAllString(s, " ")
+	s = html.UnescapeString(s)
+	s = urlRe.ReplaceAllString(s, " ")
+	return strings.TrimSpace(spaceRe.ReplaceAllString(s, " "))
+}
+
+// segment 切分出的一段连续文字：汉字串或英文 / 数字串
+type segment struct {
+	text string
+	cjk  bool
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r)
+}
+
+func segments(text string) []segment {
+	var result []segment
+	var buf strings.Builder
+	cjk := false
+	flush := func() {
+		if buf.Len() > 0 {
+			result = append(result, segment{text: buf.String(), cjk: cjk})
+			buf.Reset()
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case isCJK(r):
+			if !cjk {
+				flush()
+			}
+			cjk = true
+			buf.WriteRune(r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if cjk {
+				flush()
+			}
+			cjk = false
+			buf.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return result
+}
+
+// tokens 一段文字的词元 (汉字二元切分，单个汉字保留单字)
+func (s segment) tokens() []string {
+	if !s.cjk {
+		if utf8.RuneCountInString(s.text) > maxTokenLen {
+			return []string{string([]rune(s.text)[:maxTokenLen])}
+		}
+		return []string{s.text}
+	}
+	runes := []rune(s.text)
+	if len(runes) == 1 {
+		return []string{s.text}
+	}
+	out := make([]string, 0, len(runes)-1)
+	for i := 0; i+1 < len(runes); i++ {
+		out = append(out, string(runes[i:i+2]))
+	}
+	return out
+}
+
+// weightedText 一个带权重的字段 (A 最高：题干；B 选项；C 解析；D 笔记)
+type weightedText struct {
+	text   string
+	weight byte
+}
+
+// buildTSVector 生成 tsvector 字面量 ('红细':1A '细胞':2A ...)
+// 汉字在二元词元之外，同一位置再记一个单字，单字查询 (如"铁") 也能命中
+// 💡 直接拼字面量再 ::tsvector，绕过数据库的文本解析器，避免不同 locale 下汉字被拆掉
+func buildTSVector(fields ...weightedText) string {
+	positions := make(map[string][]string)
+	var order []string
+	add := func(tok string, pos int, weight byte) {
+		if pos > maxPosition {
+			pos = maxPosition
+		}
+		if _, ok := positions[tok]; !ok {
+			order = append(order, tok)
+		}
+		if len(positions[tok]) >= maxPerToken {
+			return
+		}
+		positions[tok] = append(positions[tok], strconv.Itoa(pos)+string(weight))
+	}
+
+	pos := 1
+	for _, f := range fields {
+		for _, seg := range segments(PlainText(f.text)) {
+			if pos > maxPosition {
+				break
+			}
+			if !seg.cjk {
+				add(seg.tokens()[0], pos, f.weight)
+				pos += 2 // 词与词之间留空位，避免跨标点误判相邻
+				continue
+			}
+			runes := []rune(seg.text)
+			for i := range runes {
+				add(string(runes[i]), pos+i, f.weight)
+				if i+1 < len(runes) {
+					add(string(runes[i:i+2]), pos+i, f.weight)
+				}
+			}
+			pos += len(runes) + 1
+		}
+		pos += 10 // 字段之间拉开距离
+	}
+
+	var b strings.Builder
+	for i, tok := range order {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(quoteLexeme(tok))
+		b.WriteByte(':')
+		b.WriteString(strings.Join(positions[tok], ","))
+	}
+	return b.String()
+}
+
+// BuildTSQuery 把用户输入转成 tsquery 字面量；没有可检索的字时返回空串
+// 同一串汉字的词元用 <-> (相邻)，不同词之间用 & (都要出现)
+func BuildTSQuery(input string) string {
+	var parts []string
+	for _, seg := range segments(input) {
+		toks := seg.tokens()
+		quoted := make([]string, len(toks))
+		for i, t := range toks {
+			quoted[i] = quoteLexeme(t)
+		}
+		if len(quoted) == 1 {
+			parts = append(parts, quoted[0])
+		} else {
+			parts = append(parts, "("+strings.Join(quoted, " <-> ")+")")
+		}
+	}
+	return strings.Join(parts, " & ")
+}
+
+// queryTerms 用于摘要高亮的原始词 (汉字串整体高亮，英文不区分大小写)
+func queryTerms(input string) []string {
+	var terms []string
+	for _, seg := range segments(input) {
+		terms = append(terms, seg.text)
+	}
+	return terms
+}
+
+func quoteLexeme(tok string) string {
+	tok = strings.ReplaceAll(tok, `\`, `\\`)
+	return "'" + strings.ReplaceAll(tok, "'", "''") + "'"
+}
@@ -0,0 +1,92 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlainText(t *testing.T) {
+	tests := map[string]string{
+		"<p>缺铁性<b>贫血</b></p>":             "缺铁性 贫血",
+		"见图![示意](/uploads/a.png)所示":       "见图 所示",
+		"见[图片:ecg.png]  心电图":              "见 心电图",
+		"详见 https://example.com/a?b=1 说明": "详见 说明",
+		"Hb &lt; 110g/L":                  "Hb < 110g/L",
+	}
+	for in, want := range tests {
+		if got := PlainText(in); got != want {
+			t.Errorf("PlainText(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSegmentTokens(t *testing.T) {
+	tests := []struct {
+		in   string
+		want [][]string
+	}{
+		{"红细胞", [][]string{{"红细", "细胞"}}},
+		{"铁", [][]string{{"铁"}}},
+		{"MCV降低", [][]string{{"mcv"}, {"降低"}}},
+		{"维生素B12缺乏", [][]string{{"维生", "生素"}, {"b12"}, {"缺乏"}}},
+		{"贫血，乏力", [][]string{{"贫血"}, {"乏力"}}},
+		{strings.Repeat("a", 40), [][]string{{strings.Repeat("a", maxTokenLen)}}},
+		{"！？", nil},
+	}
+	for _, tt := range tests {
+		var got [][]string
+		for _, seg := range segments(tt.in) {
+			got = append(got, seg.tokens())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tokens(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestBuildTSVector(t *testing.T) {
+	got := buildTSVector(weightedText{"红细胞", 'A'}, weightedText{"Hb", 'C'})
+	want := "'红':1A '红细':1A '细':2A '细胞':2A '胞':3A 'hb':15C"
+	if got != want {
+		t.Errorf("buildTSVector = %q, want %q", got, want)
+	}
+
+	// 英文词之间留一个空位，跨标点不算相邻
+	got = buildTSVector(weightedText{"ST, T", 'B'})
+	want = "'st':1B 't':3B"
+	if got != want {
+		t.Errorf("buildTSVector = %q, want %q", got, want)
+	}
+}
+
+func TestBuildTSQuery(t *testing.T) {
+	tests := map[string]string{
+		"":          "",
+		"！？":        "",
+		"铁":         "'铁'",
+		"红细胞":       "('红细' <-> '细胞')",
+		"红细胞 MCV":   "('红细' <-> '细胞') & 'mcv'",
+		"it's":      "'it' & 's'",
+		"缺铁性贫血 铁蛋白": "('缺铁' <-> '铁性' <-> '性贫' <-> '贫血') & ('铁蛋' <-> '蛋白')",
+	}
+	for in, want := range tests {
+		if got := BuildTSQuery(in); got != want {
+			t.Errorf("BuildTSQuery(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestQuoteLexeme(t *testing.T) {
+	tests := map[string]string{
+		"mcv":  "'mcv'",
+		"a'b":  "'a''b'",
+		`a\b`:  `'a\\b'`,
+		`o'\x`: `'o''\\x'`,
+	}
+	for in, want := range tests {
+		if got := quoteLexeme(in); got != want {
+			t.Errorf("quoteLexeme(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package studygroup
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"med-platform/internal/common/db"
+	"med-platform/internal/common/service"
+	"med-platform/internal/question"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =========================================================
+// 🏁 小组挑战：进度按作答历史实时统计，有人做题就推给在线的组员
+// =========================================================
+
+// pushThrottle 同一个挑战两次推送的最小间隔 (多人同时刷题时合并推送)
+const pushThrottle = 3 * time.Second
+
+var (
+	pushMu   sync.Mutex
+	lastPush = map[uint]time.Time{}
+	trailing = map[uint]bool{} // 已排好补推的挑战
+)
+
+// challengeProgress 挑战区间内各成员的贡献 (作答历史按题库 / 章节过滤)
+func challengeProgress(ch *GroupChallenge) []MemberProgress {
+	list := []MemberProgress{}
+	counter := "COUNT(h.id)"
+	if ch.Metric == ChallengeCorrect {
+		counter = "COUNT(h.id) FILTER (WHERE h.is_correct)"
+	}
+	filter := "h.user_id = m.user_id AND h.created_at >= @start AND h.created_at < @end"
+	args := map[string]interface{}{"group": ch.GroupID, "start": ch.StartAt, "end": ch.EndAt}
+	if ch.Source != "" || ch.CategoryPath != "" {
+		filter += " AND h.question_id IN (SELECT id FROM questions WHERE TRUE"
+		if ch.Source != "" {
+			filter += " AND source = @source"
+			args["source"] = ch.Source
+		}
+		if ch.CategoryPath != "" {
+			filter += " AND (category_path = @path OR category_path LIKE @prefix)"
+			args["path"], args["prefix"] = ch.CategoryPath, ch.CategoryPath+question.CategoryPathSep+"%"
+		}
+		filter += ")"
+	}
+	db.DB.Raw(`SELECT m.user_id, COALESCE(NULLIF(u.nickname, ''), u.username) AS nickname, u.avatar, `+counter+` AS count
+		FROM group_members m
+		JOIN users u ON u.id = m.user_id
+		LEFT JOIN answer_histories h ON `+filter+`
+		WHERE m.group_id = @group
+		GROUP BY m.user_id, u.nickname, u.username, u.avatar
+		ORDER BY count DESC`, args).Scan(&list)
+	return list
+}
+
+// ViewChallenge 挑战 + 当前进度
+func ViewChallenge(ch *GroupChallenge, now time.Time) ChallengeView {
+	v := ChallengeView{GroupChallenge: *ch, Members: challengeProgress(ch)}
+	for _, m := range v.Members {
+		v.Progress += m.Count
+	}
+	if ch.Target > 0 {
+		v.Percent = float64(v.Progress) / float64(ch.Target) * 100
+		if v.Percent > 100 {
+			v.Percent = 100
+		}
+	}
+	v.Active = !now.Before(ch.StartAt) && now.Before(ch.EndAt)
+	return v
+}
+
+// OnAnswered 作答流水写入后调用 (answer 包)：该用户所在小组有进行中的挑战时，异步推送最新进度
+func OnAnswered(userID uint) {
+	if userID == 0 {
+		return
+	}
+	go func() {
+		now := time.Now()
+		var challenges []GroupChallenge
+		db.DB.Where("group_id IN (?) AND start_at <= ? AND end_at > ?",
+			db.DB.Model(&GroupMember{}).Select("group_id").Where("user_id = ?", userID), now, now).
+			Find(&challenges)
+		for _, ch := range challenges {
+			schedulePush(ch)
+		}
+	}()
+}
+
+// schedulePush 节流推送：距上次推送不足 pushThrottle 时不丢弃，而是等间隔到了补推一次，
+// 保证最后一次作答后的进度 (以及是否达标) 一定会推出去
+func schedulePush(ch GroupChallenge) {
+	pushMu.Lock()
+	now := time.Now()
+	for id, at := range lastPush {
+		if now.Sub(at) >= pushThrottle {
+			delete(lastPush, id) // 过了节流间隔的记录已无用，顺手清掉
+		}
+	}
+	wait := pushThrottle - now.Sub(lastPush[ch.ID])
+	if wait <= 0 {
+		lastPush[ch.ID] = now
+		pushMu.Unlock()
+		pushChallenge(&ch, now)
+		return
+	}
+	if trailing[ch.ID] {
+		pushMu.Unlock()
+		return
+	}
+	trailing[ch.ID] = true
+	pushMu.Unlock()
+
+	time.AfterFunc(wait, func() {
+		pushMu.Lock()
+		delete(trailing, ch.ID)
+		at := time.Now()
+		lastPush[ch.ID] = at
+		pushMu.Unlock()
+		pushChallenge(&ch, at)
+	})
+}
+
+// pushChallenge 把挑战进度推给全体在线组员；首次达标时记录完成时间并发通知
+func pushChallenge(ch *GroupChallenge, now time.Time) {
+	v := ViewChallenge(ch, now)
+	members := NewRepository().MemberIDs(ch.GroupID)
+	for _, uid := range members {
+		service.Hub.SendToUser(uid, gin.H{"type": "group_challenge_progress", "data": v})
+	}
+
+	if ch.CompletedAt != nil || ch.Target <= 0 || v.Progress < int64(ch.Target) {
+		return
+	}
+	res := db.DB.Model(&GroupChallenge{}).Where("id = ? AND completed_at IS NULL", ch.ID).Update("completed_at", now)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return // 已被别的推送标记过
+	}
+	pushMu.Lock()
+	delete(lastPush, ch.ID)
+	pushMu.Unlock()
+	for _, uid := range members {
+		service.SendNotification(uid, 0, "group_challenge", ch.ID,
+			fmt.Sprintf("小组挑战「%s」已达成：%d/%d", ch.Title, v.Progress, ch.Target), "🎉 小组挑战完成")
+	}
+}
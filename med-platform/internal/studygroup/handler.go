@@ -0,0 +1,407 @@
+package studygroup
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"med-platform/internal/common/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	repo *Repository
+}
+
+func NewHandler() *Handler {
+	return &Handler{repo: NewRepository()}
+}
+
+// member 取当前用户在 :id 小组中的身份；roles 非空时还要求角色在其中
+func (h *Handler) member(c *gin.Context, roles ...string) (*StudyGroup, *GroupMember, bool) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var g StudyGroup
+	if err := db.DB.First(&g, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "小组不存在"})
+		return nil, nil, false
+	}
+	m := h.repo.Membership(g.ID, c.MustGet("userID").(uint))
+	if m == nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "你不是该小组成员"})
+		return nil, nil, false
+	}
+	if len(roles) > 0 {
+		ok := false
+		for _, r := range roles {
+			ok = ok || m.Role == r
+		}
+		if !ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "权限不足"})
+			return nil, nil, false
+		}
+	}
+	return &g, m, true
+}
+
+// =========================================================
+// 👥 小组与成员
+// =========================================================
+
+// CreateGroup 建组
+// POST /groups {"name":"","description":""}
+func (h *Handler) CreateGroup(c *gin.Context) {
+	var req struct {
+		Name        string `json:"name" binding:"required"`
+		Description string `json:"description"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请填写小组名称"})
+		return
+	}
+	g, err := h.repo.Create(c.MustGet("userID").(uint), strings.TrimSpace(req.Name), req.Description)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "小组已创建，把邀请码发给同学即可加入", "data": g})
+}
+
+// ListMyGroups 我加入的小组
+// GET /groups
+func (h *Handler) ListMyGroups(c *gin.Context) {
+	type row struct {
+		StudyGroup
+		Role string `json:"role"`
+	}
+	list := []row{}
+	db.DB.Table("study_groups g").Select("g.*, m.role").
+		Joins("JOIN group_members m ON m.group_id = g.id").
+		Where("m.user_id = ? AND g.deleted_at IS NULL", c.MustGet("userID").(uint)).
+		Order("m.joined_at desc").Scan(&list)
+	// 邀请码只给组长 / 管理员看
+	for i := range list {
+		if list[i].Role == RoleMember {
+			list[i].InviteCode = ""
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// JoinGroup 凭邀请码加入
+// POST /groups/join {"invite_code":"AB12CD34"}
+func (h *Handler) JoinGroup(c *gin.Context) {
+	var req struct {
+		InviteCode string `json:"invite_code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请输入邀请码"})
+		return
+	}
+	g, err := h.repo.Join(c.MustGet("userID").(uint), req.InviteCode)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	g.InviteCode = ""
+	c.JSON(http.StatusOK, gin.H{"message": "已加入小组「" + g.Name + "」", "data": g})
+}
+
+// GetGroup 小组详情与成员
+// GET /groups/:id
+func (h *Handler) GetGroup(c *gin.Context) {
+	g, m, ok := h.member(c)
+	if !ok {
+		return
+	}
+	if m.Role == RoleMember {
+		g.InviteCode = ""
+	}
+	c.JSON(http.StatusOK, gin.H{"data": g, "role": m.Role, "members": h.repo.Members(g.ID)})
+}
+
+// LeaveGroup 退出小组 (组长需先转让或解散)
+// POST /groups/:id/leave
+func (h *Handler) LeaveGroup(c *gin.Context) {
+	g, m, ok := h.member(c)
+	if !ok {
+		return
+	}
+	if m.Role == RoleOwner {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "组长请先转让小组或直接解散"})
+		return
+	}
+	if err := h.repo.RemoveMember(g.ID, m.UserID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "退出失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已退出小组"})
+}
+
+// UpdateMemberRole 设置成员角色 (admin / member)，或转让组长 (owner)
+// PUT /groups/:id/members/:uid {"role":"admin"}
+func (h *Handler) UpdateMemberRole(c *gin.Context) {
+	g, me, ok := h.member(c, RoleOwner)
+	if !ok {
+		return
+	}
+	var req struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	uid, _ := strconv.Atoi(c.Param("uid"))
+	target := h.repo.Membership(g.ID, uint(uid))
+	if target == nil || target.UserID == me.UserID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "成员不存在"})
+		return
+	}
+
+	var err error
+	switch req.Role {
+	case RoleOwner:
+		err = h.repo.TransferOwner(g.ID, me.UserID, target.UserID)
+	case RoleAdmin, RoleMember:
+		err = db.DB.Model(target).Update("role", req.Role).Error
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的角色"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "设置失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已更新成员角色"})
+}
+
+// RemoveMember 移出成员 (管理员不能移出组长和其他管理员)
+// DELETE /groups/:id/members/:uid
+func (h *Handler) RemoveMember(c *gin.Context) {
+	g, me, ok := h.member(c, RoleOwner, RoleAdmin)
+	if !ok {
+		return
+	}
+	uid, _ := strconv.Atoi(c.Param("uid"))
+	target := h.repo.Membership(g.ID, uint(uid))
+	if target == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "成员不存在"})
+		return
+	}
+	if target.Role == RoleOwner || (me.Role == RoleAdmin && target.Role == RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "权限不足"})
+		return
+	}
+	if err := h.repo.RemoveMember(g.ID, target.UserID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移出失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已移出成员"})
+}
+
+// ResetInviteCode 重置邀请码 (旧码立即失效)
+// POST /groups/:id/invite-code
+func (h *Handler) ResetInviteCode(c *gin.Context) {
+	g, _, ok := h.member(c, RoleOwner, RoleAdmin)
+	if !ok {
+		return
+	}
+	code := newInviteCode()
+	if err := db.DB.Model(g).Update("invite_code", code).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "重置失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "邀请码已重置", "invite_code": code})
+}
+
+// DissolveGroup 解散小组
+// DELETE /groups/:id
+func (h *Handler) DissolveGroup(c *gin.Context) {
+	g, _, ok := h.member(c, RoleOwner)
+	if !ok {
+		return
+	}
+	if err := h.repo.Dissolve(g.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "解散失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "小组已解散"})
+}
+
+// GetDashboard 小组看板：每日做题、成员排行、各科目正确率
+// GET /groups/:id/dashboard?days=14
+func (h *Handler) GetDashboard(c *gin.Context) {
+	g, _, ok := h.member(c)
+	if !ok {
+		return
+	}
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "14"))
+	if days <= 0 || days > 90 {
+		days = 14
+	}
+	c.JSON(http.StatusOK, gin.H{"data": h.repo.Dashboard(g.ID, days)})
+}
+
+// =========================================================
+// 🏁 挑战
+// =========================================================
+
+// CreateChallenge 发起挑战
+// POST /groups/:id/challenges {"title":"本周生化 300 题","source":"","category_path":"生物化学","metric":"count","target":300,"start_at":"","end_at":""}
+// start_at / end_at 不填时为本周一 0 点到下周一 0 点
+func (h *Handler) CreateChallenge(c *gin.Context) {
+	g, me, ok := h.member(c, RoleOwner, RoleAdmin)
+	if !ok {
+		return
+	}
+	var req struct {
+		Title        string     `json:"title" binding:"required"`
+		Source       string     `json:"source"`
+		CategoryPath string     `json:"category_path"`
+		Metric       string     `json:"metric"`
+		Target       int        `json:"target" binding:"required,gt=0"`
+		StartAt      *time.Time `json:"start_at"`
+		EndAt        *time.Time `json:"end_at"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请填写挑战名称和目标题量"})
+		return
+	}
+	if req.Metric == "" {
+		req.Metric = ChallengeCount
+	}
+	if req.Metric != ChallengeCount && req.Metric != ChallengeCorrect {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的挑战指标"})
+		return
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	monday := today.AddDate(0, 0, -int((today.Weekday()+6)%7))
+	ch := GroupChallenge{
+		GroupID: g.ID, Title: req.Title, Source: req.Source, CategoryPath: strings.TrimSpace(req.CategoryPath),
+		Metric: req.Metric, Target: req.Target, StartAt: monday, EndAt: monday.AddDate(0, 0, 7), CreatedBy: me.UserID,
+	}
+	if req.StartAt != nil {
+		ch.StartAt = *req.StartAt
+	}
+	if req.EndAt != nil {
+		ch.EndAt = *req.EndAt
+	}
+	if !ch.EndAt.After(ch.StartAt) || !ch.EndAt.After(now) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "结束时间必须晚于开始时间和当前时间"})
+		return
+	}
+	if err := db.DB.Create(&ch).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "挑战已发起", "data": ViewChallenge(&ch, now)})
+}
+
+// ListChallenges 小组挑战 (进行中的在前)
+// GET /groups/:id/challenges
+func (h *Handler) ListChallenges(c *gin.Context) {
+	g, _, ok := h.member(c)
+	if !ok {
+		return
+	}
+	var list []GroupChallenge
+	db.DB.Where("group_id = ?", g.ID).Order("end_at desc").Limit(20).Find(&list)
+	now := time.Now()
+	views := make([]ChallengeView, 0, len(list))
+	for i := range list {
+		views = append(views, ViewChallenge(&list[i], now))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": views})
+}
+
+// DeleteChallenge 取消挑战
+// DELETE /groups/:id/challenges/:cid
+func (h *Handler) DeleteChallenge(c *gin.Context) {
+	g, _, ok := h.member(c, RoleOwner, RoleAdmin)
+	if !ok {
+		return
+	}
+	cid, _ := strconv.Atoi(c.Param("cid"))
+	if res := db.DB.Where("id = ? AND group_id = ?", cid, g.ID).Delete(&GroupChallenge{}); res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "挑战不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "挑战已取消"})
+}
+
+// =========================================================
+// 📝 布置练习
+// =========================================================
+
+// CreateAssignment 布置练习 (按范围随机抽题，或直接指定题目)
+// POST /groups/:id/assignments
+func (h *Handler) CreateAssignment(c *gin.Context) {
+	g, me, ok := h.member(c, RoleOwner, RoleAdmin)
+	if !ok {
+		return
+	}
+	var req AssignmentReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请填写练习名称和题库"})
+		return
+	}
+	a, err := h.repo.BuildAssignment(g.ID, me.UserID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "练习已布置", "data": a})
+}
+
+// ListAssignments 小组练习列表 (含我自己的完成数)
+// GET /groups/:id/assignments
+func (h *Handler) ListAssignments(c *gin.Context) {
+	g, me, ok := h.member(c)
+	if !ok {
+		return
+	}
+	var list []GroupAssignment
+	db.DB.Where("group_id = ?", g.ID).Order("id desc").Limit(50).Find(&list)
+	out := make([]gin.H, 0, len(list))
+	for _, a := range list {
+		var done int64
+		if len(a.QuestionIDs) > 0 {
+			db.DB.Table("answer_records").Where("user_id = ? AND question_id IN ? AND deleted_at IS NULL", me.UserID, a.QuestionIDs).Count(&done)
+		}
+		out = append(out, gin.H{"assignment": a, "total": len(a.QuestionIDs), "my_done": done})
+	}
+	c.JSON(http.StatusOK, gin.H{"data": out})
+}
+
+// GetAssignment 练习详情与全员完成情况；题目内容走 /questions/:id (仍按个人授权校验)
+// GET /groups/:id/assignments/:aid
+func (h *Handler) GetAssignment(c *gin.Context) {
+	g, _, ok := h.member(c)
+	if !ok {
+		return
+	}
+	var a GroupAssignment
+	if err := db.DB.Where("id = ? AND group_id = ?", c.Param("aid"), g.ID).First(&a).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "练习不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": a, "total": len(a.QuestionIDs), "members": h.repo.AssignmentProgress(&a)})
+}
+
+// DeleteAssignment 撤回练习
+// DELETE /groups/:id/assignments/:aid
+func (h *Handler) DeleteAssignment(c *gin.Context) {
+	g, _, ok := h.member(c, RoleOwner, RoleAdmin)
+	if !ok {
+		return
+	}
+	if res := db.DB.Where("id = ? AND group_id = ?", c.Param("aid"), g.ID).Delete(&GroupAssignment{}); res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "练习不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "练习已撤回"})
+}
@@ -0,0 +1,106 @@
+package studygroup
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 成员角色
+const (
+	RoleOwner  = "owner"  // 组长：解散、转让、设管理员、布置练习
+	RoleAdmin  = "admin"  // 管理员：移除成员、发起挑战、布置练习
+	RoleMember = "member" // 普通成员
+)
+
+// 挑战指标
+const (
+	ChallengeCount   = "count"   // 做题量 (作答次数，重做也算)
+	ChallengeCorrect = "correct" // 答对题数
+)
+
+// MaxGroupMembers 每个小组的人数上限
+const MaxGroupMembers = 50
+
+// StudyGroup 学习小组
+type StudyGroup struct {
+	ID          uint   `gorm:"primarykey" json:"id"`
+	Name        string `gorm:"size:50;not null" json:"name"`
+	Description string `gorm:"size:500" json:"description"`
+	OwnerID     uint   `gorm:"index" json:"owner_id"`
+	InviteCode  string `gorm:"size:16;uniqueIndex" json:"invite_code,omitempty"`
+	MemberCount int    `gorm:"default:1" json:"member_count"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (StudyGroup) TableName() string {
+	return "study_groups"
+}
+
+// GroupMember 小组成员
+type GroupMember struct {
+	ID       uint      `gorm:"primarykey" json:"id"`
+	GroupID  uint      `gorm:"index:idx_group_member,unique;not null" json:"group_id"`
+	UserID   uint      `gorm:"index:idx_group_member,unique;index;not null" json:"user_id"`
+	Role     string    `gorm:"size:20;default:'member'" json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+func (GroupMember) TableName() string {
+	return "group_members"
+}
+
+// GroupChallenge 小组挑战：例如 "本周生化 300 题"，全组合力完成
+type GroupChallenge struct {
+	ID           uint       `gorm:"primarykey" json:"id"`
+	GroupID      uint       `gorm:"index;not null" json:"group_id"`
+	Title        string     `gorm:"size:100" json:"title"`
+	Source       string     `gorm:"size:100" json:"source"`         // 空 = 不限题库
+	CategoryPath string     `gorm:"type:text" json:"category_path"` // 空 = 不限章节 (按前缀匹配子树)
+	Metric       string     `gorm:"size:20;default:'count'" json:"metric"`
+	Target       int        `json:"target"`
+	StartAt      time.Time  `gorm:"index" json:"start_at"`
+	EndAt        time.Time  `gorm:"index" json:"end_at"`
+	CompletedAt  *time.Time `json:"completed_at"`
+	CreatedBy    uint       `json:"created_by"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func (GroupChallenge) TableName() string {
+	return "group_challenges"
+}
+
+// GroupAssignment 组长布置的练习：题目只从全体成员都有权限的题库中抽取
+type GroupAssignment struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	GroupID     uint       `gorm:"index;not null" json:"group_id"`
+	Title       string     `gorm:"size:100" json:"title"`
+	QuestionIDs []uint     `gorm:"type:jsonb;serializer:json" json:"question_ids"`
+	DueAt       *time.Time `json:"due_at"`
+	CreatedBy   uint       `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (GroupAssignment) TableName() string {
+	return "group_assignments"
+}
+
+// MemberProgress 成员在挑战 / 练习中的贡献
+type MemberProgress struct {
+	UserID   uint   `json:"user_id"`
+	Nickname string `json:"nickname"`
+	Avatar   string `json:"avatar"`
+	Count    int64  `json:"count"`
+}
+
+// ChallengeView 挑战及实时进度
+type ChallengeView struct {
+	GroupChallenge
+	Progress int64            `json:"progress"`
+	Percent  float64          `json:"percent"`
+	Active   bool             `json:"active"`
+	Members  []MemberProgress `json:"members"`
+}
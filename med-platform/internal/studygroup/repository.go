@@ -0,0 +1,354 @@
+package studygroup
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"med-platform/internal/common/db"
+	"med-platform/internal/product"
+	"med-platform/internal/question"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct{}
+
+func NewRepository() *Repository {
+	return &Repository{}
+}
+
+// newInviteCode 8 位邀请码
+func newInviteCode() string {
+	raw := strings.ReplaceAll(uuid.New().String(), "-", "")
+	return strings.ToUpper(raw[:8])
+}
+
+// Membership 用户在组内的身份 (不是成员返回 nil)
+func (r *Repository) Membership(groupID, userID uint) *GroupMember {
+	var m GroupMember
+	if err := db.DB.Where("group_id = ? AND user_id = ?", groupID, userID).First(&m).Error; err != nil {
+		return nil
+	}
+	return &m
+}
+
+// MemberIDs 组内全部成员
+func (r *Repository) MemberIDs(groupID uint) []uint {
+	var ids []uint
+	db.DB.Model(&GroupMember{}).Where("group_id = ?", groupID).Pluck("user_id", &ids)
+	return ids
+}
+
+// Create 建组，创建者为组长
+func (r *Repository) Create(ownerID uint, name, desc string) (*StudyGroup, error) {
+	g := &StudyGroup{Name: name, Description: desc, OwnerID: ownerID, InviteCode: newInviteCode(), MemberCount: 1}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(g).Error; err != nil {
+			return err
+		}
+		return tx.Create(&GroupMember{GroupID: g.ID, UserID: ownerID, Role: RoleOwner, JoinedAt: time.Now()}).Error
+	})
+	return g, err
+}
+
+// Join 凭邀请码入组
+func (r *Repository) Join(userID uint, code string) (*StudyGroup, error) {
+	var g StudyGroup
+	if err := db.DB.Where("invite_code = ?", strings.ToUpper(strings.TrimSpace(code))).First(&g).Error; err != nil {
+		return nil, fmt.Errorf("邀请码无效")
+	}
+	if r.Membership(g.ID, userID) != nil {
+		return nil, fmt.Errorf("你已经在该小组中")
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		// 行锁防止并发加入超员
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&g, g.ID).Error; err != nil {
+			return err
+		}
+		if g.MemberCount >= MaxGroupMembers {
+			return fmt.Errorf("小组已满 (%d 人)", MaxGroupMembers)
+		}
+		if err := tx.Create(&GroupMember{GroupID: g.ID, UserID: userID, Role: RoleMember, JoinedAt: time.Now()}).Error; err != nil {
+			return err
+		}
+		g.MemberCount++
+		return tx.Model(&g).Update("member_count", g.MemberCount).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// RemoveMember 退出 / 移出小组
+func (r *Repository) RemoveMember(groupID, userID uint) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("group_id = ? AND user_id = ?", groupID, userID).Delete(&GroupMember{})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		return tx.Model(&StudyGroup{}).Where("id = ?", groupID).
+			UpdateColumn("member_count", gorm.Expr("GREATEST(member_count - 1, 0)")).Error
+	})
+}
+
+// TransferOwner 转让组长，原组长降为管理员
+func (r *Repository) TransferOwner(groupID, fromID, toID uint) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&GroupMember{}).Where("group_id = ? AND user_id = ?", groupID, fromID).Update("role", RoleAdmin).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&GroupMember{}).Where("group_id = ? AND user_id = ?", groupID, toID).Update("role", RoleOwner).Error; err != nil {
+			return err
+		}
+		return tx.Model(&StudyGroup{}).Where("id = ?", groupID).Update("owner_id", toID).Error
+	})
+}
+
+// Dissolve 解散小组 (成员、挑战、练习一并删除)
+func (r *Repository) Dissolve(groupID uint) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		for _, m := range []interface{}{&GroupMember{}, &GroupChallenge{}, &GroupAssignment{}} {
+			if err := tx.Where("group_id = ?", groupID).Delete(m).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&StudyGroup{}, groupID).Error
+	})
+}
+
+// memberInfo 成员展示信息
+type memberInfo struct {
+	UserID   uint      `json:"user_id"`
+	Username string    `json:"username"`
+	Nickname string    `json:"nickname"`
+	Avatar   string    `json:"avatar"`
+	School   string    `json:"school"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// Members 成员列表 (组长、管理员在前)
+func (r *Repository) Members(groupID uint) []memberInfo {
+	list := []memberInfo{}
+	db.DB.Table("group_members m").
+		Select("m.user_id, u.username, COALESCE(NULLIF(u.nickname, ''), u.username) AS nickname, u.avatar, u.school, m.role, m.joined_at").
+		Joins("JOIN users u ON u.id = m.user_id").
+		Where("m.group_id = ?", groupID).
+		Order("CASE m.role WHEN 'owner' THEN 0 WHEN 'admin' THEN 1 ELSE 2 END, m.joined_at").
+		Scan(&list)
+	return list
+}
+
+// =========================================================
+// 📊 小组看板
+// =========================================================
+
+type DailyPoint struct {
+	Date    string `json:"date"`
+	Count   int64  `json:"count"`   // 全组当天做题数
+	Members int64  `json:"members"` // 当天有做题的人数
+}
+
+type MemberTotal struct {
+	UserID   uint   `json:"user_id"`
+	Nickname string `json:"nickname"`
+	Avatar   string `json:"avatar"`
+	Count    int64  `json:"count"`
+	Days     int64  `json:"days"` // 区间内有做题的天数
+}
+
+type CategoryAccuracy struct {
+	Source   string  `json:"source"`
+	Category string  `json:"category"`
+	Done     int64   `json:"done"`
+	Correct  int64   `json:"correct"`
+	Accuracy float64 `json:"accuracy"`
+	Members  int64   `json:"members"` // 做过该科目的人数
+}
+
+type Dashboard struct {
+	Daily      []DailyPoint       `json:"daily"`
+	Members    []MemberTotal      `json:"members"`
+	Categories []CategoryAccuracy `json:"categories"`
+}
+
+// Dashboard 汇总成员的每日做题数 (user_daily_stats) 与各科目正确率 (answer_records 当前状态)
+func (r *Repository) Dashboard(groupID uint, days int) Dashboard {
+	now := time.Now()
+	since := now.AddDate(0, 0, -(days - 1)).Format("2006-01-02")
+	members := db.DB.Model(&GroupMember{}).Select("user_id").Where("group_id = ?", groupID)
+	d := Dashboard{Members: []MemberTotal{}, Categories: []CategoryAccuracy{}}
+
+	var daily []DailyPoint
+	db.DB.Table("user_daily_stats").
+		Select("date_str AS date, SUM(count) AS count, COUNT(*) FILTER (WHERE count > 0) AS members").
+		Where("user_id IN (?) AND date_str >= ?", members, since).
+		Group("date_str").Scan(&daily)
+	byDate := make(map[string]DailyPoint, len(daily))
+	for _, p := range daily {
+		byDate[p.Date] = p
+	}
+	for i := days - 1; i >= 0; i-- {
+		date := now.AddDate(0, 0, -i).Format("2006-01-02")
+		p := byDate[date]
+		p.Date = date
+		d.Daily = append(d.Daily, p)
+	}
+
+	db.DB.Table("group_members m").
+		Select("m.user_id, COALESCE(NULLIF(u.nickname, ''), u.username) AS nickname, u.avatar, COALESCE(SUM(s.count), 0) AS count, COUNT(s.id) FILTER (WHERE s.count > 0) AS days").
+		Joins("JOIN users u ON u.id = m.user_id").
+		Joins("LEFT JOIN user_daily_stats s ON s.user_id = m.user_id AND s.date_str >= ?", since).
+		Where("m.group_id = ?", groupID).
+		Group("m.user_id, u.nickname, u.username, u.avatar").
+		Order("count DESC").Scan(&d.Members)
+
+	db.DB.Table("answer_records ar").
+		Select("q.source, q.category, COUNT(*) AS done, COUNT(*) FILTER (WHERE ar.is_correct) AS correct, COUNT(DISTINCT ar.user_id) AS members").
+		Joins("JOIN questions q ON q.id = ar.question_id AND q.deleted_at IS NULL").
+		Where("ar.user_id IN (?) AND ar.deleted_at IS NULL", members).
+		Group("q.source, q.category").
+		Order("done DESC").Scan(&d.Categories)
+	for i := range d.Categories {
+		if c := &d.Categories[i]; c.Done > 0 {
+			c.Accuracy = float64(c.Correct) / float64(c.Done) * 100
+		}
+	}
+	return d
+}
+
+// =========================================================
+// 📝 布置练习：只从全体成员都有权限的 (题库, 科目) 中抽题
+// =========================================================
+
+// CommonEntitlements 全组共同拥有的 (题库, 一级科目)；unrestricted 为 true 表示成员全是管理员 / 代理，不受限
+func (r *Repository) CommonEntitlements(groupID uint) (common []product.Entitlement, unrestricted bool, err error) {
+	type member struct {
+		ID   uint
+		Role string
+	}
+	var members []member
+	if err := db.DB.Table("group_members m").Select("u.id, u.role").
+		Joins("JOIN users u ON u.id = m.user_id").Where("m.group_id = ?", groupID).Scan(&members).Error; err != nil {
+		return nil, false, err
+	}
+
+	productRepo := product.NewRepository()
+	var counts map[product.Entitlement]int
+	restricted := 0
+	for _, m := range members {
+		if m.Role == "admin" || m.Role == "agent" { // 与 checkAccess 口径一致
+			continue
+		}
+		list, err := productRepo.ListEntitlements(m.ID)
+		if err != nil {
+			return nil, false, err
+		}
+		if counts == nil {
+			counts = make(map[product.Entitlement]int)
+		}
+		for _, e := range list {
+			counts[e]++
+		}
+		restricted++
+	}
+	if restricted == 0 {
+		return nil, true, nil
+	}
+	for e, n := range counts {
+		if n == restricted {
+			common = append(common, e)
+		}
+	}
+	return common, false, nil
+}
+
+// AssignmentReq 布置练习的抽题条件
+type AssignmentReq struct {
+	Title        string     `json:"title" binding:"required"`
+	Source       string     `json:"source" binding:"required"`
+	CategoryPath string     `json:"category_path"`
+	Types        []string   `json:"types"`
+	Count        int        `json:"count"`
+	QuestionIDs  []uint     `json:"question_ids"` // 也可以直接指定题目
+	DueAt        *time.Time `json:"due_at"`
+}
+
+// BuildAssignment 按条件抽题并校验全组权限
+func (r *Repository) BuildAssignment(groupID, creatorID uint, req AssignmentReq) (*GroupAssignment, error) {
+	if req.Count <= 0 || req.Count > 200 {
+		req.Count = 50
+	}
+	common, unrestricted, err := r.CommonEntitlements(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 只出可作答的小题 / 单题 (组合题父题本身不作答)
+	query := db.DB.Model(&question.Question{}).
+		Where("source = ?", req.Source).
+		Where("NOT EXISTS (SELECT 1 FROM questions c WHERE c.parent_id = questions.id AND c.deleted_at IS NULL)")
+	if !unrestricted {
+		var allowed [][]interface{}
+		for _, e := range common {
+			if e.Source == req.Source {
+				allowed = append(allowed, []interface{}{e.Source, e.Category})
+			}
+		}
+		if len(allowed) == 0 {
+			return nil, fmt.Errorf("该题库不是所有成员都已开通，无法布置")
+		}
+		query = query.Where("(source, category) IN ?", allowed)
+	}
+	if req.CategoryPath != "" {
+		query = query.Where("(category_path = ? OR category_path LIKE ?)", req.CategoryPath, req.CategoryPath+question.CategoryPathSep+"%")
+	}
+	if len(req.Types) > 0 {
+		query = query.Where("type IN ?", req.Types)
+	}
+
+	var ids []uint
+	if len(req.QuestionIDs) > 0 {
+		if err := query.Where("id IN ?", req.QuestionIDs).Pluck("id", &ids).Error; err != nil {
+			return nil, err
+		}
+		if len(ids) != len(req.QuestionIDs) {
+			return nil, fmt.Errorf("有 %d 道题不在全组都有权限的范围内", len(req.QuestionIDs)-len(ids))
+		}
+	} else {
+		if err := query.Pluck("id", &ids).Error; err != nil {
+			return nil, err
+		}
+		rand.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+		if len(ids) > req.Count {
+			ids = ids[:req.Count]
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("该范围内没有可布置的题目")
+	}
+
+	a := &GroupAssignment{GroupID: groupID, Title: req.Title, QuestionIDs: ids, DueAt: req.DueAt, CreatedBy: creatorID}
+	return a, db.DB.Create(a).Error
+}
+
+// AssignmentProgress 每位成员在该练习中已做 / 答对的题数
+func (r *Repository) AssignmentProgress(a *GroupAssignment) []MemberProgress {
+	list := []MemberProgress{}
+	if len(a.QuestionIDs) == 0 {
+		return list
+	}
+	db.DB.Table("group_members m").
+		Select("m.user_id, COALESCE(NULLIF(u.nickname, ''), u.username) AS nickname, u.avatar, COUNT(ar.id) AS count").
+		Joins("JOIN users u ON u.id = m.user_id").
+		Joins("LEFT JOIN answer_records ar ON ar.user_id = m.user_id AND ar.deleted_at IS NULL AND ar.question_id IN ?", a.QuestionIDs).
+		Where("m.group_id = ?", a.GroupID).
+		Group("m.user_id, u.nickname, u.username, u.avatar").
+		Order("count DESC").Scan(&list)
+	return list
+}
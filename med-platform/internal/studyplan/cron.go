@@ -0,0 +1,54 @@
+package studyplan
+
+import (
+	"time"
+
+	"med-platform/internal/common/db"
+	"med-platform/internal/common/logger"
+
+	"go.uber.org/zap"
+)
+
+// PlanInterval 计划巡检频率：跨天重排 + 到点提醒
+const PlanInterval = time.Hour
+
+// StartPlanTask 启动备考计划守护任务
+// 请在 main.go 中调用: studyplan.StartPlanTask()
+func StartPlanTask() {
+	go func() {
+		time.Sleep(2 * time.Minute)
+		RunPlans()
+
+		ticker := time.NewTicker(PlanInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			RunPlans()
+		}
+	}()
+}
+
+// RunPlans 逐个进行中的计划滚动到今天并按需提醒
+func RunPlans() {
+	repo := NewRepository()
+	now := time.Now()
+	lastID := uint(0)
+	for {
+		var plans []StudyPlan
+		if err := db.DB.Where("status = ? AND id > ?", PlanActive, lastID).Order("id asc").Limit(200).Find(&plans).Error; err != nil {
+			logger.Log.Error("备考计划巡检失败", zap.Error(err))
+			return
+		}
+		if len(plans) == 0 {
+			return
+		}
+		for i := range plans {
+			p := &plans[i]
+			lastID = p.ID
+			if err := repo.Roll(p, now); err != nil {
+				logger.Log.Error("备考计划重排失败", zap.Uint("plan_id", p.ID), zap.Error(err))
+				continue
+			}
+			repo.Remind(p, now)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package studyplan
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"med-platform/internal/common/db"
+	"med-platform/internal/question"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	repo *Repository
+}
+
+func NewHandler() *Handler {
+	return &Handler{repo: NewRepository()}
+}
+
+// loadPlan 取自己的计划
+func (h *Handler) loadPlan(c *gin.Context) (*StudyPlan, bool) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var p StudyPlan
+	if err := db.DB.Where("id = ? AND user_id = ?", id, c.MustGet("userID").(uint)).First(&p).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "计划不存在"})
+		return nil, false
+	}
+	return &p, true
+}
+
+// checkCategories 计划范围内的每个目录都要有访问授权 (与刷题同一套口径)
+func checkCategories(c *gin.Context, categoryIDs []uint) bool {
+	if len(categoryIDs) == 0 {
+		return true
+	}
+	var cats []question.Category
+	db.DB.Select("source, full_path").Where("id IN ?", categoryIDs).Find(&cats)
+	for _, cat := range cats {
+		if !question.CheckAccess(c, cat.Source, cat.FullPath) {
+			return false
+		}
+	}
+	return true
+}
+
+// GetPlan 当前进行中的备考计划 (没有则 data 为 null)
+// GET /study-plan
+func (h *Handler) GetPlan(c *gin.Context) {
+	p, err := h.repo.Active(c.MustGet("userID").(uint))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"data": nil})
+		return
+	}
+	now := time.Now()
+	if err := h.repo.Roll(p, now); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新计划失败"})
+		return
+	}
+	if p.Status != PlanActive {
+		c.JSON(http.StatusOK, gin.H{"data": nil, "message": "考试日已到，计划已结束"})
+		return
+	}
+	view, err := h.repo.View(p, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取计划进度失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": view})
+}
+
+// CreatePlan 新建备考计划 (替换掉进行中的旧计划)
+// POST /study-plan  {"title":"","category_ids":[1,2],"exam_date":"2025-12-20","review_days":7,"remind_hour":20}
+func (h *Handler) CreatePlan(c *gin.Context) {
+	var req PlanReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !checkCategories(c, req.CategoryIDs) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "FORBIDDEN", "message": "🔒 您尚未获得所选科目的访问授权"})
+		return
+	}
+	p, err := h.repo.Create(c.MustGet("userID").(uint), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	view, err := h.repo.View(p, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取计划进度失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "计划已创建", "data": view})
+}
+
+// UpdatePlan 修改计划 (考试日期、范围、复习天数、提醒时间)，立即重排每日目标
+// PUT /study-plan/:id
+func (h *Handler) UpdatePlan(c *gin.Context) {
+	p, ok := h.loadPlan(c)
+	if !ok {
+		return
+	}
+	if p.Status != PlanActive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "计划已结束，请新建计划"})
+		return
+	}
+	var req PlanReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !checkCategories(c, req.CategoryIDs) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "FORBIDDEN", "message": "🔒 您尚未获得所选科目的访问授权"})
+		return
+	}
+	if err := h.repo.Update(p, req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	view, err := h.repo.View(p, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取计划进度失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "计划已更新", "data": view})
+}
+
+// DeletePlan 放弃计划 (归档，日程记录保留)
+// DELETE /study-plan/:id
+func (h *Handler) DeletePlan(c *gin.Context) {
+	p, ok := h.loadPlan(c)
+	if !ok {
+		return
+	}
+	db.DB.Model(p).Update("status", PlanArchived)
+	c.JSON(http.StatusOK, gin.H{"message": "计划已放弃"})
+}
+
+// ListDays 计划的全部日程与每天完成情况
+// GET /study-plan/:id/days
+func (h *Handler) ListDays(c *gin.Context) {
+	p, ok := h.loadPlan(c)
+	if !ok {
+		return
+	}
+	days, err := h.repo.Days(p.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取日程失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": days})
+}
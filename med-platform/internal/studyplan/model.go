@@ -0,0 +1,74 @@
+package studyplan
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 计划状态
+const (
+	PlanActive   = "active"   // 进行中 (每人同时只有一个)
+	PlanFinished = "finished" // 已到考试日
+	PlanArchived = "archived" // 用户放弃 / 被新计划替换
+)
+
+// DateLayout 计划里的日期统一用 "2006-01-02" 字符串 (与 user_daily_stats.date_str 一致)
+const DateLayout = "2006-01-02"
+
+// StudyPlan 备考计划：选定题库目录 + 考试日期，把剩余未做的题平摊到剩下的每一天
+type StudyPlan struct {
+	ID     uint   `gorm:"primarykey" json:"id"`
+	UserID uint   `gorm:"index;not null" json:"user_id"`
+	Title  string `gorm:"size:100" json:"title"`
+
+	CategoryIDs []uint `gorm:"type:jsonb;serializer:json" json:"category_ids"` // 目标目录 (含子树)
+	ExamDate    string `gorm:"type:char(10);not null" json:"exam_date"`
+	ReviewDays  int    `gorm:"default:0" json:"review_days"`  // 考前留出的冲刺复习天数 (不再排新题)
+	RemindHour  int    `gorm:"default:20" json:"remind_hour"` // 每天几点检查进度并提醒 (0~23)
+
+	Status         string `gorm:"size:20;index;default:'active'" json:"status"`
+	DailyTarget    int    `json:"daily_target"`             // 当前每日目标题量
+	StartDone      int64  `json:"start_done"`               // 建计划时已做题数
+	Replans        int    `gorm:"default:0" json:"replans"` // 因落后自动重排的次数
+	LastPlannedOn  string `gorm:"type:char(10)" json:"last_planned_on"`
+	LastRemindedOn string `gorm:"type:char(10)" json:"-"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (StudyPlan) TableName() string {
+	return "study_plans"
+}
+
+// StudyPlanDay 计划每一天的目标与完成情况 (完成量取自 user_daily_stats 当天做题数)
+type StudyPlanDay struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	PlanID    uint      `gorm:"index:idx_plan_day,unique;not null" json:"plan_id"`
+	Date      string    `gorm:"index:idx_plan_day,unique;type:char(10)" json:"date"`
+	Target    int       `json:"target"`
+	Done      int       `json:"done"`
+	Met       bool      `json:"met"`
+	Replan    bool      `gorm:"default:false" json:"replan"` // 当天因前一天落后而重排
+	UpdatedAt time.Time `json:"-"`
+}
+
+func (StudyPlanDay) TableName() string {
+	return "study_plan_days"
+}
+
+// PlanView 计划详情 (含倒计时与进度)
+type PlanView struct {
+	StudyPlan
+	DaysLeft   int            `json:"days_left"`   // 距考试还有几天
+	StudyDays  int            `json:"study_days"`  // 其中还能排新题的天数
+	Total      int64          `json:"total"`       // 目标范围总题数
+	Done       int64          `json:"done"`        // 已做
+	Correct    int64          `json:"correct"`     // 已答对
+	Remaining  int64          `json:"remaining"`   // 剩余未做
+	TodayDone  int            `json:"today_done"`  // 今天已做 (全站做题数)
+	OnTrack    bool           `json:"on_track"`    // 今天是否已完成目标
+	RecentDays []StudyPlanDay `json:"recent_days"` // 最近 14 天
+}
@@ -0,0 +1,298 @@
+package studyplan
+
+import (
+	"fmt"
+	"time"
+
+	"med-platform/internal/common/db"
+	"med-platform/internal/common/service"
+	"med-platform/internal/question"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	questionRepo *question.Repository
+}
+
+func NewRepository() *Repository {
+	return &Repository{questionRepo: question.NewRepository()}
+}
+
+// PlanReq 新建 / 修改计划的参数
+type PlanReq struct {
+	Title       string `json:"title"`
+	CategoryIDs []uint `json:"category_ids"`
+	ExamDate    string `json:"exam_date"` // "2006-01-02"
+	ReviewDays  *int   `json:"review_days"`
+	RemindHour  *int   `json:"remind_hour"`
+}
+
+// daysBetween from 到 to 相隔的天数 (to 当天不算)
+// 只比较日历日，按 UTC 解析，避免夏令时切换那天只有 23 小时被算成 0 天
+func daysBetween(from, to string) int {
+	f, err1 := time.Parse(DateLayout, from)
+	t, err2 := time.Parse(DateLayout, to)
+	if err1 != nil || err2 != nil {
+		return 0
+	}
+	return int(t.Sub(f).Hours() / 24)
+}
+
+// countdown 距考试天数与其中可排新题的天数
+func countdown(p *StudyPlan, today string) (daysLeft, studyDays int) {
+	daysLeft = daysBetween(today, p.ExamDate)
+	if daysLeft <= 0 {
+		return 0, 0
+	}
+	studyDays = daysLeft - p.ReviewDays
+	if studyDays < 1 {
+		studyDays = 0 // 已进入冲刺复习期
+	}
+	return daysLeft, studyDays
+}
+
+// todayCount 某天的做题数 (与每日统计 / 排行榜同一口径)
+func todayCount(userID uint, date string) int {
+	var n int
+	db.DB.Table("user_daily_stats").Select("COALESCE(SUM(count), 0)").
+		Where("user_id = ? AND date_str = ?", userID, date).Scan(&n)
+	return n
+}
+
+// validate 检查参数并写入计划
+func (r *Repository) validate(p *StudyPlan, req PlanReq, today string) error {
+	if req.Title != "" {
+		p.Title = req.Title
+	}
+	if req.ExamDate != "" {
+		if _, err := time.ParseInLocation(DateLayout, req.ExamDate, time.Local); err != nil {
+			return fmt.Errorf("考试日期格式应为 2006-01-02")
+		}
+		p.ExamDate = req.ExamDate
+	}
+	if daysBetween(today, p.ExamDate) <= 0 {
+		return fmt.Errorf("考试日期必须晚于今天")
+	}
+	if req.CategoryIDs != nil {
+		var n int64
+		db.DB.Model(&question.Category{}).Where("id IN ?", req.CategoryIDs).Count(&n)
+		if len(req.CategoryIDs) == 0 || n != int64(len(req.CategoryIDs)) {
+			return fmt.Errorf("请选择有效的题库目录")
+		}
+		p.CategoryIDs = req.CategoryIDs
+	}
+	if req.ReviewDays != nil {
+		if *req.ReviewDays < 0 || *req.ReviewDays > 60 {
+			return fmt.Errorf("冲刺复习天数应在 0~60 之间")
+		}
+		p.ReviewDays = *req.ReviewDays
+	}
+	if req.RemindHour != nil {
+		if *req.RemindHour < 0 || *req.RemindHour > 23 {
+			return fmt.Errorf("提醒时间应在 0~23 点之间")
+		}
+		p.RemindHour = *req.RemindHour
+	}
+	return nil
+}
+
+// Active 用户当前进行中的计划
+func (r *Repository) Active(userID uint) (*StudyPlan, error) {
+	var p StudyPlan
+	err := db.DB.Where("user_id = ? AND status = ?", userID, PlanActive).Order("id desc").First(&p).Error
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Create 新建计划 (旧的进行中计划自动归档)
+func (r *Repository) Create(userID uint, req PlanReq) (*StudyPlan, error) {
+	today := time.Now().Format(DateLayout)
+	p := &StudyPlan{UserID: userID, Title: "备考计划", RemindHour: 20, Status: PlanActive}
+	if req.CategoryIDs == nil {
+		return nil, fmt.Errorf("请选择要复习的题库目录")
+	}
+	if err := r.validate(p, req, today); err != nil {
+		return nil, err
+	}
+	_, done, _, err := r.questionRepo.CategoryProgress(userID, p.CategoryIDs)
+	if err != nil {
+		return nil, err
+	}
+	p.StartDone = done
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&StudyPlan{}).Where("user_id = ? AND status = ?", userID, PlanActive).
+			Update("status", PlanArchived).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(p).Error; err != nil {
+			return err
+		}
+		return r.planDay(tx, p, today, false)
+	})
+	return p, err
+}
+
+// Update 修改计划，按新的日期 / 范围立即重排今天的目标
+func (r *Repository) Update(p *StudyPlan, req PlanReq) error {
+	today := time.Now().Format(DateLayout)
+	if err := r.validate(p, req, today); err != nil {
+		return err
+	}
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("title", "category_ids", "exam_date", "review_days", "remind_hour").Updates(p).Error; err != nil {
+			return err
+		}
+		return r.planDay(tx, p, today, false)
+	})
+}
+
+// planDay 按剩余题量与剩余天数算出今天的目标，写入计划与当天记录
+// 💡 今天新做掉的 (计划范围内) 题也计入"剩余"，避免白天改计划时把今天的目标算小
+func (r *Repository) planDay(tx *gorm.DB, p *StudyPlan, today string, replan bool) error {
+	total, done, _, err := r.questionRepo.CategoryProgress(p.UserID, p.CategoryIDs)
+	if err != nil {
+		return err
+	}
+	dayStart, _ := time.ParseInLocation(DateLayout, today, time.Local)
+	doneToday, err := r.questionRepo.CategoryDoneSince(p.UserID, p.CategoryIDs, dayStart)
+	if err != nil {
+		return err
+	}
+	_, studyDays := countdown(p, today)
+	target := 0
+	if remaining := total - done + doneToday; remaining > 0 && studyDays > 0 {
+		target = int((remaining + int64(studyDays) - 1) / int64(studyDays))
+	}
+
+	p.DailyTarget = target
+	p.LastPlannedOn = today
+	if err := tx.Model(p).Updates(map[string]interface{}{
+		"daily_target": target, "last_planned_on": today, "replans": p.Replans,
+	}).Error; err != nil {
+		return err
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "plan_id"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"target", "replan", "updated_at"}),
+	}).Create(&StudyPlanDay{PlanID: p.ID, Date: today, Target: target, Replan: replan}).Error
+}
+
+// settleDays 用每日统计回填往日的完成量
+func settleDays(tx *gorm.DB, p *StudyPlan, today string) error {
+	return tx.Exec(`UPDATE study_plan_days d SET done = s.count, met = s.count >= d.target, updated_at = NOW()
+		FROM user_daily_stats s
+		WHERE d.plan_id = ? AND d.date < ? AND s.user_id = ? AND s.date_str = d.date`, p.ID, today, p.UserID).Error
+}
+
+// Roll 跨天滚动：结算往日完成情况，到考试日则结束计划；昨天没完成目标时重排并提醒
+func (r *Repository) Roll(p *StudyPlan, now time.Time) error {
+	today := now.Format(DateLayout)
+	if p.Status != PlanActive || p.LastPlannedOn == today {
+		return nil
+	}
+
+	lastPlanned, oldTarget := p.LastPlannedOn, p.DailyTarget
+	var claimed, behind bool
+	var last StudyPlanDay
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		// 先抢占今天的滚动：打开页面与定时任务可能同时进来，只有改到这一行的那个继续往下走，
+		// 其余的等它提交后拿到 0 行，避免重复结算 / 重复计数 replans / 重复提醒
+		res := tx.Model(&StudyPlan{}).Where("id = ? AND status = ? AND last_planned_on < ?", p.ID, PlanActive, today).
+			Update("last_planned_on", today)
+		if res.Error != nil {
+			return res.Error
+		}
+		if claimed = res.RowsAffected > 0; !claimed {
+			return nil
+		}
+		if err := settleDays(tx, p, today); err != nil {
+			return err
+		}
+		if daysBetween(today, p.ExamDate) <= 0 {
+			p.Status = PlanFinished
+			return tx.Model(p).Update("status", PlanFinished).Error
+		}
+
+		behind = tx.Where("plan_id = ? AND date = ?", p.ID, lastPlanned).First(&last).Error == nil &&
+			last.Target > 0 && !last.Met
+		if behind {
+			p.Replans++
+		}
+		return r.planDay(tx, p, today, behind)
+	})
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return db.DB.First(p, p.ID).Error // 已被别处滚动过，取最新的计划
+	}
+	if behind && p.DailyTarget > 0 {
+		daysLeft, _ := countdown(p, today)
+		service.SendNotification(p.UserID, 0, "study_plan", p.ID,
+			fmt.Sprintf("%s 只完成了 %d/%d 题，剩余题量已重新分配：每天 %d 题 (原 %d 题)，距考试还有 %d 天",
+				last.Date, last.Done, last.Target, p.DailyTarget, oldTarget, daysLeft),
+			"📅 备考计划已调整")
+	}
+	return nil
+}
+
+// Remind 到了提醒时间、今天还没完成目标的，发一次提醒
+func (r *Repository) Remind(p *StudyPlan, now time.Time) {
+	today := now.Format(DateLayout)
+	if p.Status != PlanActive || p.DailyTarget <= 0 || now.Hour() < p.RemindHour || p.LastRemindedOn == today {
+		return
+	}
+	done := todayCount(p.UserID, today)
+	if done >= p.DailyTarget {
+		return
+	}
+	daysLeft, _ := countdown(p, today)
+	service.SendNotification(p.UserID, 0, "study_plan", p.ID,
+		fmt.Sprintf("今天还差 %d 题完成目标 (%d/%d)，距考试还有 %d 天，加油！", p.DailyTarget-done, done, p.DailyTarget, daysLeft),
+		"⏰ 今日学习提醒")
+	p.LastRemindedOn = today
+	db.DB.Model(p).Update("last_reminded_on", today)
+}
+
+// View 计划详情：倒计时、总进度、今天完成情况与最近 14 天
+func (r *Repository) View(p *StudyPlan, now time.Time) (*PlanView, error) {
+	today := now.Format(DateLayout)
+	v := &PlanView{StudyPlan: *p}
+	v.DaysLeft, v.StudyDays = countdown(p, today)
+
+	var err error
+	if v.Total, v.Done, v.Correct, err = r.questionRepo.CategoryProgress(p.UserID, p.CategoryIDs); err != nil {
+		return nil, err
+	}
+	if v.Remaining = v.Total - v.Done; v.Remaining < 0 {
+		v.Remaining = 0
+	}
+	v.TodayDone = todayCount(p.UserID, today)
+	v.OnTrack = v.TodayDone >= p.DailyTarget
+
+	if err := r.settleToday(p, today, v.TodayDone); err != nil {
+		return nil, err
+	}
+	v.RecentDays = []StudyPlanDay{}
+	db.DB.Where("plan_id = ? AND date > ?", p.ID, now.AddDate(0, 0, -14).Format(DateLayout)).
+		Order("date asc").Find(&v.RecentDays)
+	return v, nil
+}
+
+// settleToday 当天记录随查随更新，历史曲线里的今天与实时一致
+func (r *Repository) settleToday(p *StudyPlan, today string, done int) error {
+	return db.DB.Model(&StudyPlanDay{}).Where("plan_id = ? AND date = ?", p.ID, today).
+		Updates(map[string]interface{}{"done": done, "met": done >= p.DailyTarget}).Error
+}
+
+// Days 计划全部日程 (按日期)
+func (r *Repository) Days(planID uint) ([]StudyPlanDay, error) {
+	days := []StudyPlanDay{}
+	err := db.DB.Where("plan_id = ?", planID).Order("date asc").Find(&days).Error
+	return days, err
+}
@@ -0,0 +1,66 @@
+package studyplan
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDaysBetween(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     int
+	}{
+		{"2024-03-01", "2024-03-01", 0},
+		{"2024-03-01", "2024-03-02", 1},
+		{"2024-02-28", "2024-03-01", 2}, // 闰年
+		{"2023-02-28", "2023-03-01", 1},
+		{"2024-12-31", "2025-01-01", 1},
+		{"2024-03-10", "2024-03-01", -9},
+		{"2024-03-01", "2024/03/02", 0},
+		{"", "2024-03-02", 0},
+	}
+	for _, tt := range tests {
+		if got := daysBetween(tt.from, tt.to); got != tt.want {
+			t.Errorf("daysBetween(%q, %q) = %d, want %d", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestDaysBetweenDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no tzdata:", err)
+	}
+	old := time.Local
+	time.Local = loc
+	defer func() { time.Local = old }()
+
+	// 纽约 2024-03-10 切换夏令时，当天只有 23 小时
+	if got := daysBetween("2024-03-10", "2024-03-11"); got != 1 {
+		t.Errorf("夏令时切换日 daysBetween = %d, want 1", got)
+	}
+}
+
+func TestCountdown(t *testing.T) {
+	tests := []struct {
+		name       string
+		examDate   string
+		reviewDays int
+		daysLeft   int
+		studyDays  int
+	}{
+		{"正常排题", "2024-03-31", 5, 30, 25},
+		{"不留复习期", "2024-03-02", 0, 1, 1},
+		{"进入冲刺复习期", "2024-03-04", 5, 3, 0},
+		{"复习期最后一天前", "2024-03-07", 5, 6, 1},
+		{"考试当天", "2024-03-01", 0, 0, 0},
+		{"考试已过", "2024-02-20", 0, 0, 0},
+	}
+	for _, tt := range tests {
+		p := &StudyPlan{ExamDate: tt.examDate, ReviewDays: tt.reviewDays}
+		daysLeft, studyDays := countdown(p, "2024-03-01")
+		if daysLeft != tt.daysLeft || studyDays != tt.studyDays {
+			t.Errorf("%s: countdown = (%d, %d), want (%d, %d)", tt.name, daysLeft, studyDays, tt.daysLeft, tt.studyDays)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package sysconfig
+
+import (
+	"net/http"
+	"strconv"
+
+	"med-platform/internal/common/db"
+	"med-platform/internal/common/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListAuditLogs 后台操作审计
+// GET /admin/audit-logs?action=question.regrade&target_type=question&target_id=12&page=1
+func (h *Handler) ListAuditLogs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize := 20
+
+	query := db.DB.Model(&model.AuditLog{})
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if targetType := c.Query("target_type"); targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	if targetID, _ := strconv.Atoi(c.Query("target_id")); targetID > 0 {
+		query = query.Where("target_id = ?", targetID)
+	}
+
+	var total int64
+	query.Count(&total)
+	var list []model.AuditLog
+	if err := query.Order("id desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取审计记录失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list, "total": total, "page": page})
+}
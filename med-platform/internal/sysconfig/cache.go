@@ -9,8 +9,12 @@ import (
 
 // 🔥 定义系统标准配置 Key 常量，防止拼写错误
 const (
-	KeyAgentRateDirect = "AGENT_COMMISSION_RATE_DIRECT" // 在线支付分润比例
-	KeyAgentRateCard   = "AGENT_COMMISSION_RATE_CARD"   // 卡密兑换分润比例
+	KeyAgentRateDirect     = "AGENT_COMMISSION_RATE_DIRECT" // 在线支付分润比例
+	KeyAgentRateCard       = "AGENT_COMMISSION_RATE_CARD"   // 卡密兑换分润比例
+	KeyScoringXPolicy      = "SCORING_X_POLICY"             // X 型题部分得分策略
+	KeySubjectiveAutoScore = "SUBJECTIVE_AUTO_SCORE"        // 主观题关键词自动评分开关
+	KeyTrashRetentionDays  = "TRASH_RETENTION_DAYS"         // 回收站保留天数
+	KeyScoreBackfilled     = "MIGRATION_SCORE_BACKFILLED"   // 作答得分补记迁移完成标记 (一次性迁移，勿删)
 )
 
 var (
@@ -31,6 +35,9 @@ func seedDefaults() {
 	defaults := []SysConfig{
 		{Key: KeyAgentRateDirect, Value: "0.20", Description: "在线支付代理分润比例 (0.0-1.0)"},
 		{Key: KeyAgentRateCard, Value: "0.15", Description: "卡密兑换代理分润比例 (0.0-1.0)"},
+		{Key: KeyScoringXPolicy, Value: "all_or_nothing", Description: "X型题计分: all_or_nothing 全对才得分 / per_option 少选按比例得分 / deduct 错选倒扣"},
+		{Key: KeySubjectiveAutoScore, Value: "on", Description: "主观题关键词覆盖率自动评分: on / off (仅作参考，对错以用户自评为准)"},
+		{Key: KeyTrashRetentionDays, Value: "30", Description: "删除的题库 / 章节 / 题目在回收站保留的天数，过期后彻底清除"},
 	}
 
 	for _, d := range defaults {
@@ -50,6 +50,21 @@ func (h *Handler) SaveConfig(c *gin.Context) {
 			return
 		}
 	}
+	if req.Key == KeyScoringXPolicy {
+		switch req.Value {
+		case "all_or_nothing", "per_option", "deduct":
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "X型题计分策略只能是 all_or_nothing / per_option / deduct"})
+			return
+		}
+	}
+	if req.Key == KeyTrashRetentionDays {
+		days, err := strconv.Atoi(req.Value)
+		if err != nil || days < 1 || days > 365 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "回收站保留天数必须是 1 到 365 之间的整数"})
+			return
+		}
+	}
 
 	var config SysConfig
 	if err := db.DB.Where("key = ?", req.Key).First(&config).Error; err != nil {